@@ -34,7 +34,10 @@ func main() {
 
 	seedFlag := flag.Bool("seed", false, "наполнение БД тестовыми данными")
 	archiveFlag := flag.Bool("archive", false, "архивирование старых объявлений")
+	purgeFlag := flag.Bool("purge", false, "удаление закрытых объявлений после срока хранения")
 	downFlag := flag.Bool("down", false, "rollback миграции")
+	setWebhookFlag := flag.Bool("set-webhook", false, "регистрация вебхука telegram")
+	cleanupFlag := flag.Bool("cleanup", false, "удаление устаревших служебных данных")
 	flag.Parse()
 
 	if *seedFlag {
@@ -47,11 +50,26 @@ func main() {
 		return
 	}
 
+	if *purgeFlag {
+		app.RunPurge(dbConn, config, logger)
+		return
+	}
+
 	if *downFlag {		
 		app.RunDownMigrations(dbConn, logger)
 		return
 	}
 
+	if *setWebhookFlag {
+		app.RunSetWebhook(config, logger)
+		return
+	}
+
+	if *cleanupFlag {
+		app.RunCleanup(dbConn, config, logger)
+		return
+	}
+
 	app.RunUpMigrations(dbConn, logger)
 
 	app.RunHttpServer(dbConn, config, logger)
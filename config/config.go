@@ -4,18 +4,28 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Env         string
-	BotToken    string
-	Server      Server
-	S3Storage   S3Storage
-	StorageType string
-	Db          DbConfig
-	JwtSecret   string
+	Env           string
+	BotToken      string
+	Server        Server
+	S3Storage     S3Storage
+	StorageType   string
+	Db            DbConfig
+	JwtSecret     string
+	Duplicates    Duplicates
+	Bump          Bump
+	Ads           Ads
+	Uploads       Uploads
+	Images        Images
+	Telegram      Telegram
+	Payments      Payments
+	Notifications Notifications
 }
 
 type Server struct {
@@ -34,6 +44,72 @@ type DbConfig struct {
 	Dsn string
 }
 
+// режимы получения апдейтов бота
+const TELEGRAM_MODE_WEBHOOK = "webhook"
+const TELEGRAM_MODE_POLLING = "polling"
+
+type Telegram struct {
+	ApiUrl        string
+	WebhookSecret string
+	// webhook или polling (getUpdates для локальной разработки)
+	Mode string
+	// для ссылок вида t.me/<BotUsername>/<MiniAppName>
+	BotUsername string
+	MiniAppName string
+}
+
+func (t Telegram) IsPolling() bool {
+	return t.Mode == TELEGRAM_MODE_POLLING
+}
+
+// цены продвижения в Telegram Stars за один день
+type Payments struct {
+	PinPrice       int
+	HighlightPrice int
+	MaxDays        int
+}
+
+// очередь исходящих сообщений бота
+type Notifications struct {
+	Workers int
+}
+
+// жизненный цикл объявлений
+type Ads struct {
+	// сколько хранятся фото закрытого объявления, пока его можно восстановить
+	Retention time.Duration
+}
+
+// прямая загрузка фото по подписанной ссылке
+type Uploads struct {
+	MaxSize int64
+	UrlTtl  time.Duration
+	// подпись ссылок LocalStorage
+	SigningSecret string
+}
+
+// ресайз фото на лету по подписанным ссылкам /img/{path}
+type Images struct {
+	SigningSecret string
+	// именованные варианты для ответов API: name=WxH:fit[:fmt] через запятую
+	Variants string
+	// сколько фото ресайзится одновременно
+	Concurrency int
+}
+
+type Bump struct {
+	Cooldown time.Duration
+}
+
+// поиск похожих объявлений при создании
+type Duplicates struct {
+	// block, bump или flag
+	Action     string
+	Threshold  float64
+	OwnDays    int
+	OthersDays int
+}
+
 func Load() *Config {
 	err := godotenv.Load()
 	if err != nil {
@@ -61,6 +137,33 @@ func Load() *Config {
 	storageType := getEnvVar("STORAGE_TYPE")
 	botToken := getEnvVar("BOT_TOKEN")
 	jwtSecret := getEnvVar("JWT_SECRET")
+	duplicateAction := getEnvVarDefault("DUPLICATE_ACTION", "flag")
+	duplicateThreshold := getEnvFloatDefault("DUPLICATE_THRESHOLD", 0.6)
+	duplicateOwnDays := getEnvIntDefault("DUPLICATE_OWN_DAYS", 30)
+	duplicateOthersDays := getEnvIntDefault("DUPLICATE_OTHERS_DAYS", 7)
+	bumpCooldownHours := getEnvIntDefault("BUMP_COOLDOWN_HOURS", 24)
+	adRetentionDays := getEnvIntDefault("AD_RETENTION_DAYS", 30)
+	uploadMaxSizeMb := getEnvIntDefault("UPLOAD_MAX_SIZE_MB", 10)
+	uploadUrlTtlMinutes := getEnvIntDefault("UPLOAD_URL_TTL_MINUTES", 15)
+	uploadSigningSecret := getEnvVarDefault("UPLOAD_SIGNING_SECRET", jwtSecret)
+	imageSigningSecret := getEnvVarDefault("IMAGE_SIGNING_SECRET", jwtSecret)
+	imageVariants := getEnvVarDefault("IMAGE_VARIANTS", "thumb=300x300:fill,card=600x600:fit,full=1200x1200:fit")
+	imageResizeConcurrency := getEnvIntDefault("IMAGE_RESIZE_CONCURRENCY", 2)
+	telegramApiUrl := getEnvVarDefault("TELEGRAM_API_URL", "https://api.telegram.org")
+	telegramMode := getEnvVarDefault("TELEGRAM_MODE", TELEGRAM_MODE_WEBHOOK)
+	telegramWebhookSecret := getEnvVarDefault("TELEGRAM_WEBHOOK_SECRET", "")
+	botUsername := getEnvVarDefault("BOT_USERNAME", "")
+	miniAppName := getEnvVarDefault("MINI_APP_NAME", "app")
+	if telegramMode != TELEGRAM_MODE_WEBHOOK && telegramMode != TELEGRAM_MODE_POLLING {
+		log.Fatalf("TELEGRAM_MODE must be %s or %s", TELEGRAM_MODE_WEBHOOK, TELEGRAM_MODE_POLLING)
+	}
+	if telegramMode == TELEGRAM_MODE_WEBHOOK && telegramWebhookSecret == "" {
+		log.Fatal("TELEGRAM_WEBHOOK_SECRET is not set")
+	}
+	pinPrice := getEnvIntDefault("PROMOTION_PIN_PRICE", 50)
+	highlightPrice := getEnvIntDefault("PROMOTION_HIGHLIGHT_PRICE", 20)
+	promotionMaxDays := getEnvIntDefault("PROMOTION_MAX_DAYS", 30)
+	notificationWorkers := getEnvIntDefault("NOTIFICATION_WORKERS", 2)
 
 	return &Config{
 		Env: env,
@@ -80,6 +183,43 @@ func Load() *Config {
 			Dsn: dsn,
 		},
 		JwtSecret: jwtSecret,
+		Duplicates: Duplicates{
+			Action:     duplicateAction,
+			Threshold:  duplicateThreshold,
+			OwnDays:    duplicateOwnDays,
+			OthersDays: duplicateOthersDays,
+		},
+		Bump: Bump{
+			Cooldown: time.Duration(bumpCooldownHours) * time.Hour,
+		},
+		Ads: Ads{
+			Retention: time.Duration(adRetentionDays) * 24 * time.Hour,
+		},
+		Uploads: Uploads{
+			MaxSize:       int64(uploadMaxSizeMb) << 20,
+			UrlTtl:        time.Duration(uploadUrlTtlMinutes) * time.Minute,
+			SigningSecret: uploadSigningSecret,
+		},
+		Images: Images{
+			SigningSecret: imageSigningSecret,
+			Variants:      imageVariants,
+			Concurrency:   imageResizeConcurrency,
+		},
+		Telegram: Telegram{
+			ApiUrl:        telegramApiUrl,
+			WebhookSecret: telegramWebhookSecret,
+			Mode:          telegramMode,
+			BotUsername:   botUsername,
+			MiniAppName:   miniAppName,
+		},
+		Payments: Payments{
+			PinPrice:       pinPrice,
+			HighlightPrice: highlightPrice,
+			MaxDays:        promotionMaxDays,
+		},
+		Notifications: Notifications{
+			Workers: notificationWorkers,
+		},
 	}
 }
 
@@ -90,3 +230,37 @@ func getEnvVar(key string) string {
 	}
 	return value
 }
+
+func getEnvVarDefault(key string, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvIntDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("%s must be integer", key)
+	}
+	return result
+}
+
+func getEnvFloatDefault(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("%s must be number", key)
+	}
+	return result
+}
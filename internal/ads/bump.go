@@ -0,0 +1,53 @@
+package ads
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	appErrors "vietio/internal/errors"
+)
+
+// BumpPolicy решает, можно ли поднять объявление.
+// Позволяет заменить бесплатный кулдаун на платные поднятия
+type BumpPolicy interface {
+	// Allow возвращает *appErrors.BumpNotAllowedError, если поднимать нельзя.
+	// ad прочитано внутри транзакции bump с блокировкой строки
+	Allow(ctx context.Context, ad AdModel) error
+	// Consume списывает право на поднятие в транзакции bump: откат поднятия откатывает и списание
+	Consume(ctx context.Context, tx *sql.Tx, ad AdModel) error
+	// NextBumpAt время, когда объявление можно будет поднять снова
+	NextBumpAt(ad AdModel, bumpedAt time.Time) time.Time
+}
+
+// бесплатное поднятие не чаще одного раза за cooldown
+type CooldownBumpPolicy struct {
+	cooldown time.Duration
+}
+
+func NewCooldownBumpPolicy(cooldown time.Duration) *CooldownBumpPolicy {
+	return &CooldownBumpPolicy{
+		cooldown: cooldown,
+	}
+}
+
+func (p *CooldownBumpPolicy) Allow(ctx context.Context, ad AdModel) error {
+	availableAt := p.NextBumpAt(ad, ad.BumpedAt)
+
+	if time.Now().Before(availableAt) {
+		return &appErrors.BumpNotAllowedError{
+			Message:     "поднять объявление можно будет позже",
+			AvailableAt: availableAt,
+		}
+	}
+
+	return nil
+}
+
+func (p *CooldownBumpPolicy) Consume(ctx context.Context, tx *sql.Tx, ad AdModel) error {
+	return nil
+}
+
+func (p *CooldownBumpPolicy) NextBumpAt(ad AdModel, bumpedAt time.Time) time.Time {
+	return bumpedAt.Add(p.cooldown)
+}
@@ -0,0 +1,100 @@
+package ads
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"vietio/config"
+	appErrors "vietio/internal/errors"
+)
+
+const DUPLICATE_ACTION_BLOCK = "block"
+const DUPLICATE_ACTION_BUMP = "bump"
+const DUPLICATE_ACTION_FLAG = "flag"
+
+type DuplicateChecker struct {
+	repo   *Repository
+	config config.Duplicates
+}
+
+func NewDuplicateChecker(repo *Repository, config config.Duplicates) *DuplicateChecker {
+	return &DuplicateChecker{
+		repo:   repo,
+		config: config,
+	}
+}
+
+// Check ищет похожие объявления среди свежих объявлений пользователя
+// и среди чужих объявлений той же категории.
+// Возвращает причину для модерации или *appErrors.DuplicateAdError
+func (c *DuplicateChecker) Check(
+	ctx context.Context,
+	userId int64,
+	categoryId int,
+	title string,
+	description string,
+) (string, error) {
+	text := title + " " + description
+
+	own, err := c.repo.FindSimilarAds(ctx, SimilarAdsFilterParams{
+		Text:      text,
+		UserId:    &userId,
+		Since:     time.Now().AddDate(0, 0, -c.config.OwnDays),
+		Threshold: c.config.Threshold,
+		Limit:     1,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(own) > 0 {
+		duplicateUuid := own[0].Uuid.String()
+
+		switch c.config.Action {
+		case DUPLICATE_ACTION_BLOCK:
+			return "", &appErrors.DuplicateAdError{
+				Message: "похожее объявление уже опубликовано",
+				Uuid:    duplicateUuid,
+				Action:  DUPLICATE_ACTION_BLOCK,
+			}
+		case DUPLICATE_ACTION_BUMP:
+			return "", &appErrors.DuplicateAdError{
+				Message: "у вас уже есть похожее объявление, поднимите его вместо создания нового",
+				Uuid:    duplicateUuid,
+				Action:  DUPLICATE_ACTION_BUMP,
+			}
+		default:
+			return fmt.Sprintf("повтор своего объявления %s", duplicateUuid), nil
+		}
+	}
+
+	others, err := c.repo.FindSimilarAds(ctx, SimilarAdsFilterParams{
+		Text:          text,
+		ExcludeUserId: &userId,
+		CategoryId:    &categoryId,
+		Since:         time.Now().AddDate(0, 0, -c.config.OthersDays),
+		Threshold:     c.config.Threshold,
+		Limit:         1,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(others) > 0 {
+		duplicateUuid := others[0].Uuid.String()
+
+		// поднять чужое объявление нельзя, поэтому для bump отправляем на модерацию
+		if c.config.Action == DUPLICATE_ACTION_BLOCK {
+			return "", &appErrors.DuplicateAdError{
+				Message: "похожее объявление уже опубликовано",
+				Uuid:    duplicateUuid,
+				Action:  DUPLICATE_ACTION_BLOCK,
+			}
+		}
+
+		return fmt.Sprintf("похоже на объявление %s", duplicateUuid), nil
+	}
+
+	return "", nil
+}
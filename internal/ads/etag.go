@@ -0,0 +1,36 @@
+package ads
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errIfMatchInvalid = errors.New("invalid If-Match")
+
+// ETag объявления — его версия: "3"
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch версия из заголовка If-Match.
+// nil — заголовка нет или "*", проверка не нужна.
+// Слабые и чужие ETag с версией совпасть не могут — это ошибка
+func parseIfMatch(r *http.Request) (*int, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return nil, nil
+	}
+
+	if len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return nil, errIfMatchInvalid
+	}
+
+	version, err := strconv.Atoi(value[1 : len(value)-1])
+	if err != nil {
+		return nil, errIfMatchInvalid
+	}
+
+	return &version, nil
+}
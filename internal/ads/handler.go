@@ -1,7 +1,9 @@
 package ads
 
 import (
+	"encoding/json"
 	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 
@@ -68,6 +70,7 @@ func (h *Handler) GetAd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", etag(result.Version))
 	response.Json(w, result, http.StatusOK)
 }
 
@@ -116,6 +119,7 @@ func (h *Handler) CreateAd(w http.ResponseWriter, r *http.Request) {
 
 	price := validateIntField("price", r.FormValue("price"), false, 0, validationErrors)
 	categoryId := validateIntField("category_id", r.FormValue("category_id"), true, 0, validationErrors)
+	fileIds := validateIdsField("file_ids", r.Form["file_ids"], validationErrors)
 
 	if validationErrors.HasErrors() {
 		h.logger.Warn(appErrors.ErrCreateAdValidation.Error(), "err", err)
@@ -128,17 +132,28 @@ func (h *Handler) CreateAd(w http.ResponseWriter, r *http.Request) {
 		Description: r.FormValue("description"),
 		Price:       price,
 		CategoryId:  categoryId,
+		FileIds:     fileIds,
 	}
 
 	images := r.MultipartForm.File["images"]
 
-	result, err := h.service.CreateAd(r.Context(), payload, images)
+	result, err := h.service.CreateAd(r.Context(), payload, images, r.Header.Get("Idempotency-Key"))
 
 	if err != nil {
 		var vError *appErrors.ValidationError
+		var dError *appErrors.DuplicateAdError
 		if errors.As(err, &vError) {
 			h.logger.Warn(appErrors.ErrCreateAdValidation.Error(), "err", err, "payload", payload)
 			response.Json(w, err, http.StatusBadRequest)
+		} else if errors.As(err, &dError) {
+			h.logger.Info(appErrors.ErrDuplicateAd.Error(), "err", err, "duplicate_uuid", dError.Uuid)
+			response.Json(w, dError, http.StatusConflict)
+		} else if errors.Is(err, appErrors.ErrIdempotencyKeyReused) {
+			h.logger.Info(appErrors.ErrIdempotencyKeyReused.Error(), "err", err, "payload", payload)
+			http.Error(w, appErrors.ErrIdempotencyKeyReused.Error(), http.StatusUnprocessableEntity)
+		} else if errors.Is(err, appErrors.ErrIdempotencyInProgress) {
+			h.logger.Info(appErrors.ErrIdempotencyInProgress.Error(), "err", err, "payload", payload)
+			http.Error(w, appErrors.ErrIdempotencyInProgress.Error(), http.StatusConflict)
 		} else {
 			h.logger.Error(appErrors.ErrCreateAd.Error(), "err", err, "payload", payload)
 			http.Error(w, "internal server", http.StatusInternalServerError)
@@ -168,6 +183,7 @@ func (h *Handler) UpdateAd(w http.ResponseWriter, r *http.Request) {
 
 	price := validateIntField("price", r.FormValue("price"), false, 0, validationErrors)
 	categoryId := validateIntField("category_id", r.FormValue("category_id"), true, 0, validationErrors)
+	fileIds := validateIdsField("file_ids", r.Form["file_ids"], validationErrors)
 
 	if validationErrors.HasErrors() {
 		h.logger.Warn(appErrors.ErrCreateAdValidation.Error(), "err", err)
@@ -182,11 +198,19 @@ func (h *Handler) UpdateAd(w http.ResponseWriter, r *http.Request) {
 		Price:       price,
 		CategoryId:  categoryId,
 		OldImages:   r.Form["old_images"],
+		FileIds:     fileIds,
 	}
 
 	images := r.MultipartForm.File["images"]
 
-	result, err := h.service.UpdateAd(r.Context(), payload, images)
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		h.logger.Info(appErrors.ErrAdVersionMismatch.Error(), "err", err, "uuid", uuid)
+		http.Error(w, appErrors.ErrAdVersionMismatch.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
+	result, err := h.service.UpdateAd(r.Context(), payload, images, expectedVersion)
 
 	if err != nil {
 		var vError *appErrors.ValidationError
@@ -196,6 +220,18 @@ func (h *Handler) UpdateAd(w http.ResponseWriter, r *http.Request) {
 		} else if errors.Is(err, appErrors.ErrForbidden) {
 			h.logger.Warn(appErrors.ErrForbidden.Error(), "err", err, "payload", payload)
 			http.Error(w, "forbidden", http.StatusForbidden)
+		} else if errors.Is(err, appErrors.ErrAdNotFound) {
+			h.logger.Info(appErrors.ErrAdNotFound.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotFound.Error(), http.StatusNotFound)
+		} else if errors.Is(err, appErrors.ErrAdConflict) {
+			h.logger.Info(appErrors.ErrAdConflict.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdConflict.Error(), http.StatusConflict)
+		} else if errors.Is(err, appErrors.ErrAdNotEditable) {
+			h.logger.Info(appErrors.ErrAdNotEditable.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotEditable.Error(), http.StatusConflict)
+		} else if errors.Is(err, appErrors.ErrAdVersionMismatch) {
+			h.logger.Info(appErrors.ErrAdVersionMismatch.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdVersionMismatch.Error(), http.StatusPreconditionFailed)
 		} else {
 			h.logger.Error(appErrors.ErrUpdateAd.Error(), "err", err, "payload", payload)
 			http.Error(w, "internal server", http.StatusInternalServerError)
@@ -205,6 +241,7 @@ func (h *Handler) UpdateAd(w http.ResponseWriter, r *http.Request) {
 
 	result.Result = true
 
+	w.Header().Set("ETag", etag(result.Version))
 	response.Json(w, result, http.StatusOK)
 }
 
@@ -216,12 +253,31 @@ func (h *Handler) DeleteAd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.DeleteAd(r.Context(), uuid)
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		h.logger.Info(appErrors.ErrAdVersionMismatch.Error(), "err", err, "uuid", uuid)
+		http.Error(w, appErrors.ErrAdVersionMismatch.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
+	err = h.service.DeleteAd(r.Context(), uuid, expectedVersion)
 
 	if err != nil {
 		if errors.Is(err, appErrors.ErrForbidden) {
 			h.logger.Warn(appErrors.ErrForbidden.Error(), "err", "нет прав для удаления объявления", "uuid", uuid)
 			http.Error(w, "forbidden", http.StatusForbidden)
+		} else if errors.Is(err, appErrors.ErrAdNotFound) {
+			h.logger.Info(appErrors.ErrAdNotFound.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotFound.Error(), http.StatusNotFound)
+		} else if errors.Is(err, appErrors.ErrInvalidStatusTransition) {
+			h.logger.Info(appErrors.ErrInvalidStatusTransition.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrInvalidStatusTransition.Error(), http.StatusConflict)
+		} else if errors.Is(err, appErrors.ErrAdVersionMismatch) {
+			h.logger.Info(appErrors.ErrAdVersionMismatch.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdVersionMismatch.Error(), http.StatusPreconditionFailed)
+		} else if errors.Is(err, appErrors.ErrAdConflict) {
+			h.logger.Info(appErrors.ErrAdConflict.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdConflict.Error(), http.StatusConflict)
 		} else {
 			h.logger.Error(appErrors.ErrDeleteAd.Error(), "err", err, "uuid", uuid)
 			http.Error(w, "internal server", http.StatusInternalServerError)
@@ -240,12 +296,45 @@ func (h *Handler) MarkingSoldAd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.MarkingSoldAd(r.Context(), uuid)
+	// тело необязательное: без покупателя объявление просто закрывается
+	payload := MarkSoldRequestBody{}
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil && !errors.Is(err, io.EOF) {
+		response.Json(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		h.logger.Info(appErrors.ErrAdVersionMismatch.Error(), "err", err, "uuid", uuid)
+		http.Error(w, appErrors.ErrAdVersionMismatch.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
+	err = h.service.MarkingSoldAd(r.Context(), uuid, payload, expectedVersion)
 
 	if err != nil {
 		if errors.Is(err, appErrors.ErrForbidden) {
 			h.logger.Warn(appErrors.ErrForbidden.Error(), "err", "нет прав для изменения статуса объявления", "uuid", uuid)
 			http.Error(w, "forbidden", http.StatusForbidden)
+		} else if errors.Is(err, appErrors.ErrAdNotFound) {
+			h.logger.Info(appErrors.ErrAdNotFound.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotFound.Error(), http.StatusNotFound)
+		} else if errors.Is(err, appErrors.ErrBuyerNotContact) {
+			h.logger.Info(appErrors.ErrBuyerNotContact.Error(), "err", err, "uuid", uuid, "buyer_id", payload.BuyerId)
+			http.Error(w, appErrors.ErrBuyerNotContact.Error(), http.StatusBadRequest)
+		} else if errors.Is(err, appErrors.ErrDealExists) {
+			h.logger.Info(appErrors.ErrDealExists.Error(), "err", err, "uuid", uuid, "buyer_id", payload.BuyerId)
+			http.Error(w, appErrors.ErrDealExists.Error(), http.StatusConflict)
+		} else if errors.Is(err, appErrors.ErrInvalidStatusTransition) {
+			h.logger.Info(appErrors.ErrInvalidStatusTransition.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrInvalidStatusTransition.Error(), http.StatusConflict)
+		} else if errors.Is(err, appErrors.ErrAdVersionMismatch) {
+			h.logger.Info(appErrors.ErrAdVersionMismatch.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdVersionMismatch.Error(), http.StatusPreconditionFailed)
+		} else if errors.Is(err, appErrors.ErrAdConflict) {
+			h.logger.Info(appErrors.ErrAdConflict.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdConflict.Error(), http.StatusConflict)
 		} else {
 			h.logger.Error(appErrors.ErrSoldAd.Error(), "err", err, "uuid", uuid)
 			http.Error(w, "internal server", http.StatusInternalServerError)
@@ -256,6 +345,206 @@ func (h *Handler) MarkingSoldAd(w http.ResponseWriter, r *http.Request) {
 	response.Json(w, DeleteAdResponse{true}, http.StatusOK)
 }
 
+func (h *Handler) ReserveAd(w http.ResponseWriter, r *http.Request) {
+	h.changeReservation(w, r, true)
+}
+
+func (h *Handler) UnreserveAd(w http.ResponseWriter, r *http.Request) {
+	h.changeReservation(w, r, false)
+}
+
+func (h *Handler) changeReservation(w http.ResponseWriter, r *http.Request, reserve bool) {
+	uuid, err := uuid.Parse(r.PathValue("uuid"))
+	if err != nil {
+		h.logger.Error(appErrors.ErrNotValidUuid.Error(), "err", err, "uuid", uuid)
+		http.Error(w, appErrors.ErrNotValidUuid.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if reserve {
+		err = h.service.ReserveAd(r.Context(), uuid)
+	} else {
+		err = h.service.UnreserveAd(r.Context(), uuid)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, appErrors.ErrForbidden):
+			h.logger.Warn(appErrors.ErrForbidden.Error(), "err", "нет прав для изменения статуса объявления", "uuid", uuid)
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case errors.Is(err, appErrors.ErrAdNotFound):
+			h.logger.Info(appErrors.ErrAdNotFound.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotFound.Error(), http.StatusNotFound)
+		case errors.Is(err, appErrors.ErrInvalidStatusTransition):
+			h.logger.Info(appErrors.ErrInvalidStatusTransition.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrInvalidStatusTransition.Error(), http.StatusConflict)
+		case errors.Is(err, appErrors.ErrAdVersionMismatch):
+			h.logger.Info(appErrors.ErrAdVersionMismatch.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdVersionMismatch.Error(), http.StatusPreconditionFailed)
+		case errors.Is(err, appErrors.ErrAdConflict):
+			h.logger.Info(appErrors.ErrAdConflict.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdConflict.Error(), http.StatusConflict)
+		default:
+			h.logger.Error(appErrors.ErrReserveAd.Error(), "err", err, "uuid", uuid)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, DeleteAdResponse{true}, http.StatusOK)
+}
+
+func (h *Handler) GetAdRevisions(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuid.Parse(r.PathValue("uuid"))
+	if err != nil {
+		h.logger.Error(appErrors.ErrNotValidUuid.Error(), "err", err, "uuid", uuid)
+		http.Error(w, appErrors.ErrNotValidUuid.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.service.GetAdRevisions(r.Context(), uuid)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, appErrors.ErrForbidden):
+			h.logger.Warn(appErrors.ErrForbidden.Error(), "err", "нет прав для просмотра истории правок", "uuid", uuid)
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case errors.Is(err, appErrors.ErrAdNotFound):
+			h.logger.Info(appErrors.ErrAdNotFound.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotFound.Error(), http.StatusNotFound)
+		default:
+			h.logger.Error(appErrors.ErrAdRevisions.Error(), "err", err, "uuid", uuid)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
+
+func (h *Handler) GetFlaggedAds(w http.ResponseWriter, r *http.Request) {
+	page := utils.ParseInt(r.URL.Query().Get("page"), 1)
+
+	result, err := h.service.GetFlaggedAds(r.Context(), page)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, appErrors.ErrForbidden):
+			h.logger.Warn(appErrors.ErrForbidden.Error(), "err", "нет прав модератора")
+			http.Error(w, "forbidden", http.StatusForbidden)
+		default:
+			h.logger.Error(appErrors.ErrModeration.Error(), "err", err)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
+
+func (h *Handler) ApproveAd(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuid.Parse(r.PathValue("uuid"))
+	if err != nil {
+		h.logger.Error(appErrors.ErrNotValidUuid.Error(), "err", err, "uuid", uuid)
+		http.Error(w, appErrors.ErrNotValidUuid.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.ApproveAd(r.Context(), uuid)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, appErrors.ErrForbidden):
+			h.logger.Warn(appErrors.ErrForbidden.Error(), "err", "нет прав модератора", "uuid", uuid)
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case errors.Is(err, appErrors.ErrAdNotFound):
+			h.logger.Info(appErrors.ErrAdNotFound.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotFound.Error(), http.StatusNotFound)
+		default:
+			h.logger.Error(appErrors.ErrModeration.Error(), "err", err, "uuid", uuid)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, DeleteAdResponse{true}, http.StatusOK)
+}
+
+func (h *Handler) RestoreAd(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuid.Parse(r.PathValue("uuid"))
+	if err != nil {
+		h.logger.Error(appErrors.ErrNotValidUuid.Error(), "err", err, "uuid", uuid)
+		http.Error(w, appErrors.ErrNotValidUuid.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = h.service.RestoreAd(r.Context(), uuid)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, appErrors.ErrForbidden):
+			h.logger.Warn(appErrors.ErrForbidden.Error(), "err", "нет прав для восстановления объявления", "uuid", uuid)
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case errors.Is(err, appErrors.ErrAdNotFound):
+			h.logger.Info(appErrors.ErrAdNotFound.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotFound.Error(), http.StatusNotFound)
+		case errors.Is(err, appErrors.ErrInvalidStatusTransition):
+			h.logger.Info(appErrors.ErrInvalidStatusTransition.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrInvalidStatusTransition.Error(), http.StatusConflict)
+		case errors.Is(err, appErrors.ErrAdRestoreExpired):
+			h.logger.Info(appErrors.ErrAdRestoreExpired.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdRestoreExpired.Error(), http.StatusGone)
+		case errors.Is(err, appErrors.ErrAdVersionMismatch):
+			h.logger.Info(appErrors.ErrAdVersionMismatch.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdVersionMismatch.Error(), http.StatusPreconditionFailed)
+		case errors.Is(err, appErrors.ErrAdConflict):
+			h.logger.Info(appErrors.ErrAdConflict.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdConflict.Error(), http.StatusConflict)
+		default:
+			h.logger.Error(appErrors.ErrRestoreAd.Error(), "err", err, "uuid", uuid)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, DeleteAdResponse{true}, http.StatusOK)
+}
+
+func (h *Handler) BumpAd(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuid.Parse(r.PathValue("uuid"))
+	if err != nil {
+		h.logger.Error(appErrors.ErrNotValidUuid.Error(), "err", err, "uuid", uuid)
+		http.Error(w, appErrors.ErrNotValidUuid.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.service.BumpAd(r.Context(), uuid)
+
+	if err != nil {
+		var bError *appErrors.BumpNotAllowedError
+		switch {
+		case errors.As(err, &bError):
+			h.logger.Info(appErrors.ErrBumpAd.Error(), "err", err, "uuid", uuid)
+			response.Json(w, bError, http.StatusTooManyRequests)
+		case errors.Is(err, appErrors.ErrForbidden):
+			h.logger.Warn(appErrors.ErrForbidden.Error(), "err", "нет прав для поднятия объявления", "uuid", uuid)
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case errors.Is(err, appErrors.ErrAdNotFound):
+			h.logger.Info(appErrors.ErrAdNotFound.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotFound.Error(), http.StatusNotFound)
+		case errors.Is(err, appErrors.ErrAdNotActive):
+			h.logger.Info(appErrors.ErrAdNotActive.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotActive.Error(), http.StatusConflict)
+		default:
+			h.logger.Error(appErrors.ErrBumpAd.Error(), "err", err, "uuid", uuid)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
+
 func (h *Handler) AddFavorite(w http.ResponseWriter, r *http.Request) {
 	uuid, err := uuid.Parse(r.PathValue("uuid"))
 	if err != nil {
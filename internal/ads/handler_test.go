@@ -0,0 +1,90 @@
+package ads
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vietio/internal/authctx"
+
+	"github.com/google/uuid"
+)
+
+// драйвер пустой базы: любой SELECT возвращает ноль строк
+type emptyDriver struct{}
+
+type emptyConn struct{}
+
+type emptyStmt struct{}
+
+type emptyRows struct{}
+
+func (emptyDriver) Open(string) (driver.Conn, error) { return emptyConn{}, nil }
+
+func (emptyConn) Prepare(string) (driver.Stmt, error) { return emptyStmt{}, nil }
+func (emptyConn) Close() error                        { return nil }
+func (emptyConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (emptyStmt) Close() error                               { return nil }
+func (emptyStmt) NumInput() int                              { return -1 }
+func (emptyStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(0), nil }
+func (emptyStmt) Query([]driver.Value) (driver.Rows, error)  { return emptyRows{}, nil }
+
+func (emptyRows) Columns() []string         { return nil }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
+
+func init() {
+	sql.Register("ads-empty-test", emptyDriver{})
+}
+
+func newEmptyHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	db, err := sql.Open("ads-empty-test", "")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	service := NewService(NewRepository(db), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, 0, nil, nil)
+
+	return NewHandler(service, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func newAdRequest(method string, target string, adUuid uuid.UUID) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.SetPathValue("uuid", adUuid.String())
+
+	return req.WithContext(context.WithValue(req.Context(), authctx.UserIdKey, int64(1)))
+}
+
+func TestDeleteAdNotFound(t *testing.T) {
+	handler := newEmptyHandler(t)
+	adUuid := uuid.New()
+
+	rec := httptest.NewRecorder()
+	handler.DeleteAd(rec, newAdRequest(http.MethodDelete, "/api/ads/"+adUuid.String(), adUuid))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestMarkingSoldAdNotFound(t *testing.T) {
+	handler := newEmptyHandler(t)
+	adUuid := uuid.New()
+
+	rec := httptest.NewRecorder()
+	handler.MarkingSoldAd(rec, newAdRequest(http.MethodPost, "/api/ads/"+adUuid.String()+"/sold", adUuid))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
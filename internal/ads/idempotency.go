@@ -0,0 +1,64 @@
+package ads
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+)
+
+type idempotencyImage struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	Hash string `json:"hash"`
+}
+
+// хэш запроса на создание для сверки повторов с тем же Idempotency-Key:
+// поля формы и содержимое фото
+func createRequestHash(payload CreateAdRequestBody, images []*multipart.FileHeader) (string, error) {
+	request := struct {
+		Payload CreateAdRequestBody `json:"payload"`
+		Images  []idempotencyImage  `json:"images"`
+	}{
+		Payload: payload,
+		Images:  make([]idempotencyImage, 0, len(images)),
+	}
+
+	for _, fileHeader := range images {
+		hash, err := fileHash(fileHeader)
+		if err != nil {
+			return "", err
+		}
+
+		request.Images = append(request.Images, idempotencyImage{
+			Name: fileHeader.Filename,
+			Size: fileHeader.Size,
+			Hash: hash,
+		})
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func fileHash(fileHeader *multipart.FileHeader) (string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
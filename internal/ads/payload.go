@@ -3,6 +3,8 @@ package ads
 import (
 	"time"
 
+	"vietio/internal/user"
+
 	"github.com/google/uuid"
 )
 
@@ -10,6 +12,7 @@ type AdsListQueryParams struct {
 	Page       int
 	CategoryId *int
 	Sort       string
+	Query      string
 }
 
 type AdsListFilterParams struct {
@@ -17,16 +20,80 @@ type AdsListFilterParams struct {
 	Limit      int
 	CategoryId *int
 	Status     *int
-	UserId     *int64
-	Sort       string
-	Order      string
+	// несколько статусов сразу, если Status не задан
+	Statuses []int
+	UserId   *int64
+	Query    *string
+	Sort     string
+	Order    string
+	// закрепленные объявления идут первыми
+	Promoted bool
+	// закрепленные объявления вставляются в ленту через каждые PromotedInterval-1 обычных, 0 — выключено
+	PromotedInterval int
+	// nil — все, false — без ожидающих модерации, true — только они
+	Flagged *bool
+}
+
+type StatusHistoryModel struct {
+	AdUuid     uuid.UUID
+	FromStatus int
+	ToStatus   int
+	// nil — переход сделала система
+	ActorId *int64
+	Reason  string
+}
+
+// значение поля до и после правки
+type RevisionChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+type RevisionModel struct {
+	Id       int64
+	AdUuid   uuid.UUID
+	EditorId *int64
+	// ключ — поле объявления: title, description, price, category_id
+	Changes   map[string]RevisionChange
+	CreatedAt time.Time
+}
+
+// закрытое объявление, у которого истек срок хранения
+type PurgeCandidate struct {
+	Uuid uuid.UUID
+	// по объявлению есть сделка: строку оставляем ради отзывов, удаляем только фото
+	HasDeal bool
+}
+
+type SimilarAdsFilterParams struct {
+	Text          string
+	UserId        *int64
+	ExcludeUserId *int64
+	CategoryId    *int
+	Since         time.Time
+	Threshold     float64
+	Limit         int
+}
+
+type SimilarAdRepository struct {
+	Uuid   uuid.UUID
+	UserId int64
+	Title  string
+	Score  float64
+}
+
+// FileIds — фото, загруженные заранее через POST /api/uploads
+type CreateAdRequestBody struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Price       int     `json:"price"`
+	CategoryId  int     `json:"category_id"`
+	FileIds     []int64 `json:"file_ids"`
 }
 
-type CreateAdRequestBody struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Price       int    `json:"price"`
-	CategoryId  int    `json:"category_id"`
+type MarkSoldRequestBody struct {
+	// id покупателя из GET /api/ads/{uuid}/contacts, необязательно
+	BuyerId *int64 `json:"buyer_id"`
 }
 
 type UpdateAdRequestBody struct {
@@ -36,6 +103,7 @@ type UpdateAdRequestBody struct {
 	Price       int      `json:"price"`
 	CategoryId  int      `json:"category_id"`
 	OldImages   []string `json:"old_images"`
+	FileIds     []int64  `json:"file_ids"`
 }
 
 type AdModel struct {
@@ -47,6 +115,14 @@ type AdModel struct {
 	Price       int
 	Status      int
 	CreatedAt   time.Time
+	BumpedAt    time.Time
+
+	PinnedUntil      *time.Time
+	HighlightedUntil *time.Time
+	ClosedAt         *time.Time
+	Version          int
+	// объявление ждет модерации и скрыто из публичных списков
+	FlaggedAt *time.Time
 }
 
 type AdsListRepository struct {
@@ -55,24 +131,31 @@ type AdsListRepository struct {
 }
 
 type AdsListItemRepository struct {
-	Uuid       uuid.UUID
-	Title      string
-	CategoryId int
-	Price      int
-	Status     int
-	CreatedAt  time.Time
-	Image      string
+	Uuid          uuid.UUID
+	Title         string
+	CategoryId    int
+	Price         int
+	Status        int
+	CreatedAt     time.Time
+	Image         string
+	MasterImage   string
+	IsPinned      bool
+	IsHighlighted bool
 }
 
 type AdsListItemResponse struct {
-	Uuid       uuid.UUID `json:"uuid"`
-	Title      string    `json:"title"`
-	CategoryId int       `json:"category_id"`
-	Price      int       `json:"price"`
-	City       string    `json:"city"`
-	Status     string    `json:"status"`
-	Image      string    `json:"image"`
-	CreatedAt  time.Time `json:"created_at"`
+	Uuid          uuid.UUID         `json:"uuid"`
+	Title         string            `json:"title"`
+	CategoryId    int               `json:"category_id"`
+	Price         int               `json:"price"`
+	City          string            `json:"city"`
+	Status        string            `json:"status"`
+	Image         string            `json:"image"`
+	ImageVariants map[string]string `json:"image_variants"`
+	CreatedAt     time.Time         `json:"created_at"`
+	IsPinned      bool              `json:"is_pinned"`
+	IsHighlighted bool              `json:"is_highlighted"`
+	IsReserved    bool              `json:"is_reserved"`
 }
 
 type AdsListResponse struct {
@@ -98,27 +181,62 @@ type MyFavoritesAdsListResponse struct {
 }
 
 type CreateAdResponse struct {
-	Uuid string `json:"uuid"`
+	Uuid     string `json:"uuid"`
+	ShareUrl string `json:"share_url"`
 }
 
 type UpdateAdResponse struct {
-	Result bool `json:"result"`
+	Result  bool `json:"result"`
+	Version int  `json:"version"`
 }
 
 type DeleteAdResponse struct {
 	Result bool `json:"result"`
 }
 
+type BumpAdResponse struct {
+	Result     bool      `json:"result"`
+	BumpedAt   time.Time `json:"bumped_at"`
+	NextBumpAt time.Time `json:"next_bump_at"`
+}
+
 type AdResponse struct {
-	Uuid          uuid.UUID `json:"uuid"`
-	Title         string    `json:"title"`
-	Description   string    `json:"description"`
-	CategoryId    int       `json:"category_id"`
-	Price         int       `json:"price"`
-	City          string    `json:"city"`
-	CreatedAt     time.Time `json:"created_at"`
-	IsOwner       bool      `json:"is_owner"`
-	IsFavorite    bool      `json:"is_favorite"`
-	OwnerUsername string    `json:"owner_username"`
-	Images        []string  `json:"images"`
+	Uuid             uuid.UUID           `json:"uuid"`
+	Title            string              `json:"title"`
+	Description      string              `json:"description"`
+	CategoryId       int                 `json:"category_id"`
+	Price            int                 `json:"price"`
+	City             string              `json:"city"`
+	CreatedAt        time.Time           `json:"created_at"`
+	BumpedAt         time.Time           `json:"bumped_at"`
+	PinnedUntil      *time.Time          `json:"pinned_until"`
+	HighlightedUntil *time.Time          `json:"highlighted_until"`
+	IsOwner          bool                `json:"is_owner"`
+	IsFavorite       bool                `json:"is_favorite"`
+	Status           string              `json:"status"`
+	IsFlagged        bool                `json:"is_flagged"`
+	OwnerId          int64               `json:"owner_id"`
+	SellerRating     user.Rating         `json:"seller_rating"`
+	Images           []string            `json:"images"`
+	ImageVariants    []map[string]string `json:"image_variants"`
+	ShareUrl         string              `json:"share_url"`
+	PriceHistory     []PriceHistoryItem  `json:"price_history"`
+	Version          int                 `json:"version"`
+}
+
+type PriceHistoryItem struct {
+	Price     int       `json:"price"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type RevisionResponse struct {
+	Id        int64                     `json:"id"`
+	EditorId  *int64                    `json:"editor_id"`
+	Changes   map[string]RevisionChange `json:"changes"`
+	CreatedAt time.Time                 `json:"created_at"`
+}
+
+type RevisionsListResponse struct {
+	Items []RevisionResponse `json:"items"`
+	Total int                `json:"total"`
 }
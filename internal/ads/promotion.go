@@ -0,0 +1,19 @@
+package ads
+
+// виды платного продвижения
+const PROMOTION_PIN = "pin"
+const PROMOTION_HIGHLIGHT = "highlight"
+
+// в общей ленте закрепленное объявление занимает каждое PROMOTED_INTERVAL-е место
+const PROMOTED_INTERVAL = 5
+
+// колонка ads, в которой хранится окончание продвижения
+var promotionColumns = map[string]string{
+	PROMOTION_PIN:       "pinned_until",
+	PROMOTION_HIGHLIGHT: "highlighted_until",
+}
+
+func IsValidPromotion(promotion string) bool {
+	_, ok := promotionColumns[promotion]
+	return ok
+}
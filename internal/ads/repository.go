@@ -3,8 +3,12 @@ package ads
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 	"vietio/internal/authctx"
 
 	"github.com/google/uuid"
@@ -42,13 +46,31 @@ func (repo *Repository) FindAds(ctx context.Context, params AdsListFilterParams)
 		argsPos++
 	}
 
+	if params.Query != nil {
+		conditions = append(conditions, fmt.Sprintf("(title || ' ' || description) ILIKE $%d", argsPos))
+		args = append(args, "%"+escapeLike(*params.Query)+"%")
+		argsPos++
+	}
+
+	if params.Flagged != nil {
+		if *params.Flagged {
+			conditions = append(conditions, "flagged_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "flagged_at IS NULL")
+		}
+	}
+
 	if params.Status != nil {
 		conditions = append(conditions, fmt.Sprintf("status = $%d", argsPos))
 		args = append(args, params.Status)
 		argsPos++
+	} else if len(params.Statuses) > 0 {
+		conditions = append(conditions, fmt.Sprintf("status = ANY($%d)", argsPos))
+		args = append(args, params.Statuses)
+		argsPos++
 	} else {
-		conditions = append(conditions, fmt.Sprintf("status = $%d", argsPos))
-		args = append(args, STATUS_ACTIVE)
+		conditions = append(conditions, fmt.Sprintf("status = ANY($%d)", argsPos))
+		args = append(args, visibleStatuses)
 		argsPos++
 	}
 
@@ -57,6 +79,24 @@ func (repo *Repository) FindAds(ctx context.Context, params AdsListFilterParams)
 		where = "WHERE " + strings.Join(conditions, " AND ")
 	}
 
+	orderBy := ""
+	if params.Promoted {
+		orderBy = "is_pinned DESC, "
+	} else if params.PromotedInterval > 1 {
+		// обычные объявления нумеруются 1, 2, 3..., закрепленное с номером n
+		// встает после n*(interval-1) обычных, порядок внутри групп — как в ленте
+		orderBy = fmt.Sprintf(`
+			CASE WHEN %[1]s
+				THEN row_number() OVER (PARTITION BY %[1]s ORDER BY ads.%[2]s %[3]s) * %[4]d + 0.5
+				ELSE row_number() OVER (PARTITION BY %[1]s ORDER BY ads.%[2]s %[3]s)
+			END, `,
+			"COALESCE(ads.pinned_until > now(), false)",
+			params.Sort,
+			params.Order,
+			params.PromotedInterval-1,
+		)
+	}
+
 	query := fmt.Sprintf(`
         SELECT
 			uuid,
@@ -66,20 +106,24 @@ func (repo *Repository) FindAds(ctx context.Context, params AdsListFilterParams)
 			status,
             created_at,
 			COALESCE(f.preview_path, '') as image,
+			COALESCE(f.path, '') as master_image,
+			COALESCE(ads.pinned_until > now(), false) as is_pinned,
+			COALESCE(ads.highlighted_until > now(), false) as is_highlighted,
             count(*) over() as total
 		FROM ads
 		LEFT JOIN LATERAL (
-			SELECT preview_path
+			SELECT preview_path, path
 			FROM files
 			WHERE files.ad_uuid = ads.uuid
 			ORDER BY created_at ASC
 			LIMIT 1
 		) f ON true
         %s
-        ORDER BY ads.%s %s
+        ORDER BY %sads.%s %s
 		LIMIT %d OFFSET %d
     `,
 		where,
+		orderBy,
 		params.Sort,
 		params.Order,
 		params.Limit,
@@ -102,6 +146,9 @@ func (repo *Repository) FindAds(ctx context.Context, params AdsListFilterParams)
 			&ad.Status,
 			&ad.CreatedAt,
 			&ad.Image,
+			&ad.MasterImage,
+			&ad.IsPinned,
+			&ad.IsHighlighted,
 			&total,
 		); err != nil {
 			return result, err
@@ -115,6 +162,87 @@ func (repo *Repository) FindAds(ctx context.Context, params AdsListFilterParams)
 	return result, nil
 }
 
+// поиск похожих активных объявлений по триграммам (pg_trgm).
+// Оператор % использует индекс ads_text_trgm_idx, порог задается на время транзакции
+func (repo *Repository) FindSimilarAds(ctx context.Context, params SimilarAdsFilterParams) ([]SimilarAdRepository, error) {
+	var result []SimilarAdRepository
+
+	conditions := []string{"status = $1", "created_at > $2", "(title || ' ' || description) % $3"}
+	args := []any{STATUS_ACTIVE, params.Since, params.Text}
+
+	argsPos := 4
+
+	if params.UserId != nil {
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", argsPos))
+		args = append(args, *params.UserId)
+		argsPos++
+	}
+
+	if params.ExcludeUserId != nil {
+		conditions = append(conditions, fmt.Sprintf("user_id <> $%d", argsPos))
+		args = append(args, *params.ExcludeUserId)
+		argsPos++
+	}
+
+	if params.CategoryId != nil {
+		conditions = append(conditions, fmt.Sprintf("category_id = $%d", argsPos))
+		args = append(args, *params.CategoryId)
+		argsPos++
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			uuid,
+			user_id,
+			title,
+			similarity(title || ' ' || description, $3) as score
+		FROM ads
+		WHERE %s
+		ORDER BY score DESC
+		LIMIT %d
+	`,
+		strings.Join(conditions, " AND "),
+		params.Limit,
+	)
+
+	tx, err := repo.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	// SET LOCAL не принимает параметры, set_config(..., true) — то же самое
+	_, err = tx.ExecContext(
+		ctx,
+		"SELECT set_config('pg_trgm.similarity_threshold', $1, true)",
+		strconv.FormatFloat(params.Threshold, 'f', -1, 64),
+	)
+	if err != nil {
+		return result, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ad SimilarAdRepository
+		if err := rows.Scan(
+			&ad.Uuid,
+			&ad.UserId,
+			&ad.Title,
+			&ad.Score,
+		); err != nil {
+			return result, err
+		}
+		result = append(result, ad)
+	}
+
+	return result, rows.Err()
+}
+
 func (repo *Repository) CreateAd(ctx context.Context, tx *sql.Tx, payload CreateAdRequestBody) (uuid.UUID, error) {
 	var uuid uuid.UUID
 
@@ -158,7 +286,11 @@ func (repo *Repository) CreateAd(ctx context.Context, tx *sql.Tx, payload Create
 	return uuid, nil
 }
 
-func (repo *Repository) UpdateAd(ctx context.Context, tx *sql.Tx, ad AdModel) error {
+// обновление только прочитанной версии, возвращает новую версию.
+// sql.ErrNoRows — объявление успели изменить
+func (repo *Repository) UpdateAd(ctx context.Context, tx *sql.Tx, ad AdModel) (int, error) {
+	var version int
+
 	query := `
 		UPDATE ads
 		SET
@@ -166,12 +298,15 @@ func (repo *Repository) UpdateAd(ctx context.Context, tx *sql.Tx, ad AdModel) er
 			description = $2,
 			price = $3,
 			category_id = $4,
+			version = version + 1,
 			updated_at = now()
 		WHERE 
 			uuid = $5
+			AND version = $6
+		RETURNING version
 	`
 
-	_, err := tx.ExecContext(
+	err := tx.QueryRowContext(
 		ctx,
 		query,
 		ad.Title,
@@ -179,18 +314,154 @@ func (repo *Repository) UpdateAd(ctx context.Context, tx *sql.Tx, ad AdModel) er
 		ad.Price,
 		ad.CategoryId,
 		ad.Uuid,
-	)
+		ad.Version,
+	).Scan(&version)
 	if err != nil {
-		return err
+		return version, err
 	}
 	
-	return nil
+	return version, nil
 }
 
-func (repo *Repository) FindAdByUuid(ctx context.Context, uuid uuid.UUID) (AdModel, error) {
-	var result AdModel
+func (repo *Repository) CreateRevisionWithTx(ctx context.Context, tx *sql.Tx, revision RevisionModel) error {
+	changes, err := json.Marshal(revision.Changes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO ad_revisions (
+			ad_uuid,
+			editor_id,
+			changes
+		)
+		VALUES ($1, $2, $3)
+	`
+
+	_, err = tx.ExecContext(ctx, query, revision.AdUuid, revision.EditorId, changes)
+
+	return err
+}
+
+func (repo *Repository) FindRevisionsByAdUuid(ctx context.Context, uuid uuid.UUID) ([]RevisionModel, error) {
+	var result = []RevisionModel{}
+
+	query := `
+		SELECT
+			id,
+			ad_uuid,
+			editor_id,
+			changes,
+			created_at
+		FROM
+			ad_revisions
+		WHERE
+			ad_uuid = $1
+		ORDER BY
+			created_at DESC, id DESC
+	`
+
+	rows, err := repo.db.QueryContext(ctx, query, uuid)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item RevisionModel
+		var changes []byte
+
+		if err := rows.Scan(&item.Id, &item.AdUuid, &item.EditorId, &changes, &item.CreatedAt); err != nil {
+			return result, err
+		}
+		if err := json.Unmarshal(changes, &item.Changes); err != nil {
+			return result, err
+		}
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+// прежние цены объявления из истории правок
+func (repo *Repository) FindPriceHistory(ctx context.Context, uuid uuid.UUID, limit int) ([]PriceHistoryItem, error) {
+	var result = []PriceHistoryItem{}
 
 	query := `
+		SELECT
+			(changes->'price'->>'old')::int8,
+			created_at
+		FROM
+			ad_revisions
+		WHERE
+			ad_uuid = $1
+			AND changes->'price' IS NOT NULL
+		ORDER BY
+			created_at DESC, id DESC
+		LIMIT $2
+	`
+
+	rows, err := repo.db.QueryContext(ctx, query, uuid, limit)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item PriceHistoryItem
+		if err := rows.Scan(&item.Price, &item.ChangedAt); err != nil {
+			return result, err
+		}
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+func (repo *Repository) FlagAdWithTx(ctx context.Context, tx *sql.Tx, uuid uuid.UUID, reason string) error {
+	query := `
+		UPDATE ads
+		SET
+			flagged_at = now(),
+			flag_reason = $1
+		WHERE
+			uuid = $2
+	`
+
+	_, err := tx.ExecContext(ctx, query, reason, uuid)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnflagAd модератор проверил объявление, оно снова показывается в списках
+func (repo *Repository) UnflagAd(ctx context.Context, uuid uuid.UUID) (bool, error) {
+	query := `
+		UPDATE ads
+		SET
+			flagged_at = NULL,
+			flag_reason = NULL
+		WHERE
+			uuid = $1
+			AND flagged_at IS NOT NULL
+	`
+
+	result, err := repo.db.ExecContext(ctx, query, uuid)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+const findAdByUuidQuery = `
         SELECT
 			uuid,
 			title,
@@ -199,13 +470,31 @@ func (repo *Repository) FindAdByUuid(ctx context.Context, uuid uuid.UUID) (AdMod
             category_id,
             price,
 			status,
-            created_at
+            created_at,
+			bumped_at,
+			pinned_until,
+			highlighted_until,
+			closed_at,
+			version,
+			flagged_at
 		FROM ads
 		WHERE uuid = $1
 		LIMIT 1
     `
 
-	err := repo.db.QueryRowContext(ctx, query, uuid).Scan(
+func (repo *Repository) FindAdByUuid(ctx context.Context, uuid uuid.UUID) (AdModel, error) {
+	return scanAd(repo.db.QueryRowContext(ctx, findAdByUuidQuery, uuid))
+}
+
+// чтение внутри транзакции: строка блокируется до коммита
+func (repo *Repository) FindAdByUuidWithTx(ctx context.Context, tx *sql.Tx, uuid uuid.UUID) (AdModel, error) {
+	return scanAd(tx.QueryRowContext(ctx, findAdByUuidQuery+" FOR UPDATE", uuid))
+}
+
+func scanAd(row *sql.Row) (AdModel, error) {
+	var result AdModel
+
+	err := row.Scan(
 		&result.Uuid,
 		&result.Title,
 		&result.Description,
@@ -214,6 +503,12 @@ func (repo *Repository) FindAdByUuid(ctx context.Context, uuid uuid.UUID) (AdMod
 		&result.Price,
 		&result.Status,
 		&result.CreatedAt,
+		&result.BumpedAt,
+		&result.PinnedUntil,
+		&result.HighlightedUntil,
+		&result.ClosedAt,
+		&result.Version,
+		&result.FlaggedAt,
 	)
 	if err != nil {
 		return result, err
@@ -222,6 +517,56 @@ func (repo *Repository) FindAdByUuid(ctx context.Context, uuid uuid.UUID) (AdMod
 	return result, nil
 }
 
+func (repo *Repository) BumpAdWithTx(ctx context.Context, tx *sql.Tx, uuid uuid.UUID) (time.Time, error) {
+	var bumpedAt time.Time
+
+	query := `
+		UPDATE ads
+		SET
+			bumped_at = now(),
+			version = version + 1,
+			updated_at = now()
+		WHERE
+			uuid = $1
+		RETURNING bumped_at
+	`
+
+	err := tx.QueryRowContext(ctx, query, uuid).Scan(&bumpedAt)
+	if err != nil {
+		return bumpedAt, err
+	}
+
+	return bumpedAt, nil
+}
+
+// продлевает продвижение на days дней от текущего окончания или от текущего момента
+func (repo *Repository) PromoteAdWithTx(ctx context.Context, tx *sql.Tx, uuid uuid.UUID, promotion string, days int) (time.Time, error) {
+	var until time.Time
+
+	column, ok := promotionColumns[promotion]
+	if !ok {
+		return until, fmt.Errorf("unknown promotion: %s", promotion)
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE ads
+		SET
+			%[1]s = GREATEST(COALESCE(%[1]s, now()), now()) + make_interval(days => $1),
+			version = version + 1,
+			updated_at = now()
+		WHERE
+			uuid = $2
+		RETURNING %[1]s
+	`, column)
+
+	err := tx.QueryRowContext(ctx, query, days, uuid).Scan(&until)
+	if err != nil {
+		return until, err
+	}
+
+	return until, nil
+}
+
 func (repo *Repository) DeleteAdByUuidWithTx(ctx context.Context, tx *sql.Tx, uuid uuid.UUID) error {
 	query := `
 		DELETE FROM ads
@@ -240,28 +585,150 @@ func (repo *Repository) DeleteAdByUuidWithTx(ctx context.Context, tx *sql.Tx, uu
 	return nil
 }
 
-func (repo *Repository) ChangeStatusAdByUuidWithTx(ctx context.Context, tx *sql.Tx, status int, uuid uuid.UUID) error {
+// смена статуса только из прочитанных статуса и версии: false — объявление уже изменили параллельно.
+// При закрытии запоминаем closed_at, от него отсчитывается срок хранения
+func (repo *Repository) ChangeStatusAdByUuidWithTx(ctx context.Context, tx *sql.Tx, ad AdModel, to int) (bool, error) {
 	query := `
 		UPDATE ads
 		SET
 			status = $1,
+			closed_at = CASE WHEN $4 THEN now() END,
+			version = version + 1,
 			updated_at = now()
 		WHERE 
 			uuid = $2
+			AND status = $3
+			AND version = $5
 	`
 	
-	_, err := tx.ExecContext(
+	res, err := tx.ExecContext(
 		ctx,
 		query,
-		status,
-		uuid,
+		to,
+		ad.Uuid,
+		ad.Status,
+		isClosedStatus(to),
+		ad.Version,
 	)
-	
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
 	}
 	
-	return nil
+	return affected > 0, nil
+}
+
+func (repo *Repository) CreateStatusHistoryWithTx(ctx context.Context, tx *sql.Tx, history StatusHistoryModel) error {
+	query := `
+		INSERT INTO status_history (
+			ad_uuid,
+			from_status,
+			to_status,
+			actor_id,
+			reason
+		)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := tx.ExecContext(
+		ctx,
+		query,
+		history.AdUuid,
+		history.FromStatus,
+		history.ToStatus,
+		history.ActorId,
+		history.Reason,
+	)
+
+	return err
+}
+
+// новый срок размещения для восстановленного объявления
+func (repo *Repository) RenewAdWithTx(ctx context.Context, tx *sql.Tx, uuid uuid.UUID) error {
+	query := `
+		UPDATE ads
+		SET
+			expires_at = CURRENT_DATE + INTERVAL '1 month',
+			updated_at = now()
+		WHERE
+			uuid = $1
+	`
+
+	_, err := tx.ExecContext(ctx, query, uuid)
+
+	return err
+}
+
+// закрытые до before объявления, у которых еще не удалены фото
+func (repo *Repository) FindPurgeCandidates(ctx context.Context, before time.Time) ([]PurgeCandidate, error) {
+	var result = []PurgeCandidate{}
+
+	query := `
+		SELECT
+			a.uuid,
+			EXISTS (SELECT 1 FROM deals AS d WHERE d.ad_uuid = a.uuid) AS has_deal
+		FROM
+			ads AS a
+		WHERE
+			a.closed_at < $1
+			AND a.purged_at IS NULL
+	`
+
+	rows, err := repo.db.QueryContext(ctx, query, before)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item PurgeCandidate
+		if err := rows.Scan(&item.Uuid, &item.HasDeal); err != nil {
+			return result, err
+		}
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+// повторная проверка под блокировкой: объявление могли восстановить после выборки кандидатов
+func (repo *Repository) LockPurgeCandidateWithTx(ctx context.Context, tx *sql.Tx, uuid uuid.UUID, before time.Time) (bool, error) {
+	var result bool
+
+	query := `
+		SELECT true
+		FROM ads
+		WHERE
+			uuid = $1
+			AND closed_at < $2
+			AND purged_at IS NULL
+		FOR UPDATE
+	`
+
+	err := tx.QueryRowContext(ctx, query, uuid, before).Scan(&result)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return result, err
+}
+
+func (repo *Repository) MarkAdPurgedWithTx(ctx context.Context, tx *sql.Tx, uuid uuid.UUID) error {
+	query := `
+		UPDATE ads
+		SET
+			purged_at = now()
+		WHERE
+			uuid = $1
+	`
+
+	_, err := tx.ExecContext(ctx, query, uuid)
+
+	return err
 }
 
 func (r *Repository) Exists(ctx context.Context, uuid uuid.UUID) (bool, error) {
@@ -307,6 +774,39 @@ func (repo *Repository) FindExpiredUuidList(ctx context.Context) ([]string, erro
 	return result, nil
 }
 
+// количество объявлений пользователя по статусам
+func (repo *Repository) CountAdsByUserId(ctx context.Context, userId int64) (map[int]int, error) {
+	var result = make(map[int]int)
+
+	query := `
+		SELECT
+			status,
+			count(*)
+		FROM
+			ads
+		WHERE
+			user_id = $1
+		GROUP BY
+			status
+	`
+
+	rows, err := repo.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status, count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return result, err
+		}
+		result[status] = count
+	}
+
+	return result, rows.Err()
+}
+
 func (repo *Repository) FindFavoritesAdsByUserId(ctx context.Context, userId int64) (AdsListRepository, error) {
 	var result AdsListRepository
 
@@ -322,24 +822,26 @@ func (repo *Repository) FindFavoritesAdsByUserId(ctx context.Context, userId int
 			t2.status,
 			t2.created_at,
 			COALESCE(t3.preview_path, '') as image,
+			COALESCE(t3.path, '') as master_image,
 			count(*) over() as total
 		FROM wishlist AS t1
 		LEFT JOIN ads as t2 on t2.uuid = t1.ad_uuid
 		LEFT JOIN LATERAL (
-			SELECT preview_path
+			SELECT preview_path, path
 			FROM files
 			WHERE files.ad_uuid = t2.uuid
 			ORDER BY created_at ASC
 			LIMIT 1
 		) t3 ON true
 		WHERE 
-			t2.status IN (1, 4)
+			t2.status = ANY($2)
 			AND t1.user_id=$1
 		ORDER BY
-			t2.status ASC, t1.created_at DESC
+			t2.status = $3, t1.created_at DESC
     `
 
-	rows, err := repo.db.QueryContext(ctx, query, userId)
+	// проданные в конце списка
+	rows, err := repo.db.QueryContext(ctx, query, userId, favoriteStatuses, STATUS_SOLD)
 	if err != nil {
 		return result, err
 	}
@@ -355,6 +857,7 @@ func (repo *Repository) FindFavoritesAdsByUserId(ctx context.Context, userId int
 			&ad.Status,
 			&ad.CreatedAt,
 			&ad.Image,
+			&ad.MasterImage,
 			&total,
 		); err != nil {
 			return result, err
@@ -367,3 +870,8 @@ func (repo *Repository) FindFavoritesAdsByUserId(ctx context.Context, userId int
 
 	return result, nil
 }
+
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return replacer.Replace(value)
+}
@@ -0,0 +1,24 @@
+package ads
+
+// сколько прежних цен показываем в карточке объявления
+const priceHistoryLimit = 5
+
+// diffAd изменившиеся поля объявления для ad_revisions
+func diffAd(before AdModel, after AdModel) map[string]RevisionChange {
+	changes := make(map[string]RevisionChange)
+
+	if before.Title != after.Title {
+		changes["title"] = RevisionChange{Old: before.Title, New: after.Title}
+	}
+	if before.Description != after.Description {
+		changes["description"] = RevisionChange{Old: before.Description, New: after.Description}
+	}
+	if before.Price != after.Price {
+		changes["price"] = RevisionChange{Old: before.Price, New: after.Price}
+	}
+	if before.CategoryId != after.CategoryId {
+		changes["category_id"] = RevisionChange{Old: before.CategoryId, New: after.CategoryId}
+	}
+
+	return changes
+}
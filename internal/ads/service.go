@@ -3,39 +3,93 @@ package ads
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"mime/multipart"
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"vietio/internal/authctx"
+	"vietio/internal/contentfilter"
 	appErrors "vietio/internal/errors"
 	fileApp "vietio/internal/file"
+	"vietio/internal/idempotency"
 	"vietio/internal/user"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// сколько раз повторяем смену статуса после конфликта serializable-транзакций
+const serializationRetries = 3
+
 var allowedSort = map[string]string{
-	"date":  "created_at",
+	"date":  "bumped_at",
 	"price": "price",
 }
 
 type Service struct {
-	repo         *Repository
-	fileRepo     FileRepository
-	userRepo     UserRepository
-	wishlistRepo WishlistRepository
-	storage      FileStorage
-	validator    *Validator
+	repo          *Repository
+	fileRepo      FileRepository
+	userRepo      UserRepository
+	wishlistRepo  WishlistRepository
+	storage       FileStorage
+	validator     *Validator
+	contentFilter ContentFilter
+	duplicates    *DuplicateChecker
+	bumpPolicy    BumpPolicy
+	events        AdEvents
+	links         LinkBuilder
+	reputation    Reputation
+	// сколько хранятся фото закрытого объявления, пока его можно восстановить
+	retention     time.Duration
+	idempotency   IdempotencyStore
+	imageVariants ImageVariants
+}
+
+// AdEvents уведомляется о жизненном цикле объявления после коммита.
+// Ошибки обработчик логирует сам: на ответ пользователю они не влияют
+type AdEvents interface {
+	AdPublished(ctx context.Context, uuid uuid.UUID)
+	AdUpdated(ctx context.Context, uuid uuid.UUID)
+	AdClosed(ctx context.Context, uuid uuid.UUID, status int)
+}
+
+// ссылки t.me/<bot>/<app>?startapp= для шаринга
+type LinkBuilder interface {
+	Ad(uuid string) string
+}
+
+// сделки и рейтинг продавца (reviews.Service)
+type Reputation interface {
+	IsBuyerContact(ctx context.Context, adUuid uuid.UUID, sellerId int64, buyerId int64) (bool, error)
+	// сделка пишется в транзакции смены статуса, приглашения оценить — после коммита
+	RecordDealWithTx(ctx context.Context, tx *sql.Tx, ad AdModel, buyerId int64) (dealId int64, created bool, err error)
+	SendDealPrompts(ctx context.Context, dealId int64) error
+	CancelDealWithTx(ctx context.Context, tx *sql.Tx, adUuid uuid.UUID) error
+	GetUserRating(ctx context.Context, userId int64) (user.Rating, error)
+}
+
+// подписанные ссылки на варианты фото для /img (images.Signer)
+type ImageVariants interface {
+	GetVariantPaths(path string) map[string]string
+}
+
+// Idempotency-Key для создания объявлений (idempotency.Repository)
+type IdempotencyStore interface {
+	Begin(ctx context.Context, userId int64, key string, requestHash string) (idempotency.KeyModel, bool, error)
+	CompleteWithTx(ctx context.Context, tx *sql.Tx, userId int64, key string, response []byte) error
+	Release(ctx context.Context, userId int64, key string) error
 }
 
 type FileRepository interface {
 	Save(context.Context, *sql.Tx, fileApp.FileModel) error
 	DeleteById(context.Context, *sql.Tx, int64) error
 	FindFilesByAdUuid(context.Context, uuid.UUID) ([]fileApp.FileModel, error)
+	AttachStagedWithTx(ctx context.Context, tx *sql.Tx, userId int64, adUuid uuid.UUID, ids []int64) (int, error)
 }
 
 type UserRepository interface {
@@ -48,6 +102,10 @@ type WishlistRepository interface {
 	HasUserWishlistByAdUuid(ctx context.Context, userId int64, adUuid uuid.UUID) (bool, error)
 }
 
+type ContentFilter interface {
+	Check(ctx context.Context, categoryId int, fields []contentfilter.Field) (contentfilter.Result, error)
+}
+
 func NewService(
 	repo *Repository,
 	fileRepository FileRepository,
@@ -55,21 +113,40 @@ func NewService(
 	wishlistRepository WishlistRepository,
 	storage FileStorage,
 	validator *Validator,
+	contentFilter ContentFilter,
+	duplicates *DuplicateChecker,
+	bumpPolicy BumpPolicy,
+	events AdEvents,
+	links LinkBuilder,
+	reputation Reputation,
+	retention time.Duration,
+	idempotencyStore IdempotencyStore,
+	imageVariants ImageVariants,
 ) *Service {
 	return &Service{
-		repo:         repo,
-		fileRepo:     fileRepository,
-		userRepo:     userRepository,
-		wishlistRepo: wishlistRepository,
-		storage:      storage,
-		validator:    validator,
+		repo:          repo,
+		fileRepo:      fileRepository,
+		userRepo:      userRepository,
+		wishlistRepo:  wishlistRepository,
+		storage:       storage,
+		validator:     validator,
+		contentFilter: contentFilter,
+		duplicates:    duplicates,
+		bumpPolicy:    bumpPolicy,
+		events:        events,
+		links:         links,
+		reputation:    reputation,
+		retention:     retention,
+		idempotency:   idempotencyStore,
+		imageVariants: imageVariants,
 	}
 }
 
 func (s *Service) GetAds(ctx context.Context, params AdsListQueryParams) (AdsListResponse, error) {
 	var categoryId *int
+	var query *string
 	page := 1
-	sort := "created_at"
+	sort := "bumped_at"
 	order := "desc"
 
 	if params.Page > 0 {
@@ -91,12 +168,23 @@ func (s *Service) GetAds(ctx context.Context, params AdsListQueryParams) (AdsLis
 		categoryId = params.CategoryId
 	}
 
+	if q := strings.TrimSpace(params.Query); q != "" {
+		query = &q
+	}
+
+	// объявления на модерации в публичные списки не попадают
+	flagged := false
 	filterParams := AdsListFilterParams{
 		Page:       page,
 		CategoryId: categoryId,
+		Query:      query,
 		Sort:       sort,
 		Order:      order,
 		Limit:      20,
+		// закрепленные наверху своей категории, в общей ленте — вперемешку с обычными
+		Promoted:         categoryId != nil,
+		PromotedInterval: PROMOTED_INTERVAL,
+		Flagged:          &flagged,
 	}
 
 	adsListRepository, err := s.repo.FindAds(ctx, filterParams)
@@ -108,14 +196,18 @@ func (s *Service) GetAds(ctx context.Context, params AdsListQueryParams) (AdsLis
 
 	for _, adItem := range adsListRepository.Items {
 		items = append(items, AdsListItemResponse{
-			Uuid:       adItem.Uuid,
-			Title:      adItem.Title,
-			CategoryId: adItem.CategoryId,
-			Price:      adItem.Price,
-			City:       "Нячанг",
-			Status:     getTextStatus(adItem.Status),
-			Image:      s.storage.GetPublicPath(adItem.Image),
-			CreatedAt:  adItem.CreatedAt,
+			Uuid:          adItem.Uuid,
+			Title:         adItem.Title,
+			CategoryId:    adItem.CategoryId,
+			Price:         adItem.Price,
+			City:          "Нячанг",
+			Status:        getTextStatus(adItem.Status),
+			Image:         s.storage.GetPublicPath(adItem.Image),
+			ImageVariants: s.imageVariants.GetVariantPaths(adItem.MasterImage),
+			CreatedAt:     adItem.CreatedAt,
+			IsPinned:      adItem.IsPinned,
+			IsReserved:    adItem.Status == STATUS_RESERVED,
+			IsHighlighted: adItem.IsHighlighted,
 		})
 	}
 
@@ -135,11 +227,12 @@ func (s *Service) GetMyAds(ctx context.Context) (MyAdsListResponse, error) {
 	}
 
 	filterParams := AdsListFilterParams{
-		Page:   1,
-		Sort:   "created_at",
-		UserId: &userId,
-		Order:  "desc",
-		Limit:  1000,
+		Page:     1,
+		Sort:     "created_at",
+		UserId:   &userId,
+		Statuses: visibleStatuses,
+		Order:    "desc",
+		Limit:    1000,
 	}
 	adsListRepository, err := s.repo.FindAds(ctx, filterParams)
 	if err != nil {
@@ -150,14 +243,18 @@ func (s *Service) GetMyAds(ctx context.Context) (MyAdsListResponse, error) {
 
 	for _, adItem := range adsListRepository.Items {
 		items = append(items, AdsListItemResponse{
-			Uuid:       adItem.Uuid,
-			Title:      adItem.Title,
-			CategoryId: adItem.CategoryId,
-			Price:      adItem.Price,
-			City:       "Нячанг",
-			Status:     getTextStatus(adItem.Status),
-			Image:      s.storage.GetPublicPath(adItem.Image),
-			CreatedAt:  adItem.CreatedAt,
+			Uuid:          adItem.Uuid,
+			Title:         adItem.Title,
+			CategoryId:    adItem.CategoryId,
+			Price:         adItem.Price,
+			City:          "Нячанг",
+			Status:        getTextStatus(adItem.Status),
+			Image:         s.storage.GetPublicPath(adItem.Image),
+			ImageVariants: s.imageVariants.GetVariantPaths(adItem.MasterImage),
+			CreatedAt:     adItem.CreatedAt,
+			IsPinned:      adItem.IsPinned,
+			IsReserved:    adItem.Status == STATUS_RESERVED,
+			IsHighlighted: adItem.IsHighlighted,
 		})
 	}
 
@@ -208,6 +305,54 @@ func (s *Service) GetMySoldAds(ctx context.Context) (MySoldAdsListResponse, erro
 	return result, nil
 }
 
+// активные объявления пользователя для его публичного профиля
+func (s *Service) GetUserAds(ctx context.Context, userId int64, page int) (AdsListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	flagged := false
+	filterParams := AdsListFilterParams{
+		Page:     page,
+		Sort:     "bumped_at",
+		UserId:   &userId,
+		Statuses: visibleStatuses,
+		Order:    "desc",
+		Limit:    20,
+		Flagged:  &flagged,
+	}
+	adsListRepository, err := s.repo.FindAds(ctx, filterParams)
+	if err != nil {
+		return AdsListResponse{}, err
+	}
+
+	items := make([]AdsListItemResponse, 0, len(adsListRepository.Items))
+
+	for _, adItem := range adsListRepository.Items {
+		items = append(items, AdsListItemResponse{
+			Uuid:          adItem.Uuid,
+			Title:         adItem.Title,
+			CategoryId:    adItem.CategoryId,
+			Price:         adItem.Price,
+			City:          "Нячанг",
+			Status:        getTextStatus(adItem.Status),
+			Image:         s.storage.GetPublicPath(adItem.Image),
+			ImageVariants: s.imageVariants.GetVariantPaths(adItem.MasterImage),
+			CreatedAt:     adItem.CreatedAt,
+			IsPinned:      adItem.IsPinned,
+			IsReserved:    adItem.Status == STATUS_RESERVED,
+			IsHighlighted: adItem.IsHighlighted,
+		})
+	}
+
+	return AdsListResponse{
+		Items: items,
+		Total: adsListRepository.Total,
+		Limit: filterParams.Limit,
+		Page:  filterParams.Page,
+	}, nil
+}
+
 func (s *Service) GetMyFavoritesAds(ctx context.Context) (MyFavoritesAdsListResponse, error) {
 	var result MyFavoritesAdsListResponse
 	userId, err := authctx.GeUserIdFromContext(ctx)
@@ -225,20 +370,25 @@ func (s *Service) GetMyFavoritesAds(ctx context.Context) (MyFavoritesAdsListResp
 	for _, adItem := range adsListRepository.Items {
 		status := getTextStatus(adItem.Status)
 		var image string
+		var imageVariants map[string]string
 
-		if status == "active" {
+		// у проданных фото скоро удалит PurgeClosedAds
+		if IsVisibleStatus(adItem.Status) {
 			image = s.storage.GetPublicPath(adItem.Image)
+			imageVariants = s.imageVariants.GetVariantPaths(adItem.MasterImage)
 		}
 
 		items = append(items, AdsListItemResponse{
-			Uuid:       adItem.Uuid,
-			Title:      adItem.Title,
-			CategoryId: adItem.CategoryId,
-			Price:      adItem.Price,
-			City:       "Нячанг",
-			Status:     status,
-			Image:      image,
-			CreatedAt:  adItem.CreatedAt,
+			Uuid:          adItem.Uuid,
+			Title:         adItem.Title,
+			CategoryId:    adItem.CategoryId,
+			Price:         adItem.Price,
+			City:          "Нячанг",
+			Status:        status,
+			Image:         image,
+			ImageVariants: imageVariants,
+			CreatedAt:     adItem.CreatedAt,
+			IsReserved:    adItem.Status == STATUS_RESERVED,
 		})
 	}
 
@@ -248,14 +398,95 @@ func (s *Service) GetMyFavoritesAds(ctx context.Context) (MyFavoritesAdsListResp
 	return result, nil
 }
 
-func (s *Service) CreateAd(ctx context.Context, payload CreateAdRequestBody, images []*multipart.FileHeader) (CreateAdResponse, error) {
+// CreateAd с непустым idempotencyKey повтор того же запроса в течение суток
+// возвращает первый ответ, а тот же ключ с другими данными — ошибку
+func (s *Service) CreateAd(ctx context.Context, payload CreateAdRequestBody, images []*multipart.FileHeader, idempotencyKey string) (CreateAdResponse, error) {
+	if idempotencyKey == "" {
+		return s.createAd(ctx, payload, images, nil)
+	}
+
 	result := CreateAdResponse{}
 
+	userId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	if len(idempotencyKey) > idempotency.MAX_KEY_LENGTH {
+		validationErrors := appErrors.NewValidationError()
+		validationErrors.Add("idempotency_key", fmt.Sprintf("Idempotency-Key должен быть не длиннее %d символов", idempotency.MAX_KEY_LENGTH))
+		return result, validationErrors
+	}
+
+	requestHash, err := createRequestHash(payload, images)
+	if err != nil {
+		return result, err
+	}
+
+	record, started, err := s.idempotency.Begin(ctx, userId, idempotencyKey, requestHash)
+	if err != nil {
+		return result, err
+	}
+
+	if !started {
+		switch {
+		case record.RequestHash != requestHash:
+			return result, appErrors.ErrIdempotencyKeyReused
+		case record.IsPending():
+			return result, appErrors.ErrIdempotencyInProgress
+		}
+
+		err = json.Unmarshal(record.Response, &result)
+		return result, err
+	}
+
+	// ответ сохраняется в той же транзакции, что и объявление
+	complete := func(tx *sql.Tx, result CreateAdResponse) error {
+		response, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		return s.idempotency.CompleteWithTx(ctx, tx, userId, idempotencyKey, response)
+	}
+
+	result, err = s.createAd(ctx, payload, images, complete)
+	if err != nil {
+		releaseErr := s.idempotency.Release(context.WithoutCancel(ctx), userId, idempotencyKey)
+		return result, errors.Join(err, releaseErr)
+	}
+
+	return result, nil
+}
+
+func (s *Service) createAd(
+	ctx context.Context,
+	payload CreateAdRequestBody,
+	images []*multipart.FileHeader,
+	beforeCommit func(tx *sql.Tx, result CreateAdResponse) error,
+) (CreateAdResponse, error) {
+	result := CreateAdResponse{}
+
+	userId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
 	validationErrors := s.validator.createAdValidate(ctx, payload, images)
 	if validationErrors.HasErrors() {
 		return result, validationErrors
 	}
 
+	flagReason, err := s.filterContent(ctx, payload.CategoryId, &payload.Title, &payload.Description)
+	if err != nil {
+		return result, err
+	}
+
+	duplicateReason, err := s.duplicates.Check(ctx, userId, payload.CategoryId, payload.Title, payload.Description)
+	if err != nil {
+		return result, err
+	}
+	flagReason = joinReasons(flagReason, duplicateReason)
+
 	tx, err := s.repo.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
 		return result, err
@@ -267,27 +498,47 @@ func (s *Service) CreateAd(ctx context.Context, payload CreateAdRequestBody, ima
 		return result, fmt.Errorf("возникла ошибка при сохранении объявления: %w", err)
 	}
 
+	if flagReason != "" {
+		err = s.repo.FlagAdWithTx(ctx, tx, uuid, flagReason)
+		if err != nil {
+			return result, err
+		}
+	}
+
 	err = s.saveNewImages(ctx, tx, uuid, images)
 	if err != nil {
 		return result, err
 	}
 
+	err = s.attachStagedFiles(ctx, tx, userId, uuid, payload.FileIds)
+	if err != nil {
+		return result, err
+	}
+
 	result.Uuid = uuid.String()
+	result.ShareUrl = s.links.Ad(result.Uuid)
+
+	if beforeCommit != nil {
+		err = beforeCommit(tx, result)
+		if err != nil {
+			return result, err
+		}
+	}
 
 	if err := tx.Commit(); err != nil {
-		return result, nil
+		return result, err
 	}
 
-	return result, err
+	s.events.AdPublished(ctx, uuid)
+
+	return result, nil
 }
 
+// GetAd доступен и без авторизации: тогда is_owner и is_favorite всегда false
 func (s *Service) GetAd(ctx context.Context, uuid uuid.UUID) (AdResponse, error) {
 	var result AdResponse
 
-	ctxUserId, err := authctx.GeUserIdFromContext(ctx)
-	if err != nil {
-		return result, err
-	}
+	ctxUserId, _ := authctx.GeUserIdFromContext(ctx)
 
 	adModel, err := s.repo.FindAdByUuid(ctx, uuid)
 	if err != nil {
@@ -297,16 +548,29 @@ func (s *Service) GetAd(ctx context.Context, uuid uuid.UUID) (AdResponse, error)
 		return result, err
 	}
 
-	if adModel.Status != STATUS_ACTIVE {
+	if !IsVisibleStatus(adModel.Status) {
 		return result, appErrors.ErrAdNotActive
 	}
 
+	// до модерации объявление видят только владелец и модераторы
+	if adModel.FlaggedAt != nil && adModel.UserId != ctxUserId {
+		isModerator, err := s.isModerator(ctx, ctxUserId)
+		if err != nil {
+			return result, err
+		}
+		if !isModerator {
+			return result, appErrors.ErrAdNotFound
+		}
+	}
+
 	adFiles, err := s.fileRepo.FindFilesByAdUuid(ctx, uuid)
 	if err != nil {
 		return result, err
 	}
 
-	adOwner, err := s.userRepo.GetUserById(ctx, adModel.UserId)
+	// владелец должен существовать, но его username наружу не отдаем:
+	// связаться с продавцом можно только через переписку в боте
+	_, err = s.userRepo.GetUserById(ctx, adModel.UserId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return result, appErrors.ErrAdUserNotFound
@@ -314,33 +578,225 @@ func (s *Service) GetAd(ctx context.Context, uuid uuid.UUID) (AdResponse, error)
 		return result, err
 	}
 
-	isFavorite, err := s.wishlistRepo.HasUserWishlistByAdUuid(ctx, ctxUserId, uuid)
-	if err != nil {
-		return result, appErrors.ErrAdFavorite
+	isFavorite := false
+	if ctxUserId != 0 {
+		isFavorite, err = s.wishlistRepo.HasUserWishlistByAdUuid(ctx, ctxUserId, uuid)
+		if err != nil {
+			return result, appErrors.ErrAdFavorite
+		}
 	}
 
 	var images = make([]string, 0, len(adFiles))
+	var imageVariants = make([]map[string]string, 0, len(adFiles))
 	for _, file := range adFiles {
 		publicPath := s.storage.GetPublicPath(file.Path)
 		images = append(images, publicPath)
+		imageVariants = append(imageVariants, s.imageVariants.GetVariantPaths(file.Path))
+	}
+
+	sellerRating, err := s.reputation.GetUserRating(ctx, adModel.UserId)
+	if err != nil {
+		return result, err
+	}
+
+	priceHistory, err := s.repo.FindPriceHistory(ctx, adModel.Uuid, priceHistoryLimit)
+	if err != nil {
+		return result, err
 	}
 
 	return AdResponse{
-		Uuid:          adModel.Uuid,
-		Title:         adModel.Title,
-		Description:   adModel.Description,
-		CategoryId:    adModel.CategoryId,
-		Price:         adModel.Price,
-		City:          "Нячанг",
-		CreatedAt:     adModel.CreatedAt,
-		IsOwner:       adModel.UserId == ctxUserId,
-		IsFavorite:    isFavorite,
-		OwnerUsername: adOwner.Username,
-		Images:        images,
+		Uuid:             adModel.Uuid,
+		Title:            adModel.Title,
+		Description:      adModel.Description,
+		CategoryId:       adModel.CategoryId,
+		Price:            adModel.Price,
+		City:             "Нячанг",
+		CreatedAt:        adModel.CreatedAt,
+		BumpedAt:         adModel.BumpedAt,
+		PinnedUntil:      adModel.PinnedUntil,
+		HighlightedUntil: adModel.HighlightedUntil,
+		IsOwner:          ctxUserId != 0 && adModel.UserId == ctxUserId,
+		IsFavorite:       isFavorite,
+		Status:           getTextStatus(adModel.Status),
+		IsFlagged:        adModel.FlaggedAt != nil,
+		OwnerId:          adModel.UserId,
+		SellerRating:     sellerRating,
+		Images:           images,
+		ImageVariants:    imageVariants,
+		ShareUrl:         s.links.Ad(adModel.Uuid.String()),
+		PriceHistory:     priceHistory,
+		Version:          adModel.Version,
+	}, nil
+}
+
+// GetFlaggedAds очередь модерации: объявления, отмеченные контент-фильтром, от старых к новым
+func (s *Service) GetFlaggedAds(ctx context.Context, page int) (AdsListResponse, error) {
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return AdsListResponse{}, err
+	}
+
+	isModerator, err := s.isModerator(ctx, contextUserId)
+	if err != nil {
+		return AdsListResponse{}, err
+	}
+	if !isModerator {
+		return AdsListResponse{}, appErrors.ErrForbidden
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	flagged := true
+	filterParams := AdsListFilterParams{
+		Page:     page,
+		Sort:     "created_at",
+		Statuses: visibleStatuses,
+		Order:    "asc",
+		Limit:    20,
+		Flagged:  &flagged,
+	}
+	adsListRepository, err := s.repo.FindAds(ctx, filterParams)
+	if err != nil {
+		return AdsListResponse{}, err
+	}
+
+	items := make([]AdsListItemResponse, 0, len(adsListRepository.Items))
+
+	for _, adItem := range adsListRepository.Items {
+		items = append(items, AdsListItemResponse{
+			Uuid:          adItem.Uuid,
+			Title:         adItem.Title,
+			CategoryId:    adItem.CategoryId,
+			Price:         adItem.Price,
+			City:          "Нячанг",
+			Status:        getTextStatus(adItem.Status),
+			Image:         s.storage.GetPublicPath(adItem.Image),
+			ImageVariants: s.imageVariants.GetVariantPaths(adItem.MasterImage),
+			CreatedAt:     adItem.CreatedAt,
+			IsPinned:      adItem.IsPinned,
+			IsReserved:    adItem.Status == STATUS_RESERVED,
+			IsHighlighted: adItem.IsHighlighted,
+		})
+	}
+
+	return AdsListResponse{
+		Items: items,
+		Total: adsListRepository.Total,
+		Limit: filterParams.Limit,
+		Page:  filterParams.Page,
 	}, nil
 }
 
-func (s *Service) UpdateAd(ctx context.Context, payload UpdateAdRequestBody, images []*multipart.FileHeader) (UpdateAdResponse, error) {
+// ApproveAd модератор снимает отметку фильтра, объявление появляется в списках и каналах
+func (s *Service) ApproveAd(ctx context.Context, uuid uuid.UUID) error {
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	isModerator, err := s.isModerator(ctx, contextUserId)
+	if err != nil {
+		return err
+	}
+	if !isModerator {
+		return appErrors.ErrForbidden
+	}
+
+	ad, err := s.repo.FindAdByUuid(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return appErrors.ErrAdNotFound
+		}
+		return err
+	}
+
+	approved, err := s.repo.UnflagAd(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	// публикуем в каналы только то, что до этого было скрыто
+	if approved && IsVisibleStatus(ad.Status) {
+		s.events.AdUpdated(ctx, uuid)
+	}
+
+	return nil
+}
+
+func (s *Service) isModerator(ctx context.Context, userId int64) (bool, error) {
+	if userId == 0 {
+		return false, nil
+	}
+
+	contextUser, err := s.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return contextUser.IsModerator, nil
+}
+
+// GetAdRevisions история правок объявления для владельца и модераторов
+func (s *Service) GetAdRevisions(ctx context.Context, uuid uuid.UUID) (RevisionsListResponse, error) {
+	var result RevisionsListResponse
+
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	ad, err := s.repo.FindAdByUuid(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErrors.ErrAdNotFound
+		}
+		return result, err
+	}
+
+	if ad.UserId != contextUserId {
+		isModerator, err := s.isModerator(ctx, contextUserId)
+		if err != nil {
+			return result, err
+		}
+		if !isModerator {
+			return result, appErrors.ErrForbidden
+		}
+	}
+
+	items, err := s.repo.FindRevisionsByAdUuid(ctx, uuid)
+	if err != nil {
+		return result, err
+	}
+
+	result.Items = make([]RevisionResponse, 0, len(items))
+	for _, item := range items {
+		result.Items = append(result.Items, RevisionResponse{
+			Id:        item.Id,
+			EditorId:  item.EditorId,
+			Changes:   item.Changes,
+			CreatedAt: item.CreatedAt,
+		})
+	}
+	result.Total = len(result.Items)
+
+	return result, nil
+}
+
+// UpdateAd expectedVersion — версия из If-Match, nil — без проверки
+// конфликт serializable-транзакции не повторяем: удаление и сохранение фото в хранилище
+// не откатываются вместе с транзакцией, клиент получает 409 и отправляет правку заново
+func (s *Service) UpdateAd(ctx context.Context, payload UpdateAdRequestBody, images []*multipart.FileHeader, expectedVersion *int) (UpdateAdResponse, error) {
+	result, err := s.updateAd(ctx, payload, images, expectedVersion)
+	if isSerializationFailure(err) {
+		return result, fmt.Errorf("%w: %w", appErrors.ErrAdConflict, err)
+	}
+
+	return result, err
+}
+
+func (s *Service) updateAd(ctx context.Context, payload UpdateAdRequestBody, images []*multipart.FileHeader, expectedVersion *int) (UpdateAdResponse, error) {
 	result := UpdateAdResponse{}
 
 	contextUserId, err := authctx.GeUserIdFromContext(ctx)
@@ -353,14 +809,22 @@ func (s *Service) UpdateAd(ctx context.Context, payload UpdateAdRequestBody, ima
 		return result, validationErrors
 	}
 
+	flagReason, err := s.filterContent(ctx, payload.CategoryId, &payload.Title, &payload.Description)
+	if err != nil {
+		return result, err
+	}
+
 	tx, err := s.repo.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
 		return result, err
 	}
 	defer tx.Rollback()
 
-	ad, err := s.repo.FindAdByUuid(ctx, payload.Uuid)
+	ad, err := s.repo.FindAdByUuidWithTx(ctx, tx, payload.Uuid)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErrors.ErrAdNotFound
+		}
 		return result, err
 	}
 
@@ -368,16 +832,48 @@ func (s *Service) UpdateAd(ctx context.Context, payload UpdateAdRequestBody, ima
 		return result, appErrors.ErrForbidden
 	}
 
+	// закрытое объявление сначала восстанавливают, правки в архиве не нужны
+	if !IsVisibleStatus(ad.Status) {
+		return result, appErrors.ErrAdNotEditable
+	}
+
+	if expectedVersion != nil && *expectedVersion != ad.Version {
+		return result, appErrors.ErrAdVersionMismatch
+	}
+
+	before := ad
+
 	ad.Title = payload.Title
 	ad.Description = payload.Description
 	ad.Price = payload.Price
 	ad.CategoryId = payload.CategoryId
 
-	err = s.repo.UpdateAd(ctx, tx, ad)
+	result.Version, err = s.repo.UpdateAd(ctx, tx, ad)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErrors.ErrAdVersionMismatch
+		}
 		return result, err
 	}
 
+	if changes := diffAd(before, ad); len(changes) > 0 {
+		err = s.repo.CreateRevisionWithTx(ctx, tx, RevisionModel{
+			AdUuid:   ad.Uuid,
+			EditorId: &contextUserId,
+			Changes:  changes,
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if flagReason != "" {
+		err = s.repo.FlagAdWithTx(ctx, tx, ad.Uuid, flagReason)
+		if err != nil {
+			return result, err
+		}
+	}
+
 	var oldImagesMap = make(map[string]bool)
 
 	for _, i := range payload.OldImages {
@@ -423,11 +919,18 @@ func (s *Service) UpdateAd(ctx context.Context, payload UpdateAdRequestBody, ima
 		return result, err
 	}
 
+	err = s.attachStagedFiles(ctx, tx, contextUserId, payload.Uuid, payload.FileIds)
+	if err != nil {
+		return result, err
+	}
+
 	if err := tx.Commit(); err != nil {
-		return result, nil
+		return result, err
 	}
 
-	return result, err
+	s.events.AdUpdated(ctx, payload.Uuid)
+
+	return result, nil
 }
 
 func (s *Service) ArchiveAd(ctx context.Context, uuid uuid.UUID) error {
@@ -436,10 +939,10 @@ func (s *Service) ArchiveAd(ctx context.Context, uuid uuid.UUID) error {
 		return err
 	}
 
-	return s.processDeleteAd(ctx, ad, STATUS_EXPIRED)
+	return s.changeStatus(ctx, ad, STATUS_EXPIRED, nil, "истек срок размещения", nil)
 }
 
-func (s *Service) DeleteAd(ctx context.Context, uuid uuid.UUID) error {
+func (s *Service) DeleteAd(ctx context.Context, uuid uuid.UUID, expectedVersion *int) error {
 	contextUserId, err := authctx.GeUserIdFromContext(ctx)
 	if err != nil {
 		return err
@@ -447,6 +950,9 @@ func (s *Service) DeleteAd(ctx context.Context, uuid uuid.UUID) error {
 
 	ad, err := s.repo.FindAdByUuid(ctx, uuid)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return appErrors.ErrAdNotFound
+		}
 		return err
 	}
 
@@ -454,17 +960,186 @@ func (s *Service) DeleteAd(ctx context.Context, uuid uuid.UUID) error {
 		return appErrors.ErrForbidden
 	}
 
-	return s.processDeleteAd(ctx, ad, STATUS_USER_DELETED)
+	if expectedVersion != nil && *expectedVersion != ad.Version {
+		return appErrors.ErrAdVersionMismatch
+	}
+
+	return s.changeStatus(ctx, ad, STATUS_USER_DELETED, &contextUserId, "удалено владельцем", nil)
+}
+
+// ReserveAd покупатель найден: объявление остается видно, но с пометкой «забронировано»
+func (s *Service) ReserveAd(ctx context.Context, uuid uuid.UUID) error {
+	return s.changeOwnStatus(ctx, uuid, STATUS_ACTIVE, STATUS_RESERVED, "забронировано владельцем")
+}
+
+// UnreserveAd сделка сорвалась, объявление снова активно
+func (s *Service) UnreserveAd(ctx context.Context, uuid uuid.UUID) error {
+	return s.changeOwnStatus(ctx, uuid, STATUS_RESERVED, STATUS_ACTIVE, "бронь снята владельцем")
 }
 
-func (s *Service) processDeleteAd(ctx context.Context, ad AdModel, finalStatus int) error {
+// RestoreAd возвращает удаленное, проданное или истекшее объявление в активные
+// с новым сроком размещения, пока не истек срок хранения фото
+func (s *Service) RestoreAd(ctx context.Context, uuid uuid.UUID) error {
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	ad, err := s.repo.FindAdByUuid(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return appErrors.ErrAdNotFound
+		}
+		return err
+	}
+
+	if ad.UserId != contextUserId {
+		return appErrors.ErrForbidden
+	}
+
+	if !isClosedStatus(ad.Status) {
+		return appErrors.ErrInvalidStatusTransition
+	}
+
+	if ad.ClosedAt == nil || time.Now().After(ad.ClosedAt.Add(s.retention)) {
+		return appErrors.ErrAdRestoreExpired
+	}
+
+	var cancelDeal func(tx *sql.Tx) error
+	if ad.Status == STATUS_SOLD {
+		cancelDeal = func(tx *sql.Tx) error {
+			return s.reputation.CancelDealWithTx(ctx, tx, ad.Uuid)
+		}
+	}
+
+	return s.changeStatus(ctx, ad, STATUS_ACTIVE, &contextUserId, "восстановлено владельцем", cancelDeal)
+}
+
+func (s *Service) changeOwnStatus(ctx context.Context, uuid uuid.UUID, from int, status int, reason string) error {
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	ad, err := s.repo.FindAdByUuid(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return appErrors.ErrAdNotFound
+		}
+		return err
+	}
+
+	if ad.UserId != contextUserId {
+		return appErrors.ErrForbidden
+	}
+
+	if ad.Status != from {
+		return appErrors.ErrInvalidStatusTransition
+	}
+
+	return s.changeStatus(ctx, ad, status, &contextUserId, reason, nil)
+}
+
+// changeStatus единственное место смены статуса: проверяет переход по statusTransitions,
+// пишет status_history, восстановленному объявлению продлевает срок размещения.
+// Фото закрытых объявлений не удаляются: это делает PurgeClosedAds после срока хранения
+// withTx выполняется в той же транзакции, что и смена статуса (сделка при продаже и восстановлении)
+func (s *Service) changeStatus(
+	ctx context.Context,
+	ad AdModel,
+	status int,
+	actorId *int64,
+	reason string,
+	withTx func(tx *sql.Tx) error,
+) error {
+	if !CanTransition(ad.Status, status) {
+		return appErrors.ErrInvalidStatusTransition
+	}
+
+	// транзакция только из запросов к базе, поэтому ее можно безопасно повторить
+	var err error
+	for attempt := 0; attempt < serializationRetries; attempt++ {
+		err = s.tryChangeStatus(ctx, ad, status, actorId, reason, withTx)
+		if !isSerializationFailure(err) {
+			break
+		}
+	}
+	if isSerializationFailure(err) {
+		return fmt.Errorf("%w: %w", appErrors.ErrAdConflict, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if isClosedStatus(status) {
+		s.events.AdClosed(ctx, ad.Uuid, status)
+	} else {
+		s.events.AdUpdated(ctx, ad.Uuid)
+	}
+
+	return nil
+}
+
+func (s *Service) tryChangeStatus(
+	ctx context.Context,
+	ad AdModel,
+	status int,
+	actorId *int64,
+	reason string,
+	withTx func(tx *sql.Tx) error,
+) error {
 	tx, err := s.repo.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	files, err := s.fileRepo.FindFilesByAdUuid(ctx, ad.Uuid)
+	// условие UPDATE включает version, которая растет при любом изменении:
+	// объявление успели изменить после того, как мы его прочитали
+	changed, err := s.repo.ChangeStatusAdByUuidWithTx(ctx, tx, ad, status)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return appErrors.ErrAdVersionMismatch
+	}
+
+	err = s.repo.CreateStatusHistoryWithTx(ctx, tx, StatusHistoryModel{
+		AdUuid:     ad.Uuid,
+		FromStatus: ad.Status,
+		ToStatus:   status,
+		ActorId:    actorId,
+		Reason:     reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	if isClosedStatus(ad.Status) {
+		err = s.repo.RenewAdWithTx(ctx, tx, ad.Uuid)
+		if err != nil {
+			return err
+		}
+	}
+
+	if withTx != nil {
+		err = withTx(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SQLSTATE 40001: serializable-транзакция конфликтует с параллельной
+func isSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "40001"
+}
+
+func (s *Service) deleteFilesWithTx(ctx context.Context, tx *sql.Tx, adUuid uuid.UUID) error {
+	files, err := s.fileRepo.FindFilesByAdUuid(ctx, adUuid)
 	if err != nil {
 		return err
 	}
@@ -486,15 +1161,50 @@ func (s *Service) processDeleteAd(ctx context.Context, ad AdModel, finalStatus i
 		}
 	}
 
-	err = s.repo.ChangeStatusAdByUuidWithTx(ctx, tx, finalStatus, ad.Uuid)
+	return nil
+}
+
+// проверка текста объявления контент-фильтром:
+// запрещенное возвращается ошибкой валидации, маскировка применяется к title и description,
+// для отправки на модерацию возвращается причина
+func (s *Service) filterContent(ctx context.Context, categoryId int, title *string, description *string) (string, error) {
+	filterResult, err := s.contentFilter.Check(ctx, categoryId, []contentfilter.Field{
+		{Name: "title", Value: *title},
+		{Name: "description", Value: *description},
+	})
 	if err != nil {
-		return err
+		return "", err
 	}
-	if err := tx.Commit(); err != nil {
-		return nil
+
+	if filterResult.IsRejected() {
+		validationErrors := appErrors.NewValidationError()
+		for _, m := range filterResult.Rejected() {
+			validationErrors.Add(m.Field, m.Message)
+		}
+		return "", validationErrors
+	}
+
+	*title = filterResult.Value("title")
+	*description = filterResult.Value("description")
+
+	var reasons []string
+	for _, m := range filterResult.Flagged() {
+		reasons = append(reasons, m.Field+": "+m.Message)
+	}
+
+	return strings.Join(reasons, "; "), nil
+}
+
+func joinReasons(reasons ...string) string {
+	var result []string
+
+	for _, r := range reasons {
+		if r != "" {
+			result = append(result, r)
+		}
 	}
 
-	return err
+	return strings.Join(result, "; ")
 }
 
 func (s *Service) saveNewImages(
@@ -535,6 +1245,26 @@ func (s *Service) saveNewImages(
 	return nil
 }
 
+// прикрепляет к объявлению фото, загруженные заранее этим пользователем
+func (s *Service) attachStagedFiles(ctx context.Context, tx *sql.Tx, userId int64, adUuid uuid.UUID, fileIds []int64) error {
+	if len(fileIds) == 0 {
+		return nil
+	}
+
+	attached, err := s.fileRepo.AttachStagedWithTx(ctx, tx, userId, adUuid, fileIds)
+	if err != nil {
+		return err
+	}
+
+	if attached != len(fileIds) {
+		validationErrors := appErrors.NewValidationError()
+		validationErrors.Add("file_ids", "файлы не найдены или уже прикреплены к объявлению")
+		return validationErrors
+	}
+
+	return nil
+}
+
 func (s *Service) ArchivingAds(ctx context.Context) error {
 	uuidList, err := s.repo.FindExpiredUuidList(ctx)
 	if err != nil {
@@ -552,7 +1282,62 @@ func (s *Service) ArchivingAds(ctx context.Context) error {
 	return nil
 }
 
-func (s *Service) MarkingSoldAd(ctx context.Context, uuid uuid.UUID) error {
+// PurgeClosedAds окончательно удаляет закрытые объявления, у которых истек срок хранения:
+// фото удаляются всегда, строка остается только если по объявлению была сделка
+func (s *Service) PurgeClosedAds(ctx context.Context) (int, error) {
+	before := time.Now().Add(-s.retention)
+
+	candidates, err := s.repo.FindPurgeCandidates(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, candidate := range candidates {
+		ok, err := s.purgeAd(ctx, candidate, before)
+		if err != nil {
+			return purged, err
+		}
+		if ok {
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+func (s *Service) purgeAd(ctx context.Context, candidate PurgeCandidate, before time.Time) (bool, error) {
+	tx, err := s.repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	locked, err := s.repo.LockPurgeCandidateWithTx(ctx, tx, candidate.Uuid, before)
+	if err != nil || !locked {
+		return false, err
+	}
+
+	err = s.deleteFilesWithTx(ctx, tx, candidate.Uuid)
+	if err != nil {
+		return false, err
+	}
+
+	if candidate.HasDeal {
+		err = s.repo.MarkAdPurgedWithTx(ctx, tx, candidate.Uuid)
+	} else {
+		err = s.repo.DeleteAdByUuidWithTx(ctx, tx, candidate.Uuid)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// продавец может указать покупателя из тех, кто писал ему по объявлению:
+// тогда создается сделка и обеим сторонам приходит предложение оставить отзыв
+func (s *Service) MarkingSoldAd(ctx context.Context, uuid uuid.UUID, payload MarkSoldRequestBody, expectedVersion *int) error {
 	contextUserId, err := authctx.GeUserIdFromContext(ctx)
 	if err != nil {
 		return err
@@ -560,6 +1345,9 @@ func (s *Service) MarkingSoldAd(ctx context.Context, uuid uuid.UUID) error {
 
 	ad, err := s.repo.FindAdByUuid(ctx, uuid)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return appErrors.ErrAdNotFound
+		}
 		return err
 	}
 
@@ -567,7 +1355,103 @@ func (s *Service) MarkingSoldAd(ctx context.Context, uuid uuid.UUID) error {
 		return appErrors.ErrForbidden
 	}
 
-	return s.processDeleteAd(ctx, ad, STATUS_SOLD)
+	if expectedVersion != nil && *expectedVersion != ad.Version {
+		return appErrors.ErrAdVersionMismatch
+	}
+
+	if !CanTransition(ad.Status, STATUS_SOLD) {
+		return appErrors.ErrInvalidStatusTransition
+	}
+
+	if payload.BuyerId != nil {
+		isContact, err := s.reputation.IsBuyerContact(ctx, ad.Uuid, ad.UserId, *payload.BuyerId)
+		if err != nil {
+			return err
+		}
+		if !isContact {
+			return appErrors.ErrBuyerNotContact
+		}
+	}
+
+	var dealId int64
+	var dealCreated bool
+	var recordDeal func(tx *sql.Tx) error
+	if payload.BuyerId != nil {
+		recordDeal = func(tx *sql.Tx) error {
+			var err error
+			dealId, dealCreated, err = s.reputation.RecordDealWithTx(ctx, tx, ad, *payload.BuyerId)
+			return err
+		}
+	}
+
+	err = s.changeStatus(ctx, ad, STATUS_SOLD, &contextUserId, "продано", recordDeal)
+	if err != nil {
+		return err
+	}
+
+	if dealCreated {
+		return s.reputation.SendDealPrompts(ctx, dealId)
+	}
+
+	return nil
+}
+
+func (s *Service) BumpAd(ctx context.Context, uuid uuid.UUID) (BumpAdResponse, error) {
+	var result BumpAdResponse
+
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	// read committed + FOR UPDATE: параллельный bump ждет коммита
+	// и проверяет кулдаун уже по новому bumped_at
+	tx, err := s.repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	ad, err := s.repo.FindAdByUuidWithTx(ctx, tx, uuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErrors.ErrAdNotFound
+		}
+		return result, err
+	}
+
+	if ad.UserId != contextUserId {
+		return result, appErrors.ErrForbidden
+	}
+
+	if ad.Status != STATUS_ACTIVE {
+		return result, appErrors.ErrAdNotActive
+	}
+
+	err = s.bumpPolicy.Allow(ctx, ad)
+	if err != nil {
+		return result, err
+	}
+
+	bumpedAt, err := s.repo.BumpAdWithTx(ctx, tx, ad.Uuid)
+	if err != nil {
+		return result, err
+	}
+
+	err = s.bumpPolicy.Consume(ctx, tx, ad)
+	if err != nil {
+		return result, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+
+	result.Result = true
+	result.BumpedAt = bumpedAt
+	result.NextBumpAt = s.bumpPolicy.NextBumpAt(ad, bumpedAt)
+
+	return result, nil
 }
 
 func (s *Service) AddFavorite(ctx context.Context, uuid uuid.UUID) error {
@@ -5,6 +5,46 @@ const STATUS_USER_DELETED = 2
 const STATUS_EXPIRED = 3
 const STATUS_SOLD = 4
 
+// покупатель найден, ждем передачи товара
+const STATUS_RESERVED = 5
+
+// разрешенные переходы статусов, все остальные — 409.
+// Из закрытых статусов вернуть в активные можно только через restore в пределах срока хранения
+var statusTransitions = map[int][]int{
+	STATUS_ACTIVE:       {STATUS_RESERVED, STATUS_SOLD, STATUS_USER_DELETED, STATUS_EXPIRED},
+	STATUS_RESERVED:     {STATUS_ACTIVE, STATUS_SOLD, STATUS_USER_DELETED},
+	STATUS_USER_DELETED: {STATUS_ACTIVE},
+	STATUS_EXPIRED:      {STATUS_ACTIVE},
+	STATUS_SOLD:         {STATUS_ACTIVE},
+}
+
+// CanTransition можно ли перевести объявление из статуса from в to
+func CanTransition(from int, to int) bool {
+	for _, status := range statusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// статусы объявлений в ленте, поиске и профиле продавца: забронированные видны с пометкой
+var visibleStatuses = []int{STATUS_ACTIVE, STATUS_RESERVED}
+
+// в избранном проданные остаются, чтобы покупатель видел, что товар ушел
+var favoriteStatuses = []int{STATUS_ACTIVE, STATUS_RESERVED, STATUS_SOLD}
+
+// IsVisibleStatus объявление открывается по ссылке и показывается в профиле продавца
+func IsVisibleStatus(status int) bool {
+	return status == STATUS_ACTIVE || status == STATUS_RESERVED
+}
+
+// объявление снято с публикации: фото хранятся до конца срока хранения
+func isClosedStatus(status int) bool {
+	return status == STATUS_USER_DELETED || status == STATUS_EXPIRED || status == STATUS_SOLD
+}
+
 func getTextStatus(codeStatus int) string {
 	switch codeStatus {
 	case STATUS_ACTIVE:
@@ -15,6 +55,8 @@ func getTextStatus(codeStatus int) string {
 		return "expired"
 	case STATUS_SOLD:
 		return "sold"
+	case STATUS_RESERVED:
+		return "reserved"
 	default:
 		return ""
 	}
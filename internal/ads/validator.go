@@ -45,7 +45,9 @@ func (v *Validator) createAdValidate(
         payload.CategoryId,
     )
 
-	if len(images) == 0 || len(images) > 3 {
+	// фото из формы и загруженные заранее
+	countImages := len(images) + len(payload.FileIds)
+	if countImages == 0 || countImages > 3 {
 		errors.Add("images", "images должен быть > 0 и меньше 3")
 	}
 
@@ -77,7 +79,7 @@ func (v *Validator) updateAdValidate(
 	}
 
 	// общее количество картинок
-	countImages := len(images) + len(payload.OldImages)
+	countImages := len(images) + len(payload.OldImages) + len(payload.FileIds)
 	if countImages == 0 || countImages > 3 {
 		errors.Add("images", "общее количество изображений должно быть > 0 и <= 3")
 	}
@@ -141,3 +143,23 @@ func validateIntField(
 
 	return result
 }
+
+// список id из повторяющегося поля формы: file_ids=1&file_ids=2
+func validateIdsField(
+	fieldName string,
+	fieldValues []string,
+	errors *appErrors.ValidationError,
+) []int64 {
+	result := make([]int64, 0, len(fieldValues))
+
+	for _, value := range fieldValues {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 1 {
+			errors.Add(fieldName, "в поле должны быть id файлов")
+			return nil
+		}
+		result = append(result, id)
+	}
+
+	return result
+}
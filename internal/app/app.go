@@ -3,19 +3,37 @@ package app
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"vietio/config"
 	"vietio/internal/ads"
 	"vietio/internal/auth"
+	"vietio/internal/bot"
 	"vietio/internal/categories"
+	"vietio/internal/channels"
+	"vietio/internal/contentfilter"
+	"vietio/internal/conversations"
 	"vietio/internal/db/seed"
+	"vietio/internal/deeplink"
 	"vietio/internal/file"
+	"vietio/internal/idempotency"
+	"vietio/internal/images"
 	"vietio/internal/middleware"
+	"vietio/internal/notifications"
+	"vietio/internal/payments"
+	"vietio/internal/profile"
+	"vietio/internal/reviews"
+	"vietio/internal/share"
 	"vietio/internal/storage"
 	"vietio/internal/telegram"
+	"vietio/internal/uploads"
 	"vietio/internal/user"
 	"vietio/internal/wishlist"
 	"vietio/migrations"
@@ -71,34 +89,164 @@ func RunSeed(dbConn *sql.DB, config *config.Config, logger *slog.Logger) {
 }
 
 func RunArchive(dbConn *sql.DB, config *config.Config, logger *slog.Logger) {
+	adsService := newJobAdsService(dbConn, config, logger)
+
+	err := adsService.ArchivingAds(context.Background())
+	if err != nil {
+		logger.Error("ошибка при отправке объявлений в архив", "err", err)
+		os.Exit(1)
+	}
+
+	logger.Info("объявления успешно отправлены в архив")
+}
+
+// удаление фото и строк закрытых объявлений после срока хранения
+func RunPurge(dbConn *sql.DB, config *config.Config, logger *slog.Logger) {
+	adsService := newJobAdsService(dbConn, config, logger)
+
+	purged, err := adsService.PurgeClosedAds(context.Background())
+	if err != nil {
+		logger.Error("ошибка при удалении закрытых объявлений", "err", err, "purged", purged)
+		os.Exit(1)
+	}
+
+	logger.Info("закрытые объявления удалены", "count", purged)
+}
+
+// ads.Service для фоновых задач, запускаемых по флагу
+func newJobAdsService(dbConn *sql.DB, config *config.Config, logger *slog.Logger) *ads.Service {
 	adsRepository := ads.NewRepository(dbConn)
 	categoryRepository := categories.NewRepository(dbConn)
 	fileRepository := file.NewFileRepository(dbConn)
 	userRepository := user.NewRepository(dbConn)
 	wishlistRepository := wishlist.NewRepository(dbConn)
 	adValidator := ads.NewValidator(categoryRepository, adsRepository)
+	contentFilter := contentfilter.NewService(contentfilter.NewRepository(dbConn), logger)
+	duplicateChecker := ads.NewDuplicateChecker(adsRepository, config.Duplicates)
+	bumpPolicy := ads.NewCooldownBumpPolicy(config.Bump.Cooldown)
 
 	fileStorage, err := getFileStorage(config, logger)
 	if err != nil {
 		os.Exit(1)
 	}
 
-	adsService := ads.NewService(
+	imageSigner, err := getImageSigner(config, logger)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	tgClient := telegram.NewClient(config.BotToken, config.Telegram.ApiUrl)
+	links := deeplink.NewBuilder(config.Telegram.BotUsername, config.Telegram.MiniAppName)
+
+	notificationsService := notifications.NewService(
+		notifications.NewRepository(dbConn),
+		tgClient,
+		userRepository,
+		logger,
+	)
+	channelsService := channels.NewService(
+		channels.NewRepository(dbConn),
+		tgClient,
+		notificationsService,
+		imageSigner,
+		links,
+		logger,
+	)
+	reviewsService := reviews.NewService(
+		reviews.NewRepository(dbConn),
+		userRepository,
+		notificationsService,
+		contentFilter,
+	)
+
+	return ads.NewService(
 		adsRepository,
 		fileRepository,
 		userRepository,
 		wishlistRepository,
 		fileStorage,
 		adValidator,
+		contentFilter,
+		duplicateChecker,
+		bumpPolicy,
+		channelsService,
+		links,
+		reviewsService,
+		config.Ads.Retention,
+		idempotency.NewRepository(dbConn),
+		imageSigner,
 	)
+}
+
+func RunSetWebhook(config *config.Config, logger *slog.Logger) {
+	tgClient := telegram.NewClient(config.BotToken, config.Telegram.ApiUrl)
 
-	err = adsService.ArchivingAds(context.Background())
+	err := tgClient.SetWebhook(context.Background(), telegram.SetWebhookParams{
+		Url:            config.Server.PublicUrl + "/api/webhook",
+		SecretToken:    config.Telegram.WebhookSecret,
+		AllowedUpdates: telegram.AllowedUpdates,
+	})
 	if err != nil {
-		logger.Error("ошибка при отправке объявлений в архив", "err", err)
+		logger.Error("ошибка при регистрации вебхука", "err", err)
 		os.Exit(1)
 	}
 
-	logger.Info("объявления успешно отправлены в архив")
+	logger.Info("вебхук успешно зарегистрирован", "url", config.Server.PublicUrl+"/api/webhook")
+}
+
+// удаление устаревших служебных данных
+func RunCleanup(dbConn *sql.DB, config *config.Config, logger *slog.Logger) {
+	ctx := context.Background()
+
+	deleted, err := telegram.NewRepository(dbConn).DeleteProcessedBefore(ctx, time.Now().AddDate(0, 0, -1))
+	if err != nil {
+		logger.Error("ошибка при удалении обработанных апдейтов telegram", "err", err)
+		os.Exit(1)
+	}
+
+	logger.Info("обработанные апдейты telegram удалены", "count", deleted)
+
+	deleted, err = notifications.NewRepository(dbConn).DeleteSentBefore(ctx, time.Now().AddDate(0, 0, -7))
+	if err != nil {
+		logger.Error("ошибка при удалении отправленных уведомлений", "err", err)
+		os.Exit(1)
+	}
+
+	logger.Info("отправленные уведомления удалены", "count", deleted)
+
+	deleted, err = bot.NewRepository(dbConn).DeleteDialogsBefore(ctx, time.Now().AddDate(0, 0, -1))
+	if err != nil {
+		logger.Error("ошибка при удалении брошенных диалогов бота", "err", err)
+		os.Exit(1)
+	}
+
+	logger.Info("брошенные диалоги бота удалены", "count", deleted)
+
+	deleted, err = idempotency.NewRepository(dbConn).DeleteBefore(ctx, time.Now().Add(-idempotency.TTL))
+	if err != nil {
+		logger.Error("ошибка при удалении истекших ключей идемпотентности", "err", err)
+		os.Exit(1)
+	}
+
+	logger.Info("истекшие ключи идемпотентности удалены", "count", deleted)
+
+	fileStorage, err := getFileStorage(config, logger)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	staged, err := uploads.NewService(
+		file.NewFileRepository(dbConn),
+		uploads.NewRepository(dbConn),
+		fileStorage,
+		config.Uploads,
+	).DeleteExpired(ctx)
+	if err != nil {
+		logger.Error("ошибка при удалении неприкрепленных фото", "err", err, "count", staged)
+		os.Exit(1)
+	}
+
+	logger.Info("неприкрепленные фото удалены", "count", staged)
 }
 
 func RunHttpServer(dbConn *sql.DB, config *config.Config, logger *slog.Logger) {
@@ -108,12 +256,44 @@ func RunHttpServer(dbConn *sql.DB, config *config.Config, logger *slog.Logger) {
 	userRepository := user.NewRepository(dbConn)
 	wishlistRepository := wishlist.NewRepository(dbConn)
 	adValidator := ads.NewValidator(categoryRepository, adsRepository)
+	contentFilter := contentfilter.NewService(contentfilter.NewRepository(dbConn), logger)
+	duplicateChecker := ads.NewDuplicateChecker(adsRepository, config.Duplicates)
+	bumpPolicy := ads.NewCooldownBumpPolicy(config.Bump.Cooldown)
 
 	fileStorage, err := getFileStorage(config, logger)
 	if err != nil {
 		os.Exit(1)
 	}
 
+	imageSigner, err := getImageSigner(config, logger)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	tgClient := telegram.NewClient(config.BotToken, config.Telegram.ApiUrl)
+	links := deeplink.NewBuilder(config.Telegram.BotUsername, config.Telegram.MiniAppName)
+
+	notificationsService := notifications.NewService(
+		notifications.NewRepository(dbConn),
+		tgClient,
+		userRepository,
+		logger,
+	)
+	channelsService := channels.NewService(
+		channels.NewRepository(dbConn),
+		tgClient,
+		notificationsService,
+		imageSigner,
+		links,
+		logger,
+	)
+	reviewsService := reviews.NewService(
+		reviews.NewRepository(dbConn),
+		userRepository,
+		notificationsService,
+		contentFilter,
+	)
+
 	adsService := ads.NewService(
 		adsRepository,
 		fileRepository,
@@ -121,39 +301,132 @@ func RunHttpServer(dbConn *sql.DB, config *config.Config, logger *slog.Logger) {
 		wishlistRepository,
 		fileStorage,
 		adValidator,
+		contentFilter,
+		duplicateChecker,
+		bumpPolicy,
+		channelsService,
+		links,
+		reviewsService,
+		config.Ads.Retention,
+		idempotency.NewRepository(dbConn),
+		imageSigner,
 	)
 	adsHandler := ads.NewHandler(adsService, logger)
+	shareHandler := share.NewHandler(adsService, config.Server.PublicUrl, logger)
+	profileHandler := profile.NewHandler(
+		profile.NewService(userRepository, adsRepository, adsService, reviewsService, links),
+		logger,
+	)
+	uploadsHandler := uploads.NewHandler(
+		uploads.NewService(fileRepository, uploads.NewRepository(dbConn), fileStorage, config.Uploads),
+		config.Uploads.MaxSize,
+		logger,
+	)
+
+	imageStorage, ok := fileStorage.(images.Storage)
+	if !ok {
+		logger.Error("хранилище не поддерживает ресайз фото", "storage", config.StorageType)
+		os.Exit(1)
+	}
+	imagesHandler := images.NewHandler(
+		images.NewService(imageStorage, config.Images.Concurrency),
+		imageSigner,
+		logger,
+	)
 
 	authValidator := auth.NewValidator()
 	authService := auth.NewService(config, authValidator, userRepository)
 	authHandler := auth.NewHandler(authService)
 
-	tgClient := telegram.NewClient(config.BotToken)
-	telegramHandler := telegram.NewHandler(logger, tgClient)
+	paymentsService := payments.NewService(
+		payments.NewRepository(dbConn),
+		adsRepository,
+		userRepository,
+		tgClient,
+		config.Payments,
+	)
+	paymentsHandler := payments.NewHandler(paymentsService, logger)
+
+	conversationsService := conversations.NewService(
+		conversations.NewRepository(dbConn),
+		adsRepository,
+		userRepository,
+		notificationsService,
+		imageSigner,
+		links,
+	)
+	conversationsHandler := conversations.NewHandler(conversationsService, logger)
+	reviewsHandler := reviews.NewHandler(reviewsService, logger)
+
+	telegramRepository := telegram.NewRepository(dbConn)
+	telegramRouter := telegram.NewRouter()
+	bot.NewBot(
+		logger,
+		tgClient,
+		notificationsService,
+		adsService,
+		categoryRepository,
+		userRepository,
+		bot.NewRepository(dbConn),
+		paymentsService,
+		conversationsService,
+		reviewsService,
+		links,
+	).Register(
+		telegramRouter,
+		telegramRepository,
+	)
+	telegramHandler := telegram.NewHandler(logger, telegramRouter, config.Telegram.WebhookSecret)
 
 	// middleware
 	authMiddleware := middleware.AuthJWT(authService)
+	optionalAuthMiddleware := middleware.OptionalAuthJWT(authService)
 
 	router := http.NewServeMux()
 
 	// публичные роуты
 	router.HandleFunc("GET /api/ads", adsHandler.GetAds)
 	router.HandleFunc("POST /api/auth/login", authHandler.GetToken)
-	router.HandleFunc("POST /api/webhook", telegramHandler.Webhook)
+	router.HandleFunc("GET /ad/{uuid}", shareHandler.GetAdPage)
+	router.HandleFunc("GET /api/users/{id}", profileHandler.GetProfile)
+	router.HandleFunc("GET /api/users/{id}/reviews", reviewsHandler.GetUserReviews)
+	// доступ по подписи в ссылке из POST /api/uploads/presign
+	router.HandleFunc("PUT /api/uploads/direct/{key}", uploadsHandler.ReceiveDirectUpload)
+	// варианты фото по подписанным ссылкам из image_variants
+	router.HandleFunc("GET /img/{path}", imagesHandler.GetImage)
+
+	// публичные роуты, которые учитывают пользователя, если он авторизован
+	router.Handle(
+		"GET /api/ads/{uuid}",
+		optionalAuthMiddleware(http.HandlerFunc(adsHandler.GetAd)),
+	)
+
+	// в режиме polling апдейты забирает Poller, вебхук не нужен
+	if !config.Telegram.IsPolling() {
+		router.HandleFunc("POST /api/webhook", telegramHandler.Webhook)
+	}
 
 	// роуты с авторизацией
 	router.Handle(
 		"GET /api/my",
 		authMiddleware(http.HandlerFunc(adsHandler.GetMyAds)),
 	)
-	router.Handle(
-		"GET /api/ads/{uuid}",
-		authMiddleware(http.HandlerFunc(adsHandler.GetAd)),
-	)
 	router.Handle(
 		"POST /api/ads",
 		authMiddleware(http.HandlerFunc(adsHandler.CreateAd)),
 	)
+	router.Handle(
+		"POST /api/uploads",
+		authMiddleware(http.HandlerFunc(uploadsHandler.UploadImage)),
+	)
+	router.Handle(
+		"POST /api/uploads/presign",
+		authMiddleware(http.HandlerFunc(uploadsHandler.PresignUpload)),
+	)
+	router.Handle(
+		"POST /api/uploads/{id}/complete",
+		authMiddleware(http.HandlerFunc(uploadsHandler.CompleteUpload)),
+	)
 	router.Handle(
 		"PUT /api/ads/{uuid}",
 		authMiddleware(http.HandlerFunc(adsHandler.UpdateAd)),
@@ -168,6 +441,44 @@ func RunHttpServer(dbConn *sql.DB, config *config.Config, logger *slog.Logger) {
 		authMiddleware(http.HandlerFunc(adsHandler.MarkingSoldAd)),
 	)
 
+	router.Handle(
+		"POST /api/ads/{uuid}/reserve",
+		authMiddleware(http.HandlerFunc(adsHandler.ReserveAd)),
+	)
+
+	router.Handle(
+		"DELETE /api/ads/{uuid}/reserve",
+		authMiddleware(http.HandlerFunc(adsHandler.UnreserveAd)),
+	)
+
+	router.Handle(
+		"GET /api/ads/{uuid}/revisions",
+		authMiddleware(http.HandlerFunc(adsHandler.GetAdRevisions)),
+	)
+
+	router.Handle(
+		"POST /api/ads/{uuid}/restore",
+		authMiddleware(http.HandlerFunc(adsHandler.RestoreAd)),
+	)
+	router.Handle(
+		"POST /api/ads/{uuid}/approve",
+		authMiddleware(http.HandlerFunc(adsHandler.ApproveAd)),
+	)
+	router.Handle(
+		"GET /api/moderation/ads",
+		authMiddleware(http.HandlerFunc(adsHandler.GetFlaggedAds)),
+	)
+
+	router.Handle(
+		"POST /api/ads/{uuid}/bump",
+		authMiddleware(http.HandlerFunc(adsHandler.BumpAd)),
+	)
+
+	router.Handle(
+		"POST /api/ads/{uuid}/promote",
+		authMiddleware(http.HandlerFunc(paymentsHandler.PromoteAd)),
+	)
+
 	router.Handle(
 		"GET /api/my/sold",
 		authMiddleware(http.HandlerFunc(adsHandler.GetMySoldAds)),
@@ -188,6 +499,51 @@ func RunHttpServer(dbConn *sql.DB, config *config.Config, logger *slog.Logger) {
 		authMiddleware(http.HandlerFunc(adsHandler.GetMyFavoritesAds)),
 	)
 
+	router.Handle(
+		"POST /api/ads/{uuid}/conversations",
+		authMiddleware(http.HandlerFunc(conversationsHandler.StartConversation)),
+	)
+
+	router.Handle(
+		"GET /api/ads/{uuid}/contacts",
+		authMiddleware(http.HandlerFunc(conversationsHandler.GetAdContacts)),
+	)
+
+	router.Handle(
+		"GET /api/my/conversations",
+		authMiddleware(http.HandlerFunc(conversationsHandler.GetMyConversations)),
+	)
+
+	router.Handle(
+		"GET /api/my/conversations/{id}",
+		authMiddleware(http.HandlerFunc(conversationsHandler.GetConversation)),
+	)
+
+	router.Handle(
+		"POST /api/my/conversations/{id}/block",
+		authMiddleware(http.HandlerFunc(conversationsHandler.BlockConversation)),
+	)
+
+	router.Handle(
+		"DELETE /api/my/conversations/{id}/block",
+		authMiddleware(http.HandlerFunc(conversationsHandler.UnblockConversation)),
+	)
+
+	router.Handle(
+		"POST /api/my/conversations/{id}/report",
+		authMiddleware(http.HandlerFunc(conversationsHandler.ReportConversation)),
+	)
+
+	router.Handle(
+		"GET /api/my/deals",
+		authMiddleware(http.HandlerFunc(reviewsHandler.GetMyDeals)),
+	)
+
+	router.Handle(
+		"POST /api/deals/{id}/reviews",
+		authMiddleware(http.HandlerFunc(reviewsHandler.CreateReview)),
+	)
+
 	// @todo убрать
 	if config.Env == "dev" {
 		router.HandleFunc("/api/test-init-data/{username}", authHandler.GetTestInitData)
@@ -209,7 +565,48 @@ func RunHttpServer(dbConn *sql.DB, config *config.Config, logger *slog.Logger) {
 		Handler: middleware.RecoverMiddleware(logger, router),
 	}
 
-	server.ListenAndServe()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		notificationsService.Run(ctx, config.Notifications.Workers)
+	}()
+
+	if config.Telegram.IsPolling() {
+		poller := telegram.NewPoller(tgClient, telegramRouter, telegramRepository, logger)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := poller.Run(ctx); err != nil {
+				logger.Error("ошибка telegram polling", "err", err)
+			}
+		}()
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("ошибка http сервера", "err", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("ошибка при остановке http сервера", "err", err)
+	}
+
+	// ждем, пока poller и воркеры уведомлений дообработают текущие задачи
+	wg.Wait()
 }
 
 func getFileStorage(config *config.Config, logger *slog.Logger) (ads.FileStorage, error) {
@@ -218,7 +615,13 @@ func getFileStorage(config *config.Config, logger *slog.Logger) (ads.FileStorage
 
 	switch config.StorageType {
 	case "local":
-		fileStorage = storage.NewLocalStorage(config.Server.PublicUrl, "./uploads")
+		fileStorage = storage.NewLocalStorage(
+			config.Server.PublicUrl,
+			"./uploads",
+			"./originals",
+			"./variants",
+			config.Uploads.SigningSecret,
+		)
 	case "s3":
 		fileStorage, err = storage.NewS3Storage(
 			context.Background(),
@@ -238,3 +641,13 @@ func getFileStorage(config *config.Config, logger *slog.Logger) (ads.FileStorage
 
 	return fileStorage, nil
 }
+
+func getImageSigner(config *config.Config, logger *slog.Logger) (*images.Signer, error) {
+	variants, err := images.ParseVariants(config.Images.Variants)
+	if err != nil {
+		logger.Error("некорректный IMAGE_VARIANTS", "err", err)
+		return nil, err
+	}
+
+	return images.NewSigner(config.Server.PublicUrl, config.Images.SigningSecret, variants), nil
+}
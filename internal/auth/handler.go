@@ -42,7 +42,7 @@ func (h *Handler) GetTestInitData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.GenerateTestInitData(username)
+	result, err := h.service.GenerateTestInitData(username, r.URL.Query().Get("start_param"))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -1,6 +1,12 @@
 package auth
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"time"
+
+	"vietio/internal/deeplink"
+
+	"github.com/golang-jwt/jwt/v5"
+)
 
 type TelegramUser struct {
 	ID              int64  `json:"id"`
@@ -9,6 +15,18 @@ type TelegramUser struct {
 	Username        string `json:"username"`
 	LanguageCode    string `json:"language_code"`
 	AllowsWriteToPm bool   `json:"allows_write_to_pm"`
+	PhotoUrl        string `json:"photo_url"`
+}
+
+// проверенные данные initData Mini App
+type InitData struct {
+	User TelegramUser
+	// параметр startapp из ссылки t.me/<bot>/<app>?startapp=
+	StartParam string
+	// private, group, supergroup, channel или sender (из вложений)
+	ChatType     string
+	ChatInstance string
+	AuthDate     time.Time
 }
 
 type AuthLoginRequestBody struct {
@@ -16,7 +34,11 @@ type AuthLoginRequestBody struct {
 }
 
 type AuthLoginResponse struct {
-	Token string `json:"token"`
+	Token        string           `json:"token"`
+	StartParam   string           `json:"start_param,omitempty"`
+	Start        *deeplink.Target `json:"start,omitempty"`
+	ChatType     string           `json:"chat_type,omitempty"`
+	ChatInstance string           `json:"chat_instance,omitempty"`
 }
 
 type TestInitDataResponse struct {
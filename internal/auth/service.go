@@ -6,6 +6,7 @@ import (
 	"errors"
 	"time"
 	"vietio/config"
+	"vietio/internal/deeplink"
 	appUser "vietio/internal/user"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -19,7 +20,7 @@ type Service struct {
 
 type UserRepo interface {
 	GetUserByTelegramId(ctx context.Context, telegramId int64) (appUser.UserModel, error)
-	UpdateUsername(context.Context, appUser.UserModel) error
+	UpdateProfile(context.Context, appUser.UserModel) error
 	CreateUser(context.Context, appUser.UserModel) (id int64, err error)
 }
 
@@ -34,10 +35,11 @@ func NewService(config *config.Config, validator *Validator, userRepo UserRepo)
 func (s *Service) GetJwtToken(ctx context.Context, payload AuthLoginRequestBody) (AuthLoginResponse, error) {
 	var result AuthLoginResponse
 
-	telegramUser, err := s.Validator.ValidateWebAppData(payload.InitData, s.Config.BotToken)
+	initData, err := s.Validator.ValidateWebAppData(payload.InitData, s.Config.BotToken)
 	if err != nil {
 		return result, err
 	}
+	telegramUser := initData.User
 	
 	user, err := s.UserRepo.GetUserByTelegramId(ctx, telegramUser.ID)
 	if err != nil {
@@ -46,6 +48,9 @@ func (s *Service) GetJwtToken(ctx context.Context, payload AuthLoginRequestBody)
 			user = appUser.UserModel{
 				TelegramId: telegramUser.ID,
 				Username:   telegramUser.Username,
+				FirstName:  telegramUser.FirstName,
+				LastName:   telegramUser.LastName,
+				PhotoUrl:   telegramUser.PhotoUrl,
 			}
 			id, err := s.UserRepo.CreateUser(ctx, user)
 			if err != nil {
@@ -57,10 +62,16 @@ func (s *Service) GetJwtToken(ctx context.Context, payload AuthLoginRequestBody)
 		}
 	}
 
-	// обновляем username
-	if user.Username != telegramUser.Username {
+	// обновляем username, имя и аватар
+	if user.Username != telegramUser.Username ||
+		user.FirstName != telegramUser.FirstName ||
+		user.LastName != telegramUser.LastName ||
+		user.PhotoUrl != telegramUser.PhotoUrl {
 		user.Username = telegramUser.Username
-		err = s.UserRepo.UpdateUsername(ctx, user)
+		user.FirstName = telegramUser.FirstName
+		user.LastName = telegramUser.LastName
+		user.PhotoUrl = telegramUser.PhotoUrl
+		err = s.UserRepo.UpdateProfile(ctx, user)
 		if err != nil {
 			return result, err
 		}
@@ -72,12 +83,21 @@ func (s *Service) GetJwtToken(ctx context.Context, payload AuthLoginRequestBody)
 	}
 
 	result.Token = token
+
+	// откуда открыли Mini App: фронт переходит на нужный экран и шлет это в аналитику
+	result.StartParam = initData.StartParam
+	if target, ok := deeplink.Parse(initData.StartParam); ok {
+		result.Start = &target
+	}
+	result.ChatType = initData.ChatType
+	result.ChatInstance = initData.ChatInstance
+
 	return result, nil
 }
 
-func (s *Service) GenerateTestInitData(username string) (TestInitDataResponse, error) {
+func (s *Service) GenerateTestInitData(username string, startParam string) (TestInitDataResponse, error) {
 	var result TestInitDataResponse
-	initData, err := generateTestInitData(s.Config.BotToken, username)
+	initData, err := generateTestInitData(s.Config.BotToken, username, startParam)
 	if err != nil {
 		return result, err
 	}
@@ -11,7 +11,7 @@ import (
 )
 
 // генерация тестовых данных initData
-func generateTestInitData(botToken string, userName string) (string, error) {
+func generateTestInitData(botToken string, userName string, startParam string) (string, error) {
 	// Создаем фейкового пользователя
 	user := TelegramUser{
 		ID:              999999999,
@@ -35,6 +35,12 @@ func generateTestInitData(botToken string, userName string) (string, error) {
 		"auth_date": fmt.Sprintf("%d", time.Now().Unix()),
 	}
 
+	// для проверки deep link: ?start_param=ad_<uuid>
+	if startParam != "" {
+		params["start_param"] = startParam
+		params["chat_type"] = "private"
+	}
+
 	// 3. Сортируем ключи по алфавиту для создания data_check_string
 	keys := make([]string, 0, len(params))
 	for k := range params {
@@ -20,7 +20,7 @@ func NewValidator() *Validator {
     return &Validator{}
 }
 
-func (v *Validator) ValidateWebAppData(initData string, botToken string) (*TelegramUser, error) {
+func (v *Validator) ValidateWebAppData(initData string, botToken string) (*InitData, error) {
 	// Парсим строку запроса (query string)
 	values, err := url.ParseQuery(initData)
 	if err != nil {
@@ -83,7 +83,13 @@ func (v *Validator) ValidateWebAppData(initData string, botToken string) (*Teleg
 		return nil, fmt.Errorf("failed to parse user json: %w", err)
 	}
 
-	return &user, nil
+	return &InitData{
+		User:         user,
+		StartParam:   values.Get("start_param"),
+		ChatType:     values.Get("chat_type"),
+		ChatInstance: values.Get("chat_instance"),
+		AuthDate:     time.Unix(authDate, 0),
+	}, nil
 }
 
 // Вспомогательная функция HMAC
@@ -0,0 +1,202 @@
+package bot
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime/multipart"
+	"text/template"
+
+	"vietio/internal/ads"
+	"vietio/internal/authctx"
+	"vietio/internal/categories"
+	"vietio/internal/conversations"
+	"vietio/internal/deeplink"
+	"vietio/internal/reviews"
+	"vietio/internal/telegram"
+	"vietio/internal/user"
+)
+
+type AdsService interface {
+	GetAds(ctx context.Context, params ads.AdsListQueryParams) (ads.AdsListResponse, error)
+	GetMyAds(ctx context.Context) (ads.MyAdsListResponse, error)
+	GetMyFavoritesAds(ctx context.Context) (ads.MyFavoritesAdsListResponse, error)
+	CreateAd(ctx context.Context, payload ads.CreateAdRequestBody, images []*multipart.FileHeader, idempotencyKey string) (ads.CreateAdResponse, error)
+}
+
+type CategoryRepository interface {
+	FindAll(ctx context.Context) ([]categories.CategoryModel, error)
+}
+
+// хранилище состояния пошаговых диалогов
+type DialogStore interface {
+	FindDialog(ctx context.Context, userId int64) (DialogModel, error)
+	SaveDialog(ctx context.Context, dialog DialogModel) error
+	AppendDialogItem(ctx context.Context, userId int64, key string, value string, limit int) (json.RawMessage, bool, error)
+	DeleteDialog(ctx context.Context, userId int64) error
+}
+
+type UserRepository interface {
+	GetUserByTelegramId(ctx context.Context, telegramId int64) (user.UserModel, error)
+	CreateUser(context.Context, user.UserModel) (id int64, err error)
+	SetBotBlocked(ctx context.Context, telegramId int64, blocked bool) error
+	SetNotificationsEnabled(ctx context.Context, id int64, enabled bool) error
+}
+
+// обработка платежей в Telegram Stars
+type PaymentProcessor interface {
+	// PreCheckout возвращает ошибку, если платеж принимать нельзя
+	PreCheckout(ctx context.Context, query telegram.PreCheckoutQuery) error
+	SuccessfulPayment(ctx context.Context, from telegram.User, payment telegram.SuccessfulPayment) error
+}
+
+// исходящие сообщения идут через очередь уведомлений
+type Notifier interface {
+	SendMessage(ctx context.Context, params telegram.SendMessageParams) error
+}
+
+type contextKey string
+
+const userContextKey contextKey = "bot_user"
+
+type Bot struct {
+	logger        *slog.Logger
+	client        *telegram.Client
+	notifier      Notifier
+	ads           AdsService
+	categories    CategoryRepository
+	users         UserRepository
+	dialogs       DialogStore
+	payments      PaymentProcessor
+	conversations ConversationsService
+	reviews       ReviewsService
+	links         *deeplink.Builder
+	templates     *template.Template
+}
+
+func NewBot(
+	logger *slog.Logger,
+	client *telegram.Client,
+	notifier Notifier,
+	adsService AdsService,
+	categoryRepository CategoryRepository,
+	userRepository UserRepository,
+	dialogs DialogStore,
+	payments PaymentProcessor,
+	conversations ConversationsService,
+	reviews ReviewsService,
+	links *deeplink.Builder,
+) *Bot {
+	return &Bot{
+		logger:        logger,
+		client:        client,
+		notifier:      notifier,
+		ads:           adsService,
+		categories:    categoryRepository,
+		users:         userRepository,
+		dialogs:       dialogs,
+		payments:      payments,
+		conversations: conversations,
+		reviews:       reviews,
+		links:         links,
+		templates:     parseTemplates(),
+	}
+}
+
+func (b *Bot) Register(router *telegram.Router, updates telegram.UpdateStore) {
+	router.Use(
+		telegram.RecoverMiddleware(b.logger),
+		telegram.LoggingMiddleware(b.logger),
+		telegram.DeduplicateMiddleware(updates, b.logger),
+		b.userMiddleware,
+	)
+
+	router.Command("start", b.start)
+	router.Command("help", b.help)
+	router.Command("my", b.my)
+	router.Command("favorites", b.favorites)
+	router.Command("search", b.search)
+	router.Command("settings", b.settings)
+	router.Command("new", b.newAd)
+	router.Command("cancel", b.cancel)
+	router.Callback(newAdCallback, b.newAdButton)
+	router.Callback(conversations.CALLBACK_PREFIX, b.conversationButton)
+	router.Callback(reviews.CALLBACK_PREFIX, b.reviewButton)
+	router.Message(b.message)
+	router.InlineQuery(b.inlineQuery)
+
+	router.MyChatMember(b.myChatMember)
+	router.PreCheckoutQuery(b.preCheckout)
+	router.SuccessfulPayment(b.successfulPayment)
+}
+
+// находит или создает пользователя по telegram id и кладет его id в контекст,
+// чтобы сервисы работали так же, как для запросов из Mini App
+func (b *Bot) userMiddleware(next telegram.HandlerFunc) telegram.HandlerFunc {
+	return func(ctx context.Context, update *telegram.Update) error {
+		from := update.From()
+		if from == nil || from.IsBot {
+			return next(ctx, update)
+		}
+
+		botUser, err := b.users.GetUserByTelegramId(ctx, from.Id)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+
+			botUser = user.UserModel{
+				TelegramId:           from.Id,
+				Username:             from.Username,
+				FirstName:            from.FirstName,
+				LastName:             from.LastName,
+				NotificationsEnabled: true,
+			}
+			botUser.Id, err = b.users.CreateUser(ctx, botUser)
+			if err != nil {
+				return err
+			}
+		}
+
+		ctx = context.WithValue(ctx, authctx.UserIdKey, botUser.Id)
+		ctx = context.WithValue(ctx, userContextKey, botUser)
+
+		return next(ctx, update)
+	}
+}
+
+func userFromContext(ctx context.Context) (user.UserModel, bool) {
+	botUser, ok := ctx.Value(userContextKey).(user.UserModel)
+	return botUser, ok
+}
+
+// отправка ответа, отрендеренного из шаблона
+func (b *Bot) reply(ctx context.Context, chatId int64, templateName string, data any) error {
+	return b.replyWithMarkup(ctx, chatId, templateName, data, nil)
+}
+
+// ответ с inline-клавиатурой
+func (b *Bot) replyWithMarkup(
+	ctx context.Context,
+	chatId int64,
+	templateName string,
+	data any,
+	markup *telegram.InlineKeyboardMarkup,
+) error {
+	text, err := b.render(templateName, data)
+	if err != nil {
+		return err
+	}
+
+	params := telegram.SendMessageParams{
+		ChatId: chatId,
+		Text:   text,
+	}
+	if markup != nil {
+		params.ReplyMarkup = markup
+	}
+
+	return b.notifier.SendMessage(ctx, params)
+}
@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	"vietio/internal/ads"
+	"vietio/internal/deeplink"
+	"vietio/internal/telegram"
+)
+
+// сколько объявлений показываем в ответе на /search
+const searchLimit = 10
+
+func (b *Bot) start(ctx context.Context, update *telegram.Update) error {
+	if _, param := update.Message.Command(); strings.HasPrefix(param, deeplink.CONVERSATION_PREFIX) {
+		return b.startConversation(ctx, update, param)
+	}
+
+	return b.reply(ctx, update.ChatId(), "start", nil)
+}
+
+func (b *Bot) help(ctx context.Context, update *telegram.Update) error {
+	return b.reply(ctx, update.ChatId(), "help", nil)
+}
+
+func (b *Bot) my(ctx context.Context, update *telegram.Update) error {
+	result, err := b.ads.GetMyAds(ctx)
+	if err != nil {
+		return err
+	}
+
+	return b.reply(ctx, update.ChatId(), "my", result)
+}
+
+func (b *Bot) favorites(ctx context.Context, update *telegram.Update) error {
+	result, err := b.ads.GetMyFavoritesAds(ctx)
+	if err != nil {
+		return err
+	}
+
+	return b.reply(ctx, update.ChatId(), "favorites", result)
+}
+
+func (b *Bot) search(ctx context.Context, update *telegram.Update) error {
+	_, query := update.Message.Command()
+
+	data := struct {
+		Query string
+		Items []ads.AdsListItemResponse
+		Total int
+	}{
+		Query: query,
+	}
+
+	if query != "" {
+		result, err := b.ads.GetAds(ctx, ads.AdsListQueryParams{
+			Page:  1,
+			Query: query,
+		})
+		if err != nil {
+			return err
+		}
+
+		data.Items = result.Items
+		if len(data.Items) > searchLimit {
+			data.Items = data.Items[:searchLimit]
+		}
+		data.Total = result.Total
+	}
+
+	return b.reply(ctx, update.ChatId(), "search", data)
+}
+
+// /settings notifications on|off
+func (b *Bot) settings(ctx context.Context, update *telegram.Update) error {
+	botUser, ok := userFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	_, args := update.Message.Command()
+
+	switch args {
+	case "notifications on", "notifications off":
+		enabled := args == "notifications on"
+
+		err := b.users.SetNotificationsEnabled(ctx, botUser.Id, enabled)
+		if err != nil {
+			return err
+		}
+		botUser.NotificationsEnabled = enabled
+	}
+
+	return b.reply(ctx, update.ChatId(), "settings", botUser)
+}
+
+// сообщения без команды: шаг активного диалога, переписка с собеседником или подсказка
+func (b *Bot) message(ctx context.Context, update *telegram.Update) error {
+	if command, _ := update.Message.Command(); command == "" {
+		dialog, ok, err := b.activeDialog(ctx)
+		if err != nil {
+			return err
+		}
+
+		if ok && dialog.Scenario == SCENARIO_NEW_AD {
+			return b.newAdMessage(ctx, update, dialog)
+		}
+
+		if ok && dialog.Scenario == SCENARIO_REVIEW {
+			return b.reviewMessage(ctx, update, dialog)
+		}
+
+		relayed, err := b.relayMessage(ctx, update)
+		if relayed || err != nil {
+			return err
+		}
+	}
+
+	return b.unknown(ctx, update)
+}
+
+// в личке подсказываем список команд, в группах молчим
+func (b *Bot) unknown(ctx context.Context, update *telegram.Update) error {
+	if update.Message.Chat.Type != "private" {
+		return nil
+	}
+
+	return b.reply(ctx, update.ChatId(), "unknown", nil)
+}
+
+// пользователь заблокировал или разблокировал бота
+func (b *Bot) myChatMember(ctx context.Context, update *telegram.Update) error {
+	member := update.MyChatMember
+	if member.Chat.Type != "private" {
+		return nil
+	}
+
+	switch member.NewChatMember.Status {
+	case "kicked":
+		return b.users.SetBotBlocked(ctx, member.From.Id, true)
+	case "member":
+		return b.users.SetBotBlocked(ctx, member.From.Id, false)
+	}
+
+	return nil
+}
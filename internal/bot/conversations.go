@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"vietio/internal/conversations"
+	"vietio/internal/deeplink"
+	appErrors "vietio/internal/errors"
+	"vietio/internal/telegram"
+)
+
+// переписка покупателя и продавца через бота
+type ConversationsService interface {
+	Activate(ctx context.Context, id int64) (conversations.ConversationModel, error)
+	Deactivate(ctx context.Context) (bool, error)
+	Relay(ctx context.Context, text string) (conversations.ConversationModel, bool, error)
+	Block(ctx context.Context, id int64) error
+	Report(ctx context.Context, id int64, reason string) error
+}
+
+// /start conv_<id>: переход по кнопке «Написать» из Mini App
+func (b *Bot) startConversation(ctx context.Context, update *telegram.Update, param string) error {
+	id, err := strconv.ParseInt(strings.TrimPrefix(param, deeplink.CONVERSATION_PREFIX), 10, 64)
+	if err != nil {
+		return b.reply(ctx, update.ChatId(), "conv_not_found", nil)
+	}
+
+	return b.activateConversation(ctx, update.ChatId(), id)
+}
+
+func (b *Bot) activateConversation(ctx context.Context, chatId int64, id int64) error {
+	botUser, ok := userFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	conversation, err := b.conversations.Activate(ctx, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, appErrors.ErrConversationNotFound), errors.Is(err, appErrors.ErrForbidden):
+			return b.reply(ctx, chatId, "conv_not_found", nil)
+		case errors.Is(err, appErrors.ErrConversationBlocked):
+			return b.reply(ctx, chatId, "conv_blocked", nil)
+		default:
+			return err
+		}
+	}
+
+	// сообщения в активный диалог важнее незаконченного /new
+	err = b.dialogs.DeleteDialog(ctx, botUser.Id)
+	if err != nil {
+		return err
+	}
+
+	recipient := "продавец"
+	if conversation.Role(botUser.Id) == conversations.ROLE_SELLER {
+		recipient = "покупатель"
+	}
+
+	return b.reply(ctx, chatId, "conv_active", struct {
+		AdTitle   string
+		Recipient string
+	}{
+		AdTitle:   conversation.AdTitle,
+		Recipient: recipient,
+	})
+}
+
+// пересылка сообщения в активный диалог, false — диалога нет
+func (b *Bot) relayMessage(ctx context.Context, update *telegram.Update) (bool, error) {
+	if update.Message.Chat.Type != "private" {
+		return false, nil
+	}
+
+	_, relayed, err := b.conversations.Relay(ctx, update.Message.Text)
+	if err != nil {
+		if errors.Is(err, appErrors.ErrConversationBlocked) {
+			return true, b.reply(ctx, update.ChatId(), "conv_blocked", nil)
+		}
+		return relayed, err
+	}
+
+	if relayed && strings.TrimSpace(update.Message.Text) == "" {
+		return true, b.reply(ctx, update.ChatId(), "conv_text_only", nil)
+	}
+
+	return relayed, nil
+}
+
+// кнопки под пересланным сообщением: conv:reply:<id>, conv:block:<id>, conv:report:<id>
+func (b *Bot) conversationButton(ctx context.Context, update *telegram.Update) error {
+	query := update.CallbackQuery
+
+	args := telegram.CallbackArgs(query.Data)
+	if len(args) != 2 {
+		return b.answer(ctx, query.Id, "")
+	}
+
+	id, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return b.answer(ctx, query.Id, "")
+	}
+
+	switch args[0] {
+	case "reply":
+		if err := b.answer(ctx, query.Id, ""); err != nil {
+			return err
+		}
+		return b.activateConversation(ctx, update.ChatId(), id)
+
+	case "block":
+		err = b.conversations.Block(ctx, id)
+		if err == nil {
+			return b.answer(ctx, query.Id, "Диалог заблокирован")
+		}
+
+	case "report":
+		err = b.conversations.Report(ctx, id, "")
+		if err == nil {
+			return b.answer(ctx, query.Id, "Жалоба отправлена, диалог заблокирован")
+		}
+
+	default:
+		return b.answer(ctx, query.Id, "")
+	}
+
+	if errors.Is(err, appErrors.ErrConversationNotFound) || errors.Is(err, appErrors.ErrForbidden) {
+		return b.answer(ctx, query.Id, "Диалог не найден")
+	}
+
+	return err
+}
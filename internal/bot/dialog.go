@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// незаконченный диалог через сутки считаем брошенным
+const dialogTTL = 24 * time.Hour
+
+// активный диалог пользователя из контекста
+func (b *Bot) activeDialog(ctx context.Context) (DialogModel, bool, error) {
+	botUser, ok := userFromContext(ctx)
+	if !ok {
+		return DialogModel{}, false, nil
+	}
+
+	dialog, err := b.dialogs.FindDialog(ctx, botUser.Id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dialog, false, nil
+		}
+		return dialog, false, err
+	}
+
+	if time.Since(dialog.UpdatedAt) > dialogTTL {
+		return dialog, false, nil
+	}
+
+	return dialog, true, nil
+}
+
+// сохраняет шаг диалога вместе с данными
+func (b *Bot) saveDialog(ctx context.Context, userId int64, scenario string, step string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return b.dialogs.SaveDialog(ctx, DialogModel{
+		UserId:   userId,
+		Scenario: scenario,
+		Step:     step,
+		Data:     raw,
+	})
+}
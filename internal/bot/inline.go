@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"context"
+	"strconv"
+
+	"vietio/internal/ads"
+	"vietio/internal/images"
+	"vietio/internal/telegram"
+	"vietio/pkg/utils"
+)
+
+// сколько Telegram кеширует ответ на одинаковый запрос
+const inlineCacheTime = 30
+
+// @vietio_bot <запрос> в любом чате: поиск тем же запросом, что и GET /api/ads.
+// offset — номер следующей страницы
+func (b *Bot) inlineQuery(ctx context.Context, update *telegram.Update) error {
+	query := update.InlineQuery
+
+	page := utils.ParseInt(query.Offset, 1)
+
+	result, err := b.ads.GetAds(ctx, ads.AdsListQueryParams{
+		Page:  page,
+		Query: query.Query,
+	})
+	if err != nil {
+		return err
+	}
+
+	results := make([]any, 0, len(result.Items))
+	for _, item := range result.Items {
+		inlineResult, err := b.inlineResult(item)
+		if err != nil {
+			return err
+		}
+		results = append(results, inlineResult)
+	}
+
+	nextOffset := ""
+	if page*result.Limit < result.Total {
+		nextOffset = strconv.Itoa(page + 1)
+	}
+
+	return b.client.AnswerInlineQuery(ctx, telegram.AnswerInlineQueryParams{
+		InlineQueryId: query.Id,
+		Results:       results,
+		CacheTime:     inlineCacheTime,
+		NextOffset:    nextOffset,
+	})
+}
+
+// объявление с фото отправляется фотографией, без фото — текстом
+func (b *Bot) inlineResult(item ads.AdsListItemResponse) (any, error) {
+	text, err := b.render("inline_ad", item)
+	if err != nil {
+		return nil, err
+	}
+
+	var markup *telegram.InlineKeyboardMarkup
+	if link := b.links.Ad(item.Uuid.String()); link != "" {
+		markup = &telegram.InlineKeyboardMarkup{
+			InlineKeyboard: [][]telegram.InlineKeyboardButton{
+				{{Text: "Открыть в Vietio", Url: link}},
+			},
+		}
+	}
+
+	id := item.Uuid.String()
+	description := utils.FormatPrice(item.Price)
+
+	photo := item.ImageVariants[images.VARIANT_CARD]
+	if photo == "" {
+		return telegram.InlineQueryResultArticle{
+			Type:        "article",
+			Id:          id,
+			Title:       item.Title,
+			Description: description,
+			InputMessageContent: telegram.InputTextMessageContent{
+				MessageText: text,
+				ParseMode:   telegram.PARSE_MODE_HTML,
+			},
+			ReplyMarkup: markup,
+		}, nil
+	}
+
+	return telegram.InlineQueryResultPhoto{
+		Type:         "photo",
+		Id:           id,
+		PhotoUrl:     photo,
+		ThumbnailUrl: item.ImageVariants[images.VARIANT_THUMB],
+		Title:        item.Title,
+		Description:  description,
+		Caption:      text,
+		ParseMode:    telegram.PARSE_MODE_HTML,
+		ReplyMarkup:  markup,
+	}, nil
+}
@@ -0,0 +1,475 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"vietio/internal/ads"
+	appErrors "vietio/internal/errors"
+	"vietio/internal/telegram"
+	"vietio/internal/user"
+)
+
+// пошаговое создание объявления: /new
+const SCENARIO_NEW_AD = "new_ad"
+
+const STEP_CATEGORY = "category"
+const STEP_TITLE = "title"
+const STEP_DESCRIPTION = "description"
+const STEP_PRICE = "price"
+const STEP_PHOTOS = "photos"
+const STEP_CONFIRM = "confirm"
+
+// префикс callback-кнопок диалога
+const newAdCallback = "new"
+
+// столько же, сколько разрешает ads.Validator
+const newAdMaxPhotos = 3
+
+// сколько фото держим в памяти при сборке multipart, остальное — во временных файлах
+const newAdPhotosMaxMemory = 32 << 20
+
+type newAdDraft struct {
+	CategoryId   int      `json:"category_id"`
+	CategoryName string   `json:"category_name"`
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	Price        int      `json:"price"`
+	Photos       []string `json:"photos"`
+}
+
+func (b *Bot) newAd(ctx context.Context, update *telegram.Update) error {
+	if update.Message.Chat.Type != "private" {
+		return nil
+	}
+
+	botUser, ok := userFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	categories, err := b.categories.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = b.saveDialog(ctx, botUser.Id, SCENARIO_NEW_AD, STEP_CATEGORY, newAdDraft{})
+	if err != nil {
+		return err
+	}
+
+	keyboard := &telegram.InlineKeyboardMarkup{}
+	for i, category := range categories {
+		button := telegram.InlineKeyboardButton{
+			Text:         category.Name,
+			CallbackData: telegram.CallbackData(newAdCallback, "category", strconv.Itoa(category.Id)),
+		}
+
+		// по две категории в ряд
+		if i%2 == 0 {
+			keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []telegram.InlineKeyboardButton{button})
+		} else {
+			last := len(keyboard.InlineKeyboard) - 1
+			keyboard.InlineKeyboard[last] = append(keyboard.InlineKeyboard[last], button)
+		}
+	}
+	keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []telegram.InlineKeyboardButton{cancelButton()})
+
+	return b.replyWithMarkup(ctx, update.ChatId(), "new_category", nil, keyboard)
+}
+
+// /cancel отменяет текущий диалог бота (объявление или отзыв), а если его нет — выходит из переписки
+func (b *Bot) cancel(ctx context.Context, update *telegram.Update) error {
+	dialog, hasDialog, err := b.activeDialog(ctx)
+	if err != nil {
+		return err
+	}
+
+	if hasDialog && dialog.Scenario == SCENARIO_REVIEW {
+		botUser, _ := userFromContext(ctx)
+		if err := b.dialogs.DeleteDialog(ctx, botUser.Id); err != nil {
+			return err
+		}
+		return b.reply(ctx, update.ChatId(), "review_cancelled", nil)
+	}
+
+	if !hasDialog {
+		left, err := b.conversations.Deactivate(ctx)
+		if err != nil {
+			return err
+		}
+		if left {
+			return b.reply(ctx, update.ChatId(), "conv_left", nil)
+		}
+	}
+
+	return b.cancelNewAd(ctx, update)
+}
+
+func (b *Bot) cancelNewAd(ctx context.Context, update *telegram.Update) error {
+	botUser, ok := userFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	err := b.dialogs.DeleteDialog(ctx, botUser.Id)
+	if err != nil {
+		return err
+	}
+
+	return b.reply(ctx, update.ChatId(), "new_cancelled", nil)
+}
+
+// текст и фото на шагах диалога
+func (b *Bot) newAdMessage(ctx context.Context, update *telegram.Update, dialog DialogModel) error {
+	botUser, _ := userFromContext(ctx)
+	chatId := update.ChatId()
+	text := strings.TrimSpace(update.Message.Text)
+
+	var draft newAdDraft
+	if err := json.Unmarshal(dialog.Data, &draft); err != nil {
+		return err
+	}
+
+	switch dialog.Step {
+	case STEP_TITLE:
+		if text == "" {
+			return b.reply(ctx, chatId, "new_title", nil)
+		}
+		draft.Title = text
+
+		if err := b.saveDialog(ctx, botUser.Id, SCENARIO_NEW_AD, STEP_DESCRIPTION, draft); err != nil {
+			return err
+		}
+		return b.reply(ctx, chatId, "new_description", nil)
+
+	case STEP_DESCRIPTION:
+		if text == "" {
+			return b.reply(ctx, chatId, "new_description", nil)
+		}
+		draft.Description = text
+
+		if err := b.saveDialog(ctx, botUser.Id, SCENARIO_NEW_AD, STEP_PRICE, draft); err != nil {
+			return err
+		}
+		return b.reply(ctx, chatId, "new_price", nil)
+
+	case STEP_PRICE:
+		price, ok := parsePrice(text)
+		if !ok {
+			return b.reply(ctx, chatId, "new_price_invalid", nil)
+		}
+		draft.Price = price
+
+		if err := b.saveDialog(ctx, botUser.Id, SCENARIO_NEW_AD, STEP_PHOTOS, draft); err != nil {
+			return err
+		}
+		return b.replyWithMarkup(ctx, chatId, "new_photos", newAdMaxPhotos, photosKeyboard())
+
+	case STEP_PHOTOS:
+		return b.newAdPhoto(ctx, update, botUser)
+
+	default:
+		// на шагах с кнопками ждем нажатия
+		return b.reply(ctx, chatId, "new_use_buttons", nil)
+	}
+}
+
+func (b *Bot) newAdPhoto(ctx context.Context, update *telegram.Update, botUser user.UserModel) error {
+	chatId := update.ChatId()
+	photos := update.Message.Photo
+
+	if len(photos) == 0 {
+		return b.replyWithMarkup(ctx, chatId, "new_photos", newAdMaxPhotos, photosKeyboard())
+	}
+
+	// размеры идут по возрастанию, берем самый большой
+	fileId := photos[len(photos)-1].FileId
+
+	data, ok, err := b.dialogs.AppendDialogItem(ctx, botUser.Id, "photos", fileId, newAdMaxPhotos)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return b.reply(ctx, chatId, "new_photos_limit", newAdMaxPhotos)
+	}
+
+	var draft newAdDraft
+	if err := json.Unmarshal(data, &draft); err != nil {
+		return err
+	}
+
+	if len(draft.Photos) >= newAdMaxPhotos {
+		return b.newAdConfirm(ctx, chatId, botUser.Id, draft)
+	}
+
+	return b.replyWithMarkup(ctx, chatId, "new_photo_added", struct {
+		Count int
+		Max   int
+	}{
+		Count: len(draft.Photos),
+		Max:   newAdMaxPhotos,
+	}, photosKeyboard())
+}
+
+func (b *Bot) newAdConfirm(ctx context.Context, chatId int64, userId int64, draft newAdDraft) error {
+	if err := b.saveDialog(ctx, userId, SCENARIO_NEW_AD, STEP_CONFIRM, draft); err != nil {
+		return err
+	}
+
+	keyboard := &telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{
+			{
+				{Text: "✅ Опубликовать", CallbackData: telegram.CallbackData(newAdCallback, "confirm")},
+				cancelButton(),
+			},
+		},
+	}
+
+	return b.replyWithMarkup(ctx, chatId, "new_confirm", draft, keyboard)
+}
+
+// кнопки диалога: new:category:<id>, new:photos_done, new:confirm, new:cancel
+func (b *Bot) newAdButton(ctx context.Context, update *telegram.Update) error {
+	query := update.CallbackQuery
+	chatId := update.ChatId()
+
+	botUser, ok := userFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	args := telegram.CallbackArgs(query.Data)
+	if len(args) == 0 {
+		return b.answer(ctx, query.Id, "")
+	}
+
+	dialog, ok, err := b.activeDialog(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok || dialog.Scenario != SCENARIO_NEW_AD {
+		return b.answer(ctx, query.Id, "Диалог устарел, начните заново: /new")
+	}
+
+	var draft newAdDraft
+	if err := json.Unmarshal(dialog.Data, &draft); err != nil {
+		return err
+	}
+
+	switch {
+	case args[0] == "cancel":
+		if err := b.answer(ctx, query.Id, ""); err != nil {
+			return err
+		}
+		return b.cancelNewAd(ctx, update)
+
+	case args[0] == "category" && dialog.Step == STEP_CATEGORY && len(args) == 2:
+		categoryId, err := strconv.Atoi(args[1])
+		if err != nil {
+			return b.answer(ctx, query.Id, "")
+		}
+
+		categories, err := b.categories.FindAll(ctx)
+		if err != nil {
+			return err
+		}
+		for _, category := range categories {
+			if category.Id == categoryId {
+				draft.CategoryId = category.Id
+				draft.CategoryName = category.Name
+			}
+		}
+		if draft.CategoryId == 0 {
+			return b.answer(ctx, query.Id, "Такой категории нет")
+		}
+
+		if err := b.saveDialog(ctx, botUser.Id, SCENARIO_NEW_AD, STEP_TITLE, draft); err != nil {
+			return err
+		}
+		if err := b.answer(ctx, query.Id, ""); err != nil {
+			return err
+		}
+		return b.reply(ctx, chatId, "new_title", nil)
+
+	case args[0] == "photos_done" && dialog.Step == STEP_PHOTOS:
+		if len(draft.Photos) == 0 {
+			return b.answer(ctx, query.Id, "Нужно хотя бы одно фото")
+		}
+		if err := b.answer(ctx, query.Id, ""); err != nil {
+			return err
+		}
+		return b.newAdConfirm(ctx, chatId, botUser.Id, draft)
+
+	case args[0] == "confirm" && dialog.Step == STEP_CONFIRM:
+		if err := b.answer(ctx, query.Id, "Публикуем…"); err != nil {
+			return err
+		}
+		// повторное нажатие «Опубликовать» не создаст второе объявление
+		idempotencyKey := ""
+		if query.Message != nil {
+			idempotencyKey = fmt.Sprintf("bot:%d:%d", chatId, query.Message.MessageId)
+		}
+		return b.newAdPublish(ctx, chatId, botUser.Id, draft, idempotencyKey)
+
+	default:
+		// кнопка от предыдущего шага
+		return b.answer(ctx, query.Id, "")
+	}
+}
+
+// скачивает фото и создает объявление через ads.Service, как Mini App
+func (b *Bot) newAdPublish(ctx context.Context, chatId int64, userId int64, draft newAdDraft, idempotencyKey string) error {
+	form, err := b.downloadPhotos(ctx, draft.Photos)
+	if err != nil {
+		b.reply(ctx, chatId, "new_failed", nil)
+		return err
+	}
+	defer form.RemoveAll()
+
+	result, err := b.ads.CreateAd(
+		ctx,
+		ads.CreateAdRequestBody{
+			Title:       draft.Title,
+			Description: draft.Description,
+			Price:       draft.Price,
+			CategoryId:  draft.CategoryId,
+		},
+		form.File["images"],
+		idempotencyKey,
+	)
+
+	var validationErr *appErrors.ValidationError
+	var duplicateErr *appErrors.DuplicateAdError
+
+	switch {
+	case errors.As(err, &validationErr):
+		if err := b.dialogs.DeleteDialog(ctx, userId); err != nil {
+			return err
+		}
+		return b.reply(ctx, chatId, "new_invalid", validationErr.Errors)
+
+	case errors.As(err, &duplicateErr):
+		if err := b.dialogs.DeleteDialog(ctx, userId); err != nil {
+			return err
+		}
+		return b.reply(ctx, chatId, "new_duplicate", duplicateErr.Message)
+
+	case errors.Is(err, appErrors.ErrIdempotencyInProgress):
+		// первое нажатие еще публикует, ответит оно
+		return nil
+
+	case err != nil:
+		// диалог оставляем, пользователь может нажать «Опубликовать» еще раз
+		b.reply(ctx, chatId, "new_failed", nil)
+		return err
+	}
+
+	if err := b.dialogs.DeleteDialog(ctx, userId); err != nil {
+		return err
+	}
+
+	b.logger.Info("объявление создано через бота", "uuid", result.Uuid, "user_id", userId)
+
+	return b.reply(ctx, chatId, "new_created", draft)
+}
+
+// собирает multipart.Form из фото Telegram, чтобы пройти тот же путь, что и загрузка из Mini App
+func (b *Bot) downloadPhotos(ctx context.Context, fileIds []string) (*multipart.Form, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for _, fileId := range fileIds {
+		file, err := b.client.GetFile(ctx, fileId)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := b.client.DownloadFile(ctx, file.FilePath)
+		if err != nil {
+			return nil, err
+		}
+
+		header := make(textproto.MIMEHeader)
+		header.Set(
+			"Content-Disposition",
+			fmt.Sprintf(`form-data; name="images"; filename="%s"`, path.Base(file.FilePath)),
+		)
+		header.Set("Content-Type", http.DetectContentType(data))
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return multipart.NewReader(&body, writer.Boundary()).ReadForm(newAdPhotosMaxMemory)
+}
+
+func (b *Bot) answer(ctx context.Context, queryId string, text string) error {
+	return b.client.AnswerCallbackQuery(ctx, telegram.AnswerCallbackQueryParams{
+		CallbackQueryId: queryId,
+		Text:            text,
+	})
+}
+
+func cancelButton() telegram.InlineKeyboardButton {
+	return telegram.InlineKeyboardButton{
+		Text:         "Отменить",
+		CallbackData: telegram.CallbackData(newAdCallback, "cancel"),
+	}
+}
+
+func photosKeyboard() *telegram.InlineKeyboardMarkup {
+	return &telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{
+			{
+				{Text: "Готово", CallbackData: telegram.CallbackData(newAdCallback, "photos_done")},
+				cancelButton(),
+			},
+		},
+	}
+}
+
+// "15 000 000", "15.000.000 ₫", "бесплатно" -> int
+func parsePrice(text string) (int, bool) {
+	lower := strings.ToLower(text)
+	if lower == "0" || lower == "бесплатно" || lower == "free" {
+		return 0, true
+	}
+
+	if strings.Contains(text, "-") {
+		return 0, false
+	}
+
+	var digits strings.Builder
+	for _, r := range text {
+		if unicode.IsDigit(r) {
+			digits.WriteRune(r)
+		}
+	}
+
+	price, err := strconv.Atoi(digits.String())
+	if err != nil {
+		return 0, false
+	}
+
+	return price, true
+}
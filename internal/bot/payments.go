@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"context"
+	"errors"
+
+	appErrors "vietio/internal/errors"
+	"vietio/internal/telegram"
+)
+
+func (b *Bot) preCheckout(ctx context.Context, update *telegram.Update) error {
+	query := update.PreCheckoutQuery
+
+	ok := true
+	errorMessage := ""
+
+	err := b.payments.PreCheckout(ctx, *query)
+	if err != nil {
+		b.logger.Warn("telegram pre checkout rejected", "err", err, "payload", query.InvoicePayload)
+		ok = false
+		errorMessage = "Платеж не может быть принят, попробуйте создать счет заново"
+	}
+
+	return b.client.AnswerPreCheckoutQuery(ctx, query.Id, ok, errorMessage)
+}
+
+func (b *Bot) successfulPayment(ctx context.Context, update *telegram.Update) error {
+	message := update.Message
+	if message.From == nil {
+		return nil
+	}
+
+	err := b.payments.SuccessfulPayment(ctx, *message.From, *message.SuccessfulPayment)
+	if errors.Is(err, appErrors.ErrPaymentInvalid) || errors.Is(err, appErrors.ErrPaymentNotFound) {
+		// звезды уже списаны, повтор апдейта ничего не изменит
+		b.logger.Error("telegram successful payment rejected", "err", err, "payload", message.SuccessfulPayment.InvoicePayload)
+		return b.reply(ctx, message.Chat.Id, "payment_invalid", nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	return b.reply(ctx, message.Chat.Id, "payment_success", nil)
+}
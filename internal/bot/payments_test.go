@@ -0,0 +1,457 @@
+package bot_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"vietio/config"
+	"vietio/internal/ads"
+	"vietio/internal/authctx"
+	"vietio/internal/bot"
+	"vietio/internal/payments"
+	"vietio/internal/telegram"
+	"vietio/internal/user"
+
+	"github.com/google/uuid"
+)
+
+const (
+	testToken  = "test-token"
+	testSecret = "test-secret"
+	ownerId    = int64(1)
+	ownerTgId  = int64(1001)
+	strangerTg = int64(2002)
+)
+
+// драйвер без базы: нужен только для того, чтобы сервис мог открыть *sql.Tx
+type txDriver struct{}
+
+type txConn struct{}
+
+func (txDriver) Open(string) (driver.Conn, error) { return txConn{}, nil }
+
+func (txConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (txConn) Close() error                        { return nil }
+func (txConn) Begin() (driver.Tx, error)           { return txConn{}, nil }
+func (txConn) Commit() error                       { return nil }
+func (txConn) Rollback() error                     { return nil }
+
+func (txConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return txConn{}, nil
+}
+
+func init() {
+	sql.Register("bot-payments-test", txDriver{})
+}
+
+// запросы, которые бот отправил в Bot API
+type telegramCall struct {
+	Method string
+	Body   map[string]any
+}
+
+type fakeTelegram struct {
+	mu    sync.Mutex
+	calls []telegramCall
+}
+
+func (f *fakeTelegram) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, "/bot"+testToken+"/")
+
+	body := map[string]any{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	f.mu.Lock()
+	f.calls = append(f.calls, telegramCall{Method: method, Body: body})
+	f.mu.Unlock()
+
+	result := any(true)
+	if method == "createInvoiceLink" {
+		result = "https://t.me/$invoice"
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+func (f *fakeTelegram) find(method string) []telegramCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var calls []telegramCall
+	for _, call := range f.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+type fakePayments struct {
+	db       *sql.DB
+	payments map[uuid.UUID]payments.PaymentModel
+}
+
+func (r *fakePayments) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, opts)
+}
+
+func (r *fakePayments) CreatePayment(ctx context.Context, payment payments.PaymentModel) (uuid.UUID, error) {
+	payment.Uuid = uuid.New()
+	r.payments[payment.Uuid] = payment
+	return payment.Uuid, nil
+}
+
+func (r *fakePayments) FindPaymentByUuid(ctx context.Context, paymentUuid uuid.UUID) (payments.PaymentModel, error) {
+	payment, ok := r.payments[paymentUuid]
+	if !ok {
+		return payment, sql.ErrNoRows
+	}
+	return payment, nil
+}
+
+func (r *fakePayments) FindPaymentByUuidWithTx(ctx context.Context, tx *sql.Tx, paymentUuid uuid.UUID) (payments.PaymentModel, error) {
+	return r.FindPaymentByUuid(ctx, paymentUuid)
+}
+
+func (r *fakePayments) MarkPaidWithTx(ctx context.Context, tx *sql.Tx, paymentUuid uuid.UUID, telegramChargeId string, providerChargeId string) (payments.PaymentModel, error) {
+	return r.mark(paymentUuid, payments.STATUS_PAID, telegramChargeId, providerChargeId), nil
+}
+
+func (r *fakePayments) MarkInvalidWithTx(ctx context.Context, tx *sql.Tx, paymentUuid uuid.UUID, telegramChargeId string, providerChargeId string) error {
+	r.mark(paymentUuid, payments.STATUS_INVALID, telegramChargeId, providerChargeId)
+	return nil
+}
+
+func (r *fakePayments) mark(paymentUuid uuid.UUID, status string, telegramChargeId string, providerChargeId string) payments.PaymentModel {
+	payment := r.payments[paymentUuid]
+	payment.Status = status
+	payment.TelegramPaymentChargeId = telegramChargeId
+	payment.ProviderPaymentChargeId = providerChargeId
+	r.payments[paymentUuid] = payment
+	return payment
+}
+
+type promotion struct {
+	AdUuid uuid.UUID
+	Type   string
+	Days   int
+}
+
+type fakeAds struct {
+	ad       ads.AdModel
+	promoted []promotion
+}
+
+func (r *fakeAds) FindAdByUuid(ctx context.Context, adUuid uuid.UUID) (ads.AdModel, error) {
+	if adUuid != r.ad.Uuid {
+		return ads.AdModel{}, sql.ErrNoRows
+	}
+	return r.ad, nil
+}
+
+func (r *fakeAds) PromoteAdWithTx(ctx context.Context, tx *sql.Tx, adUuid uuid.UUID, promotionType string, days int) (time.Time, error) {
+	r.promoted = append(r.promoted, promotion{AdUuid: adUuid, Type: promotionType, Days: days})
+	return time.Now().AddDate(0, 0, days), nil
+}
+
+type fakeUsers struct {
+	users map[int64]user.UserModel
+}
+
+func (r *fakeUsers) GetUserByTelegramId(ctx context.Context, telegramId int64) (user.UserModel, error) {
+	model, ok := r.users[telegramId]
+	if !ok {
+		return model, sql.ErrNoRows
+	}
+	return model, nil
+}
+
+func (r *fakeUsers) CreateUser(ctx context.Context, model user.UserModel) (int64, error) {
+	model.Id = int64(len(r.users) + 100)
+	r.users[model.TelegramId] = model
+	return model.Id, nil
+}
+
+func (r *fakeUsers) SetBotBlocked(ctx context.Context, telegramId int64, blocked bool) error {
+	return nil
+}
+
+func (r *fakeUsers) SetNotificationsEnabled(ctx context.Context, id int64, enabled bool) error {
+	return nil
+}
+
+type fakeUpdates struct{}
+
+func (fakeUpdates) MarkProcessed(ctx context.Context, updateId int64) (bool, error) {
+	return true, nil
+}
+
+func (fakeUpdates) UnmarkProcessed(ctx context.Context, updateId int64) error {
+	return nil
+}
+
+type fakeNotifier struct {
+	sent []telegram.SendMessageParams
+}
+
+func (n *fakeNotifier) SendMessage(ctx context.Context, params telegram.SendMessageParams) error {
+	n.sent = append(n.sent, params)
+	return nil
+}
+
+type testEnv struct {
+	telegram *fakeTelegram
+	payments *fakePayments
+	ads      *fakeAds
+	notifier *fakeNotifier
+	service  *payments.Service
+	webhook  *telegram.Handler
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	tg := &fakeTelegram{}
+	server := httptest.NewServer(tg)
+	t.Cleanup(server.Close)
+
+	db, err := sql.Open("bot-payments-test", "")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	env := &testEnv{
+		telegram: tg,
+		payments: &fakePayments{db: db, payments: map[uuid.UUID]payments.PaymentModel{}},
+		ads: &fakeAds{ad: ads.AdModel{
+			Uuid:   uuid.New(),
+			UserId: ownerId,
+			Title:  "Honda Vision",
+			Status: ads.STATUS_ACTIVE,
+		}},
+		notifier: &fakeNotifier{},
+	}
+
+	users := &fakeUsers{users: map[int64]user.UserModel{
+		ownerTgId:  {Id: ownerId, TelegramId: ownerTgId},
+		strangerTg: {Id: 2, TelegramId: strangerTg},
+	}}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := telegram.NewClient(testToken, server.URL)
+
+	env.service = payments.NewService(
+		env.payments,
+		env.ads,
+		users,
+		client,
+		config.Payments{PinPrice: 50, HighlightPrice: 30, MaxDays: 30},
+	)
+
+	router := telegram.NewRouter()
+	b := bot.NewBot(logger, client, env.notifier, nil, nil, users, nil, env.service, nil, nil, nil)
+	b.Register(router, fakeUpdates{})
+
+	env.webhook = telegram.NewHandler(logger, router, testSecret)
+
+	return env
+}
+
+func (env *testEnv) createInvoice(t *testing.T) payments.PromoteAdResponse {
+	t.Helper()
+
+	ctx := context.WithValue(context.Background(), authctx.UserIdKey, ownerId)
+	result, err := env.service.CreateInvoice(ctx, env.ads.ad.Uuid, payments.PromoteAdRequestBody{
+		Type: ads.PROMOTION_PIN,
+		Days: 3,
+	})
+	if err != nil {
+		t.Fatalf("CreateInvoice: %v", err)
+	}
+
+	return result
+}
+
+func (env *testEnv) send(t *testing.T, update telegram.Update) {
+	t.Helper()
+
+	body, err := json.Marshal(update)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/telegram/webhook", bytes.NewReader(body))
+	req.Header.Set("X-Telegram-Bot-Api-Secret-Token", testSecret)
+	rec := httptest.NewRecorder()
+
+	env.webhook.Webhook(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("webhook status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestCreateInvoice(t *testing.T) {
+	env := newTestEnv(t)
+
+	result := env.createInvoice(t)
+
+	if result.InvoiceLink != "https://t.me/$invoice" {
+		t.Errorf("InvoiceLink = %q", result.InvoiceLink)
+	}
+	if result.Amount != 150 || result.Currency != payments.CURRENCY_STARS {
+		t.Errorf("amount = %d %s, want 150 XTR", result.Amount, result.Currency)
+	}
+
+	calls := env.telegram.find("createInvoiceLink")
+	if len(calls) != 1 {
+		t.Fatalf("createInvoiceLink calls = %d, want 1", len(calls))
+	}
+
+	body := calls[0].Body
+	if body["currency"] != payments.CURRENCY_STARS {
+		t.Errorf("currency = %v", body["currency"])
+	}
+	if body["payload"] != result.PaymentUuid {
+		t.Errorf("payload = %v, want %s", body["payload"], result.PaymentUuid)
+	}
+
+	prices, _ := body["prices"].([]any)
+	if len(prices) != 1 || prices[0].(map[string]any)["amount"] != float64(150) {
+		t.Errorf("prices = %v", body["prices"])
+	}
+
+	payment := env.payments.payments[uuid.MustParse(result.PaymentUuid)]
+	if payment.Status != payments.STATUS_PENDING || payment.UserId != ownerId {
+		t.Errorf("payment = %+v", payment)
+	}
+}
+
+func TestPreCheckoutQuery(t *testing.T) {
+	tests := []struct {
+		name   string
+		from   int64
+		amount int
+		ok     bool
+	}{
+		{"accept", ownerTgId, 150, true},
+		{"reject wrong amount", ownerTgId, 1, false},
+		{"reject other payer", strangerTg, 150, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			invoice := env.createInvoice(t)
+
+			env.send(t, telegram.Update{
+				UpdateId: 1,
+				PreCheckoutQuery: &telegram.PreCheckoutQuery{
+					Id:             "query-1",
+					From:           telegram.User{Id: tt.from},
+					Currency:       payments.CURRENCY_STARS,
+					TotalAmount:    tt.amount,
+					InvoicePayload: invoice.PaymentUuid,
+				},
+			})
+
+			calls := env.telegram.find("answerPreCheckoutQuery")
+			if len(calls) != 1 {
+				t.Fatalf("answerPreCheckoutQuery calls = %d, want 1", len(calls))
+			}
+
+			body := calls[0].Body
+			if body["pre_checkout_query_id"] != "query-1" {
+				t.Errorf("pre_checkout_query_id = %v", body["pre_checkout_query_id"])
+			}
+			if body["ok"] != tt.ok {
+				t.Errorf("ok = %v, want %v", body["ok"], tt.ok)
+			}
+			if _, hasMessage := body["error_message"]; hasMessage == tt.ok {
+				t.Errorf("error_message = %v", body["error_message"])
+			}
+		})
+	}
+}
+
+func TestSuccessfulPayment(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     int64
+		currency string
+		amount   int
+		status   string
+	}{
+		{"paid", ownerTgId, payments.CURRENCY_STARS, 150, payments.STATUS_PAID},
+		{"wrong amount", ownerTgId, payments.CURRENCY_STARS, 1, payments.STATUS_INVALID},
+		{"wrong currency", ownerTgId, "USD", 150, payments.STATUS_INVALID},
+		{"other payer", strangerTg, payments.CURRENCY_STARS, 150, payments.STATUS_INVALID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			invoice := env.createInvoice(t)
+
+			update := telegram.Update{
+				UpdateId: 2,
+				Message: &telegram.Message{
+					MessageId: 10,
+					From:      &telegram.User{Id: tt.from},
+					Chat:      telegram.Chat{Id: tt.from, Type: "private"},
+					SuccessfulPayment: &telegram.SuccessfulPayment{
+						Currency:                tt.currency,
+						TotalAmount:             tt.amount,
+						InvoicePayload:          invoice.PaymentUuid,
+						TelegramPaymentChargeId: "charge-1",
+					},
+				},
+			}
+
+			env.send(t, update)
+			// повторное уведомление не должно продлевать продвижение второй раз
+			env.send(t, update)
+
+			payment := env.payments.payments[uuid.MustParse(invoice.PaymentUuid)]
+			if payment.Status != tt.status {
+				t.Errorf("status = %s, want %s", payment.Status, tt.status)
+			}
+			if payment.TelegramPaymentChargeId != "charge-1" {
+				t.Errorf("TelegramPaymentChargeId = %q", payment.TelegramPaymentChargeId)
+			}
+
+			if len(env.notifier.sent) == 0 || env.notifier.sent[0].ChatId != tt.from {
+				t.Errorf("sent = %v", env.notifier.sent)
+			}
+
+			if tt.status != payments.STATUS_PAID {
+				if len(env.ads.promoted) != 0 {
+					t.Errorf("promoted = %v, want none", env.ads.promoted)
+				}
+				if !strings.Contains(env.notifier.sent[0].Text, "не совпала") {
+					t.Errorf("reply = %q, want payment_invalid", env.notifier.sent[0].Text)
+				}
+				return
+			}
+
+			want := promotion{AdUuid: env.ads.ad.Uuid, Type: ads.PROMOTION_PIN, Days: 3}
+			if len(env.ads.promoted) != 1 || env.ads.promoted[0] != want {
+				t.Errorf("promoted = %v, want [%v]", env.ads.promoted, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// состояние пошагового диалога пользователя с ботом
+type DialogModel struct {
+	UserId    int64
+	Scenario  string
+	Step      string
+	Data      json.RawMessage
+	UpdatedAt time.Time
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) FindDialog(ctx context.Context, userId int64) (DialogModel, error) {
+	var result DialogModel
+
+	query := `
+		SELECT user_id, scenario, step, "data", updated_at
+		FROM bot_dialogs
+		WHERE user_id = $1
+	`
+
+	err := r.db.QueryRowContext(ctx, query, userId).Scan(
+		&result.UserId,
+		&result.Scenario,
+		&result.Step,
+		&result.Data,
+		&result.UpdatedAt,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// у пользователя один активный диалог, новый заменяет старый
+func (r *Repository) SaveDialog(ctx context.Context, dialog DialogModel) error {
+	query := `
+		INSERT INTO bot_dialogs (user_id, scenario, step, "data")
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET
+			scenario = EXCLUDED.scenario,
+			step = EXCLUDED.step,
+			"data" = EXCLUDED."data",
+			updated_at = now()
+	`
+
+	_, err := r.db.ExecContext(ctx, query, dialog.UserId, dialog.Scenario, dialog.Step, dialog.Data)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AppendDialogItem атомарно добавляет value в массив data->key, если в нем меньше limit элементов.
+// Альбом из нескольких фото приходит параллельными апдейтами, поэтому без read-modify-write
+func (r *Repository) AppendDialogItem(ctx context.Context, userId int64, key string, value string, limit int) (json.RawMessage, bool, error) {
+	var result json.RawMessage
+
+	query := `
+		UPDATE bot_dialogs
+		SET
+			"data" = jsonb_set("data", ARRAY[$2::text], COALESCE("data"->$2, '[]'::jsonb) || to_jsonb($3::text)),
+			updated_at = now()
+		WHERE
+			user_id = $1
+			AND jsonb_array_length(COALESCE("data"->$2, '[]'::jsonb)) < $4
+		RETURNING "data"
+	`
+
+	err := r.db.QueryRowContext(ctx, query, userId, key, value, limit).Scan(&result)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, false, nil
+		}
+		return result, false, err
+	}
+
+	return result, true, nil
+}
+
+func (r *Repository) DeleteDialog(ctx context.Context, userId int64) error {
+	query := `
+		DELETE FROM bot_dialogs
+		WHERE user_id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteDialogsBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM bot_dialogs
+		WHERE updated_at < $1
+	`
+
+	res, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
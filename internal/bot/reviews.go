@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	appErrors "vietio/internal/errors"
+	"vietio/internal/reviews"
+	"vietio/internal/telegram"
+)
+
+// отзыв после сделки: оценка кнопкой, затем комментарий
+const SCENARIO_REVIEW = "review"
+
+const STEP_REVIEW_COMMENT = "comment"
+
+type ReviewsService interface {
+	CreateReview(ctx context.Context, dealId int64, payload reviews.CreateReviewRequestBody) (reviews.CreateReviewResponse, error)
+}
+
+type reviewDraft struct {
+	DealId int64 `json:"deal_id"`
+	Rating int   `json:"rating"`
+}
+
+// кнопки отзыва: review:rate:<deal_id>:<rating>, review:skip
+func (b *Bot) reviewButton(ctx context.Context, update *telegram.Update) error {
+	query := update.CallbackQuery
+	chatId := update.ChatId()
+
+	botUser, ok := userFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	args := telegram.CallbackArgs(query.Data)
+
+	switch {
+	case len(args) == 3 && args[0] == "rate":
+		dealId, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return b.answer(ctx, query.Id, "")
+		}
+		rating, err := strconv.Atoi(args[2])
+		if err != nil || rating < reviews.MIN_RATING || rating > reviews.MAX_RATING {
+			return b.answer(ctx, query.Id, "")
+		}
+
+		draft := reviewDraft{DealId: dealId, Rating: rating}
+		if err := b.saveDialog(ctx, botUser.Id, SCENARIO_REVIEW, STEP_REVIEW_COMMENT, draft); err != nil {
+			return err
+		}
+		if err := b.answer(ctx, query.Id, ""); err != nil {
+			return err
+		}
+		return b.replyWithMarkup(ctx, chatId, "review_comment", draft, skipCommentKeyboard())
+
+	case len(args) == 1 && args[0] == "skip":
+		dialog, ok, err := b.activeDialog(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok || dialog.Scenario != SCENARIO_REVIEW {
+			return b.answer(ctx, query.Id, "Оценка устарела")
+		}
+		if err := b.answer(ctx, query.Id, ""); err != nil {
+			return err
+		}
+		return b.submitReview(ctx, chatId, dialog, "")
+
+	default:
+		return b.answer(ctx, query.Id, "")
+	}
+}
+
+// комментарий к отзыву текстом
+func (b *Bot) reviewMessage(ctx context.Context, update *telegram.Update, dialog DialogModel) error {
+	text := strings.TrimSpace(update.Message.Text)
+	if text == "" {
+		var draft reviewDraft
+		if err := json.Unmarshal(dialog.Data, &draft); err != nil {
+			return err
+		}
+		return b.replyWithMarkup(ctx, update.ChatId(), "review_comment", draft, skipCommentKeyboard())
+	}
+
+	return b.submitReview(ctx, update.ChatId(), dialog, text)
+}
+
+func (b *Bot) submitReview(ctx context.Context, chatId int64, dialog DialogModel, comment string) error {
+	botUser, _ := userFromContext(ctx)
+
+	var draft reviewDraft
+	if err := json.Unmarshal(dialog.Data, &draft); err != nil {
+		return err
+	}
+
+	result, err := b.reviews.CreateReview(ctx, draft.DealId, reviews.CreateReviewRequestBody{
+		Rating:  draft.Rating,
+		Comment: comment,
+	})
+	if err != nil {
+		var validationErr *appErrors.ValidationError
+
+		switch {
+		case errors.As(err, &validationErr):
+			// диалог оставляем: можно переписать комментарий
+			return b.replyWithMarkup(ctx, chatId, "review_invalid", validationErr.Errors, skipCommentKeyboard())
+		case errors.Is(err, appErrors.ErrReviewExists):
+			if err := b.dialogs.DeleteDialog(ctx, botUser.Id); err != nil {
+				return err
+			}
+			return b.reply(ctx, chatId, "review_exists", nil)
+		case errors.Is(err, appErrors.ErrDealNotFound), errors.Is(err, appErrors.ErrForbidden):
+			if err := b.dialogs.DeleteDialog(ctx, botUser.Id); err != nil {
+				return err
+			}
+			return b.reply(ctx, chatId, "review_not_found", nil)
+		default:
+			return err
+		}
+	}
+
+	if err := b.dialogs.DeleteDialog(ctx, botUser.Id); err != nil {
+		return err
+	}
+
+	return b.reply(ctx, chatId, "review_saved", result)
+}
+
+func skipCommentKeyboard() *telegram.InlineKeyboardMarkup {
+	return &telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{
+			{
+				{Text: "Без комментария", CallbackData: telegram.CallbackData(reviews.CALLBACK_PREFIX, "skip")},
+			},
+		},
+	}
+}
@@ -0,0 +1,33 @@
+package bot
+
+import (
+	"embed"
+	"strings"
+	"text/template"
+
+	"vietio/pkg/utils"
+)
+
+//go:embed templates/*.tmpl
+var templatesFS embed.FS
+
+func parseTemplates() *template.Template {
+	funcs := template.FuncMap{
+		"price": utils.FormatPrice,
+	}
+
+	return template.Must(
+		template.New("bot").Funcs(funcs).ParseFS(templatesFS, "templates/*.tmpl"),
+	)
+}
+
+func (b *Bot) render(name string, data any) (string, error) {
+	var result strings.Builder
+
+	err := b.templates.ExecuteTemplate(&result, name+".tmpl", data)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(result.String()), nil
+}
@@ -0,0 +1,7 @@
+package categories
+
+type CategoryModel struct {
+	Id    int
+	Name  string
+	Order int
+}
@@ -26,4 +26,29 @@ func (r *Repository) Exists(ctx context.Context, categoryId int) (bool, error) {
 
     err := r.db.QueryRowContext(ctx, query, categoryId).Scan(&result)
     return result, err
-}
\ No newline at end of file
+}
+func (r *Repository) FindAll(ctx context.Context) ([]CategoryModel, error) {
+	var result []CategoryModel
+
+	query := `
+		SELECT id, "name", "order"
+		FROM categories
+		ORDER BY "order" ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var category CategoryModel
+		if err := rows.Scan(&category.Id, &category.Name, &category.Order); err != nil {
+			return result, err
+		}
+		result = append(result, category)
+	}
+
+	return result, rows.Err()
+}
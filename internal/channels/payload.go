@@ -0,0 +1,36 @@
+package channels
+
+import (
+	"github.com/google/uuid"
+)
+
+// виды уведомлений в очереди notifications
+const KIND_PUBLISH = "channel_publish"
+const KIND_EDIT = "channel_edit"
+const KIND_DELETE = "channel_delete"
+
+// объявление в том виде, в каком оно уходит в канал
+type AdModel struct {
+	Uuid        uuid.UUID
+	Title       string
+	Description string
+	Price       int
+	Status      int
+	CityId      int
+	CategoryId  int
+	IsFlagged   bool
+	Images      []string
+}
+
+// сообщения объявления в канале
+type PostModel struct {
+	AdUuid     uuid.UUID
+	ChatId     int64
+	MessageIds []int64
+	// false — объявление без фото ушло текстом
+	WithMedia bool
+}
+
+type jobPayload struct {
+	AdUuid uuid.UUID `json:"ad_uuid"`
+}
@@ -0,0 +1,203 @@
+package channels
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// каналы, куда попадает объявление: пустые city_id и category_id означают «любой»
+func (r *Repository) FindRouteChatIds(ctx context.Context, cityId int, categoryId int) ([]int64, error) {
+	var result []int64
+
+	query := `
+		SELECT DISTINCT chat_id
+		FROM channel_routes
+		WHERE
+			is_active = true
+			AND (city_id IS NULL OR city_id = $1)
+			AND (category_id IS NULL OR category_id = $2)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, cityId, categoryId)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var chatId int64
+		if err := rows.Scan(&chatId); err != nil {
+			return result, err
+		}
+		result = append(result, chatId)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Repository) FindAd(ctx context.Context, adUuid uuid.UUID) (AdModel, error) {
+	var result AdModel
+
+	query := `
+		SELECT
+			uuid,
+			title,
+			description,
+			price,
+			status,
+			city_id,
+			category_id,
+			flagged_at IS NOT NULL
+		FROM ads
+		WHERE uuid = $1
+	`
+
+	err := r.db.QueryRowContext(ctx, query, adUuid).Scan(
+		&result.Uuid,
+		&result.Title,
+		&result.Description,
+		&result.Price,
+		&result.Status,
+		&result.CityId,
+		&result.CategoryId,
+		&result.IsFlagged,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	query = `
+		SELECT path
+		FROM files
+		WHERE ad_uuid = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, adUuid)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return result, err
+		}
+		result.Images = append(result.Images, path)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Repository) FindPosts(ctx context.Context, adUuid uuid.UUID) ([]PostModel, error) {
+	var result []PostModel
+
+	query := `
+		SELECT ad_uuid, chat_id, message_ids, with_media
+		FROM channel_posts
+		WHERE ad_uuid = $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, adUuid)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, post)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Repository) FindPost(ctx context.Context, adUuid uuid.UUID, chatId int64) (PostModel, error) {
+	query := `
+		SELECT ad_uuid, chat_id, message_ids, with_media
+		FROM channel_posts
+		WHERE
+			ad_uuid = $1
+			AND chat_id = $2
+	`
+
+	return scanPost(r.db.QueryRowContext(ctx, query, adUuid, chatId))
+}
+
+func (r *Repository) SavePost(ctx context.Context, post PostModel) error {
+	messageIds, err := json.Marshal(post.MessageIds)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO channel_posts (ad_uuid, chat_id, message_ids, with_media)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (ad_uuid, chat_id) DO UPDATE
+		SET
+			message_ids = EXCLUDED.message_ids,
+			with_media = EXCLUDED.with_media,
+			updated_at = now()
+	`
+
+	_, err = r.db.ExecContext(ctx, query, post.AdUuid, post.ChatId, messageIds, post.WithMedia)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) DeletePost(ctx context.Context, adUuid uuid.UUID, chatId int64) error {
+	query := `
+		DELETE FROM channel_posts
+		WHERE
+			ad_uuid = $1
+			AND chat_id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, adUuid, chatId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPost(row rowScanner) (PostModel, error) {
+	var result PostModel
+	var messageIds []byte
+
+	err := row.Scan(&result.AdUuid, &result.ChatId, &messageIds, &result.WithMedia)
+	if err != nil {
+		return result, err
+	}
+
+	err = json.Unmarshal(messageIds, &result.MessageIds)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,374 @@
+package channels
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"vietio/internal/ads"
+	"vietio/internal/deeplink"
+	"vietio/internal/images"
+	"vietio/internal/notifications"
+	"vietio/internal/telegram"
+	"vietio/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+// подпись к фото в Telegram — до 1024 символов текста после разбора HTML
+const captionMaxLength = 1024
+
+// длинное описание в канале не нужно, полностью оно есть в Mini App
+const descriptionMaxLength = 600
+
+var tagRegexp = regexp.MustCompile(`<[^>]*>`)
+
+type Queue interface {
+	Enqueue(ctx context.Context, kind string, chatId int64, payload any) error
+	Handle(kind string, handler notifications.HandlerFunc)
+}
+
+// подписанные ссылки на варианты фото (images.Signer): Telegram скачивает фото по ссылке
+type ImageVariants interface {
+	GetVariantPath(path string, name string) string
+}
+
+// Service публикует объявления в Telegram-каналы по channel_routes.
+// Реализует ads.AdEvents: на каждое событие ставит задачи в очередь уведомлений,
+// сами запросы к Bot API выполняются воркерами очереди
+type Service struct {
+	repo   *Repository
+	client *telegram.Client
+	queue  Queue
+	images ImageVariants
+	links  *deeplink.Builder
+	logger *slog.Logger
+}
+
+func NewService(
+	repo *Repository,
+	client *telegram.Client,
+	queue Queue,
+	imageVariants ImageVariants,
+	links *deeplink.Builder,
+	logger *slog.Logger,
+) *Service {
+	s := &Service{
+		repo:   repo,
+		client: client,
+		queue:  queue,
+		images: imageVariants,
+		links:  links,
+		logger: logger,
+	}
+
+	queue.Handle(KIND_PUBLISH, s.publish)
+	queue.Handle(KIND_EDIT, s.edit)
+	queue.Handle(KIND_DELETE, s.delete)
+
+	return s
+}
+
+// AdPublished новое активное объявление уходит во все подходящие каналы
+func (s *Service) AdPublished(ctx context.Context, adUuid uuid.UUID) {
+	ad, err := s.repo.FindAd(ctx, adUuid)
+	if err != nil {
+		s.logger.Error("ошибка при получении объявления для канала", "err", err, "uuid", adUuid)
+		return
+	}
+
+	// объявления на модерации в канал не попадают
+	if ad.Status != ads.STATUS_ACTIVE || ad.IsFlagged {
+		return
+	}
+
+	chatIds, err := s.repo.FindRouteChatIds(ctx, ad.CityId, ad.CategoryId)
+	if err != nil {
+		s.logger.Error("ошибка при получении каналов", "err", err, "uuid", adUuid)
+		return
+	}
+
+	s.enqueue(ctx, KIND_PUBLISH, chatIds, adUuid)
+}
+
+// AdUpdated обновляет подпись в каналах, где объявление уже есть
+func (s *Service) AdUpdated(ctx context.Context, adUuid uuid.UUID) {
+	chatIds, err := s.postChatIds(ctx, adUuid)
+	if err != nil {
+		s.logger.Error("ошибка при получении постов объявления", "err", err, "uuid", adUuid)
+		return
+	}
+
+	if len(chatIds) == 0 {
+		s.AdPublished(ctx, adUuid)
+		return
+	}
+
+	s.enqueue(ctx, KIND_EDIT, chatIds, adUuid)
+}
+
+// AdClosed проданное помечаем в подписи, остальное удаляем из каналов
+func (s *Service) AdClosed(ctx context.Context, adUuid uuid.UUID, status int) {
+	chatIds, err := s.postChatIds(ctx, adUuid)
+	if err != nil {
+		s.logger.Error("ошибка при получении постов объявления", "err", err, "uuid", adUuid)
+		return
+	}
+
+	kind := KIND_DELETE
+	if status == ads.STATUS_SOLD {
+		kind = KIND_EDIT
+	}
+
+	s.enqueue(ctx, kind, chatIds, adUuid)
+}
+
+func (s *Service) enqueue(ctx context.Context, kind string, chatIds []int64, adUuid uuid.UUID) {
+	for _, chatId := range chatIds {
+		err := s.queue.Enqueue(ctx, kind, chatId, jobPayload{AdUuid: adUuid})
+		if err != nil {
+			s.logger.Error("ошибка при постановке задачи канала", "err", err, "kind", kind, "uuid", adUuid)
+		}
+	}
+}
+
+func (s *Service) postChatIds(ctx context.Context, adUuid uuid.UUID) ([]int64, error) {
+	posts, err := s.repo.FindPosts(ctx, adUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	chatIds := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		chatIds = append(chatIds, post.ChatId)
+	}
+
+	return chatIds, nil
+}
+
+// альбом из фото с подписью на первом, одно фото или просто текст
+func (s *Service) publish(ctx context.Context, notification notifications.NotificationModel) error {
+	ad, err := s.jobAd(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	if ad.Status != ads.STATUS_ACTIVE || ad.IsFlagged {
+		return nil
+	}
+
+	// повтор задачи после частичного сбоя не должен дублировать пост
+	_, err = s.repo.FindPost(ctx, ad.Uuid, notification.ChatId)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	post := PostModel{
+		AdUuid:    ad.Uuid,
+		ChatId:    notification.ChatId,
+		WithMedia: len(ad.Images) > 0,
+	}
+	caption := s.caption(ad)
+
+	switch len(ad.Images) {
+	case 0:
+		message, err := s.client.SendMessage(ctx, telegram.SendMessageParams{
+			ChatId:    notification.ChatId,
+			Text:      caption,
+			ParseMode: telegram.PARSE_MODE_HTML,
+		})
+		if err != nil {
+			return err
+		}
+		post.MessageIds = []int64{message.MessageId}
+
+	case 1:
+		message, err := s.client.SendPhoto(ctx, telegram.SendPhotoParams{
+			ChatId:    notification.ChatId,
+			Photo:     s.images.GetVariantPath(ad.Images[0], images.VARIANT_FULL),
+			Caption:   caption,
+			ParseMode: telegram.PARSE_MODE_HTML,
+		})
+		if err != nil {
+			return err
+		}
+		post.MessageIds = []int64{message.MessageId}
+
+	default:
+		media := make([]telegram.InputMediaPhoto, 0, len(ad.Images))
+		for _, image := range ad.Images {
+			media = append(media, telegram.NewInputMediaPhoto(s.images.GetVariantPath(image, images.VARIANT_FULL)))
+		}
+		media[0].Caption = caption
+		media[0].ParseMode = telegram.PARSE_MODE_HTML
+
+		messages, err := s.client.SendMediaGroup(ctx, telegram.SendMediaGroupParams{
+			ChatId: notification.ChatId,
+			Media:  media,
+		})
+		if err != nil {
+			return err
+		}
+		for _, message := range messages {
+			post.MessageIds = append(post.MessageIds, message.MessageId)
+		}
+	}
+
+	return s.repo.SavePost(ctx, post)
+}
+
+func (s *Service) edit(ctx context.Context, notification notifications.NotificationModel) error {
+	ad, err := s.jobAd(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	post, err := s.repo.FindPost(ctx, ad.Uuid, notification.ChatId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	return s.editPost(ctx, post, s.caption(ad))
+}
+
+// если удалить не вышло (нет прав), хотя бы убираем текст объявления
+func (s *Service) delete(ctx context.Context, notification notifications.NotificationModel) error {
+	var payload jobPayload
+	if err := json.Unmarshal(notification.Payload, &payload); err != nil {
+		return err
+	}
+
+	post, err := s.repo.FindPost(ctx, payload.AdUuid, notification.ChatId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	err = s.client.DeleteMessages(ctx, post.ChatId, post.MessageIds)
+	if telegram.IsBadRequest(err) {
+		err = s.editPost(ctx, post, "Объявление снято с публикации")
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.repo.DeletePost(ctx, post.AdUuid, post.ChatId)
+}
+
+func (s *Service) editPost(ctx context.Context, post PostModel, text string) error {
+	var err error
+
+	if post.WithMedia {
+		err = s.client.EditMessageCaption(ctx, telegram.EditMessageCaptionParams{
+			ChatId:    post.ChatId,
+			MessageId: post.MessageIds[0],
+			Caption:   text,
+			ParseMode: telegram.PARSE_MODE_HTML,
+		})
+	} else {
+		err = s.client.EditMessageText(ctx, telegram.EditMessageTextParams{
+			ChatId:    post.ChatId,
+			MessageId: post.MessageIds[0],
+			Text:      text,
+			ParseMode: telegram.PARSE_MODE_HTML,
+		})
+	}
+
+	if telegram.IsMessageNotModified(err) {
+		return nil
+	}
+
+	return err
+}
+
+// объявление читаем в момент отправки, чтобы в канал ушли актуальные данные
+func (s *Service) jobAd(ctx context.Context, notification notifications.NotificationModel) (AdModel, error) {
+	var payload jobPayload
+	if err := json.Unmarshal(notification.Payload, &payload); err != nil {
+		return AdModel{}, err
+	}
+
+	return s.repo.FindAd(ctx, payload.AdUuid)
+}
+
+func (s *Service) caption(ad AdModel) string {
+	header := ""
+	switch ad.Status {
+	case ads.STATUS_SOLD:
+		header = "✅ <b>ПРОДАНО</b>\n\n"
+	case ads.STATUS_RESERVED:
+		header = "🤝 <b>ЗАБРОНИРОВАНО</b>\n\n"
+	}
+
+	price := fmt.Sprintf("\n💰 %s\n\n", utils.FormatPrice(ad.Price))
+
+	footer := ""
+	if link := s.links.Ad(ad.Uuid.String()); link != "" && ads.IsVisibleStatus(ad.Status) {
+		footer = fmt.Sprintf("\n\n<a href=\"%s\">Открыть в Vietio</a>", html.EscapeString(link))
+	}
+
+	// заголовок и описание делят то, что осталось от лимита после служебных строк
+	budget := captionMaxLength - visibleLength(header+price+footer)
+	title := truncate(ad.Title, budget)
+	description := truncate(ad.Description, min(budget-textLength(title), descriptionMaxLength))
+
+	var result strings.Builder
+
+	result.WriteString(header)
+	fmt.Fprintf(&result, "<b>%s</b>", html.EscapeString(title))
+	result.WriteString(price)
+	result.WriteString(html.EscapeString(description))
+	result.WriteString(footer)
+
+	return result.String()
+}
+
+// длина текста подписи так, как ее видит Telegram: без тегов, с раскрытыми сущностями
+func visibleLength(caption string) int {
+	return textLength(html.UnescapeString(tagRegexp.ReplaceAllString(caption, "")))
+}
+
+// Telegram считает длину в UTF-16: эмодзи занимают два символа
+func textLength(text string) int {
+	length := 0
+	for _, r := range text {
+		length += utf16.RuneLen(r)
+	}
+
+	return length
+}
+
+// обрезает текст до limit символов вместе с многоточием
+func truncate(text string, limit int) string {
+	if textLength(text) <= limit {
+		return text
+	}
+	if limit <= 0 {
+		return ""
+	}
+
+	length := 0
+	for i, r := range text {
+		length += utf16.RuneLen(r)
+		if length > limit-1 {
+			return strings.TrimSpace(text[:i]) + "…"
+		}
+	}
+
+	return text
+}
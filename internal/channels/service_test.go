@@ -0,0 +1,69 @@
+package channels
+
+import (
+	"strings"
+	"testing"
+
+	"vietio/internal/ads"
+	"vietio/internal/deeplink"
+
+	"github.com/google/uuid"
+)
+
+func TestCaptionLength(t *testing.T) {
+	service := &Service{links: deeplink.NewBuilder("vietio_bot", "app")}
+
+	tests := []struct {
+		name        string
+		title       string
+		description string
+		status      int
+	}{
+		{"short", "Honda Vision", "Отличное состояние", ads.STATUS_ACTIVE},
+		{"long description", "Honda Vision", strings.Repeat("описание ", 300), ads.STATUS_ACTIVE},
+		{"long title", strings.Repeat("Заголовок ", 200), strings.Repeat("описание ", 300), ads.STATUS_ACTIVE},
+		{"long title sold", strings.Repeat("Заголовок ", 200), "коротко", ads.STATUS_SOLD},
+		{"emoji and entities", strings.Repeat("🛵 <&> ", 300), strings.Repeat("🔥", 1000), ads.STATUS_RESERVED},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caption := service.caption(AdModel{
+				Uuid:        uuid.New(),
+				Title:       tt.title,
+				Description: tt.description,
+				Price:       1500000,
+				Status:      tt.status,
+			})
+
+			if length := visibleLength(caption); length > captionMaxLength {
+				t.Errorf("visible length = %d, want at most %d", length, captionMaxLength)
+			}
+			if textLength(tt.title+tt.description) < captionMaxLength/2 && !strings.Contains(caption, tt.description) {
+				t.Errorf("short description must not be truncated: %q", caption)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		text  string
+		limit int
+		want  string
+	}{
+		{"Honda Vision", 20, "Honda Vision"},
+		{"Honda Vision", 7, "Honda…"},
+		{"🛵🛵🛵", 4, "🛵…"},
+		{"Honda", 0, ""},
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.text, tt.limit); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+		}
+		if got := textLength(truncate(tt.text, tt.limit)); got > tt.limit && tt.limit > 0 {
+			t.Errorf("truncate(%q, %d) length = %d", tt.text, tt.limit, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package contentfilter
+
+import "time"
+
+// типы правил
+const RULE_WORD = "word"
+const RULE_REGEX = "regex"
+const RULE_CAPS = "caps"
+const RULE_EMOJI = "emoji"
+const RULE_REPEAT = "repeat"
+const RULE_PHONE = "phone"
+const RULE_LINK = "link"
+const RULE_HANDLE = "handle"
+
+// действия при срабатывании правила
+const ACTION_REJECT = "reject"
+const ACTION_FLAG = "flag"
+const ACTION_MASK = "mask"
+
+type RuleModel struct {
+	Id         int64
+	Type       string
+	Pattern    string
+	Action     string
+	CategoryId *int
+	Message    string
+	UpdatedAt  time.Time
+}
+
+// проверяемое поле (title, description, comment...)
+type Field struct {
+	Name  string
+	Value string
+}
+
+type Match struct {
+	RuleId  int64
+	Field   string
+	Type    string
+	Action  string
+	Message string
+}
+
+type Result struct {
+	Fields  []Field
+	Matches []Match
+}
+
+func (r Result) Value(name string) string {
+	for _, f := range r.Fields {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+
+	return ""
+}
+
+func (r Result) Rejected() []Match {
+	return r.filterMatches(ACTION_REJECT)
+}
+
+func (r Result) Flagged() []Match {
+	return r.filterMatches(ACTION_FLAG)
+}
+
+func (r Result) IsRejected() bool {
+	return len(r.Rejected()) > 0
+}
+
+func (r Result) IsFlagged() bool {
+	return len(r.Flagged()) > 0
+}
+
+func (r Result) filterMatches(action string) []Match {
+	var result []Match
+
+	for _, m := range r.Matches {
+		if m.Action == action {
+			result = append(result, m)
+		}
+	}
+
+	return result
+}
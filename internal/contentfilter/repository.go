@@ -0,0 +1,69 @@
+package contentfilter
+
+import (
+	"context"
+	"database/sql"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) FindActiveRules(ctx context.Context) ([]RuleModel, error) {
+	var result []RuleModel
+
+	query := `
+		SELECT
+			id,
+			"type",
+			pattern,
+			"action",
+			category_id,
+			COALESCE(message, ''),
+			updated_at
+		FROM
+			content_rules
+		WHERE
+			is_active = true
+		ORDER BY
+			id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rule RuleModel
+		var categoryId sql.NullInt64
+
+		if err := rows.Scan(
+			&rule.Id,
+			&rule.Type,
+			&rule.Pattern,
+			&rule.Action,
+			&categoryId,
+			&rule.Message,
+			&rule.UpdatedAt,
+		); err != nil {
+			return result, err
+		}
+
+		if categoryId.Valid {
+			id := int(categoryId.Int64)
+			rule.CategoryId = &id
+		}
+
+		result = append(result, rule)
+	}
+
+	return result, rows.Err()
+}
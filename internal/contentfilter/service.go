@@ -0,0 +1,330 @@
+package contentfilter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// как часто перечитываем правила из БД
+const rulesTTL = time.Minute
+
+// минимальное количество букв для проверки на капс
+const capsMinLetters = 10
+
+const maskReplacement = "***"
+
+// телефоны: любой номер с +, вьетнамские 0xx/84xx и российские 89xx без плюса.
+// Без префикса длинные числа не считаем телефоном — это цены в донгах
+var phoneRegexp = regexp.MustCompile(
+	`\+\d(?:[\s\-().]*\d){8,14}` +
+		`|\b(?:84|0)[\s\-().]*[1-9](?:[\s\-().]*\d){8}\b` +
+		`|\b8[\s\-().]*9(?:[\s\-().]*\d){9}\b`,
+)
+
+// число, разбитое на тысячи: 84 500 000 000, 8.900.000.000
+var groupedNumberRegexp = regexp.MustCompile(`^[1-9]\d{0,2}(?:[\s.,]\d{3})+$`)
+var linkRegexp = regexp.MustCompile(`(?i)(?:https?://|www\.)\S+|\b[a-z0-9-]+\.(?:com|ru|net|org|vn|me|io|info|biz|xyz|site|online|shop|app)\b(?:/\S*)?`)
+var handleRegexp = regexp.MustCompile(`(?i)(?:^|[^\w@])(@[a-z][a-z0-9_]{3,31}|(?:t|wa|zalo)\.me/\S+|(?:whatsapp|viber|zalo|telegram|ватсап|вотсап|вайбер|зало|телеграм)[:\s]*[+\d@][\w\-+ ]*)`)
+
+type compiledRule struct {
+	RuleModel
+	re    *regexp.Regexp
+	limit float64
+}
+
+type Service struct {
+	repo   *Repository
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	rules    []compiledRule
+	loadedAt time.Time
+}
+
+func NewService(repo *Repository, logger *slog.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Check прогоняет поля через все активные правила категории.
+// В результате поля уже с примененной маскировкой
+func (s *Service) Check(ctx context.Context, categoryId int, fields []Field) (Result, error) {
+	result := Result{
+		Fields: make([]Field, len(fields)),
+	}
+	copy(result.Fields, fields)
+
+	rules, err := s.getRules(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	for _, rule := range rules {
+		if rule.CategoryId != nil && *rule.CategoryId != categoryId {
+			continue
+		}
+
+		for i, field := range result.Fields {
+			matched, masked := rule.apply(field.Value)
+			if !matched {
+				continue
+			}
+
+			if rule.Action == ACTION_MASK {
+				result.Fields[i].Value = masked
+			}
+
+			result.Matches = append(result.Matches, Match{
+				RuleId:  rule.Id,
+				Field:   field.Name,
+				Type:    rule.Type,
+				Action:  rule.Action,
+				Message: rule.message(),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// правила кешируются, изменения в таблице подхватываются через rulesTTL
+func (s *Service) getRules(ctx context.Context) ([]compiledRule, error) {
+	s.mu.RLock()
+	if s.rules != nil && time.Since(s.loadedAt) < rulesTTL {
+		rules := s.rules
+		s.mu.RUnlock()
+		return rules, nil
+	}
+	s.mu.RUnlock()
+
+	models, err := s.repo.FindActiveRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]compiledRule, 0, len(models))
+	for _, model := range models {
+		rule, err := compileRule(model)
+		if err != nil {
+			s.logger.Warn("content rule skipped", "err", err, "rule_id", model.Id)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	s.mu.Lock()
+	s.rules = rules
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return rules, nil
+}
+
+func compileRule(model RuleModel) (compiledRule, error) {
+	rule := compiledRule{RuleModel: model}
+
+	var err error
+
+	switch model.Type {
+	case RULE_WORD:
+		rule.re, err = regexp.Compile(
+			`(?i)(?:^|[^\p{L}\p{N}_])(` + regexp.QuoteMeta(model.Pattern) + `)(?:$|[^\p{L}\p{N}_])`,
+		)
+	case RULE_REGEX:
+		rule.re, err = regexp.Compile(model.Pattern)
+	case RULE_PHONE:
+		rule.re = phoneRegexp
+	case RULE_LINK:
+		rule.re = linkRegexp
+	case RULE_HANDLE:
+		rule.re = handleRegexp
+	case RULE_CAPS, RULE_EMOJI, RULE_REPEAT:
+		rule.limit, err = strconv.ParseFloat(model.Pattern, 64)
+	default:
+		return rule, fmt.Errorf("unknown rule type: %s", model.Type)
+	}
+
+	if err != nil {
+		return rule, err
+	}
+
+	switch model.Action {
+	case ACTION_REJECT, ACTION_FLAG, ACTION_MASK:
+	default:
+		return rule, fmt.Errorf("unknown rule action: %s", model.Action)
+	}
+
+	return rule, nil
+}
+
+func (rule compiledRule) apply(text string) (bool, string) {
+	switch rule.Type {
+	case RULE_CAPS:
+		return applyCaps(text, rule.limit)
+	case RULE_EMOJI:
+		return applyEmoji(text, int(rule.limit))
+	case RULE_REPEAT:
+		return applyRepeat(text, int(rule.limit))
+	case RULE_PHONE:
+		return applyRegexp(text, rule.re, isGroupedNumber)
+	default:
+		return applyRegexp(text, rule.re, nil)
+	}
+}
+
+func (rule compiledRule) message() string {
+	if rule.Message != "" {
+		return rule.Message
+	}
+
+	switch rule.Type {
+	case RULE_WORD, RULE_REGEX:
+		return "текст содержит запрещенные слова"
+	case RULE_CAPS:
+		return "слишком много заглавных букв"
+	case RULE_EMOJI:
+		return "слишком много эмодзи"
+	case RULE_REPEAT:
+		return "слишком много повторяющихся символов"
+	case RULE_PHONE:
+		return "номера телефонов запрещены в этой категории"
+	case RULE_LINK:
+		return "ссылки запрещены в этой категории"
+	case RULE_HANDLE:
+		return "контакты мессенджеров запрещены в этой категории"
+	default:
+		return "текст не прошел проверку"
+	}
+}
+
+// маскируем найденное, если в выражении есть группа — маскируем только ее.
+// skip отбрасывает ложные совпадения
+func applyRegexp(text string, re *regexp.Regexp, skip func(string) bool) (bool, string) {
+	indexes := re.FindAllStringSubmatchIndex(text, -1)
+
+	var matched bool
+	var masked strings.Builder
+	last := 0
+
+	for _, idx := range indexes {
+		start, end := idx[0], idx[1]
+		if len(idx) >= 4 && idx[2] >= 0 {
+			start, end = idx[2], idx[3]
+		}
+		if start < last {
+			continue
+		}
+		if skip != nil && skip(text[start:end]) {
+			continue
+		}
+		matched = true
+
+		masked.WriteString(text[last:start])
+		masked.WriteString(maskReplacement)
+		last = end
+	}
+	if !matched {
+		return false, text
+	}
+	masked.WriteString(text[last:])
+
+	return true, masked.String()
+}
+
+func isGroupedNumber(text string) bool {
+	return groupedNumberRegexp.MatchString(text)
+}
+
+func applyCaps(text string, limit float64) (bool, string) {
+	var letters, upper int
+
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+
+	if letters < capsMinLetters || float64(upper)/float64(letters) < limit {
+		return false, text
+	}
+
+	// оставляем заглавной только первую букву
+	runes := []rune(strings.ToLower(text))
+	for i, r := range runes {
+		if unicode.IsLetter(r) {
+			runes[i] = unicode.ToUpper(r)
+			break
+		}
+	}
+
+	return true, string(runes)
+}
+
+func applyEmoji(text string, limit int) (bool, string) {
+	var count int
+	var masked strings.Builder
+
+	for _, r := range text {
+		if isEmoji(r) {
+			count++
+			if count > limit {
+				continue
+			}
+		}
+		masked.WriteRune(r)
+	}
+
+	if count <= limit {
+		return false, text
+	}
+
+	return true, masked.String()
+}
+
+// цифры и пробелы не считаем: цены вида 10000000 — это нормально
+func applyRepeat(text string, limit int) (bool, string) {
+	var matched bool
+	var masked strings.Builder
+	var prev rune
+	var run int
+
+	for _, r := range text {
+		if r == prev {
+			run++
+		} else {
+			prev = r
+			run = 1
+		}
+
+		if run > limit && !unicode.IsDigit(r) && !unicode.IsSpace(r) {
+			matched = true
+			continue
+		}
+		masked.WriteRune(r)
+	}
+
+	if !matched {
+		return false, text
+	}
+
+	return true, masked.String()
+}
+
+func isEmoji(r rune) bool {
+	return (r >= 0x1F300 && r <= 0x1FAFF) ||
+		(r >= 0x2600 && r <= 0x27BF) ||
+		(r >= 0x1F1E6 && r <= 0x1F1FF)
+}
@@ -0,0 +1,45 @@
+package contentfilter
+
+import "testing"
+
+func TestPhoneRule(t *testing.T) {
+	rule, err := compileRule(RuleModel{Type: RULE_PHONE, Action: ACTION_MASK})
+	if err != nil {
+		t.Fatalf("compileRule: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		matched bool
+		masked  string
+	}{
+		{"vn mobile", "звоните 0901234567", true, "звоните ***"},
+		{"vn mobile spaced", "тел 090 123 4567", true, "тел ***"},
+		{"vn mobile dotted", "0901.234.567 Лан", true, "*** Лан"},
+		{"vn international", "+84 90 123 4567", true, "***"},
+		{"vn without plus", "84901234567", true, "***"},
+		{"ru mobile", "8 (999) 123-45-67", true, "***"},
+		{"ru international", "+7 999 123 45 67", true, "***"},
+		{"price spaced", "цена 150 000 000 донгов", false, "цена 150 000 000 донгов"},
+		{"price dotted", "150.000.000 vnd", false, "150.000.000 vnd"},
+		{"price comma", "1,500,000", false, "1,500,000"},
+		{"price plain", "цена 150000000", false, "цена 150000000"},
+		{"price starting with 84", "84 500 000 000 за виллу", false, "84 500 000 000 за виллу"},
+		{"price starting with 8", "8.900.000.000", false, "8.900.000.000"},
+		{"price and phone", "2 500 000, звонить 0912345678", true, "2 500 000, звонить ***"},
+		{"year and mileage", "2019 год, пробег 15000 км", false, "2019 год, пробег 15000 км"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, masked := rule.apply(tt.text)
+			if matched != tt.matched {
+				t.Errorf("apply(%q) matched = %v, want %v", tt.text, matched, tt.matched)
+			}
+			if masked != tt.masked {
+				t.Errorf("apply(%q) masked = %q, want %q", tt.text, masked, tt.masked)
+			}
+		})
+	}
+}
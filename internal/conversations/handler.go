@@ -0,0 +1,187 @@
+package conversations
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	appErrors "vietio/internal/errors"
+	"vietio/internal/response"
+
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service *Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service *Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) StartConversation(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuid.Parse(r.PathValue("uuid"))
+	if err != nil {
+		h.logger.Error(appErrors.ErrNotValidUuid.Error(), "err", err, "uuid", uuid)
+		http.Error(w, appErrors.ErrNotValidUuid.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.service.Start(r.Context(), uuid)
+	if err != nil {
+		switch {
+		case errors.Is(err, appErrors.ErrAdNotFound):
+			h.logger.Info(appErrors.ErrAdNotFound.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotFound.Error(), http.StatusNotFound)
+		case errors.Is(err, appErrors.ErrAdNotActive):
+			h.logger.Info(appErrors.ErrAdNotActive.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotActive.Error(), http.StatusConflict)
+		case errors.Is(err, appErrors.ErrConversationSelf):
+			h.logger.Info(appErrors.ErrConversationSelf.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrConversationSelf.Error(), http.StatusBadRequest)
+		default:
+			h.logger.Error(appErrors.ErrConversation.Error(), "err", err, "uuid", uuid)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
+
+func (h *Handler) GetAdContacts(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuid.Parse(r.PathValue("uuid"))
+	if err != nil {
+		h.logger.Error(appErrors.ErrNotValidUuid.Error(), "err", err, "uuid", uuid)
+		http.Error(w, appErrors.ErrNotValidUuid.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := h.service.GetAdContacts(r.Context(), uuid)
+	if err != nil {
+		switch {
+		case errors.Is(err, appErrors.ErrAdNotFound):
+			h.logger.Info(appErrors.ErrAdNotFound.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotFound.Error(), http.StatusNotFound)
+		case errors.Is(err, appErrors.ErrForbidden):
+			h.logger.Warn(appErrors.ErrForbidden.Error(), "err", "нет прав на просмотр покупателей", "uuid", uuid)
+			http.Error(w, "forbidden", http.StatusForbidden)
+		default:
+			h.logger.Error(appErrors.ErrConversationsList.Error(), "err", err, "uuid", uuid)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
+
+func (h *Handler) GetMyConversations(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.GetMyConversations(r.Context())
+	if err != nil {
+		h.logger.Error(appErrors.ErrConversationsList.Error(), "err", err)
+		http.Error(w, "internal server", http.StatusInternalServerError)
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
+
+func (h *Handler) GetConversation(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseId(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.GetMessages(r.Context(), id)
+	if err != nil {
+		h.handleError(w, err, id)
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
+
+func (h *Handler) BlockConversation(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseId(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.service.Block(r.Context(), id)
+	if err != nil {
+		h.handleError(w, err, id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) UnblockConversation(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseId(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.service.Unblock(r.Context(), id)
+	if err != nil {
+		h.handleError(w, err, id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) ReportConversation(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseId(w, r)
+	if !ok {
+		return
+	}
+
+	payload := ReportRequestBody{}
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.Json(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.Report(r.Context(), id, payload.Reason)
+	if err != nil {
+		h.handleError(w, err, id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) parseId(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		h.logger.Info(appErrors.ErrNotValidId.Error(), "err", err, "id", r.PathValue("id"))
+		http.Error(w, appErrors.ErrNotValidId.Error(), http.StatusBadRequest)
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (h *Handler) handleError(w http.ResponseWriter, err error, id int64) {
+	switch {
+	case errors.Is(err, appErrors.ErrConversationNotFound):
+		h.logger.Info(appErrors.ErrConversationNotFound.Error(), "err", err, "id", id)
+		http.Error(w, appErrors.ErrConversationNotFound.Error(), http.StatusNotFound)
+	case errors.Is(err, appErrors.ErrForbidden):
+		h.logger.Warn(appErrors.ErrForbidden.Error(), "err", "нет доступа к диалогу", "id", id)
+		http.Error(w, "forbidden", http.StatusForbidden)
+	default:
+		h.logger.Error(appErrors.ErrConversation.Error(), "err", err, "id", id)
+		http.Error(w, "internal server", http.StatusInternalServerError)
+	}
+}
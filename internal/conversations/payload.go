@@ -0,0 +1,128 @@
+package conversations
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// роль пользователя в диалоге
+const ROLE_BUYER = "buyer"
+const ROLE_SELLER = "seller"
+
+// префикс callback-кнопок под пересланными сообщениями
+const CALLBACK_PREFIX = "conv"
+
+// сколько последних сообщений отдаем в истории диалога
+const messagesLimit = 100
+
+type ConversationModel struct {
+	Id            int64
+	AdUuid        uuid.UUID
+	AdTitle       string
+	BuyerId       int64
+	SellerId      int64
+	BlockedBy     *int64
+	BlockedAt     *time.Time
+	LastMessageAt *time.Time
+	CreatedAt     time.Time
+}
+
+// Role роль userId в диалоге
+func (c ConversationModel) Role(userId int64) string {
+	if c.SellerId == userId {
+		return ROLE_SELLER
+	}
+
+	return ROLE_BUYER
+}
+
+// Recipient второй участник диалога
+func (c ConversationModel) Recipient(userId int64) int64 {
+	if c.SellerId == userId {
+		return c.BuyerId
+	}
+
+	return c.SellerId
+}
+
+func (c ConversationModel) HasParticipant(userId int64) bool {
+	return c.BuyerId == userId || c.SellerId == userId
+}
+
+type ConversationsListItemRepository struct {
+	ConversationModel
+	AdImage     string
+	LastMessage string
+}
+
+// покупатель, писавший продавцу по объявлению
+type ContactRepository struct {
+	ConversationId int64
+	BuyerId        int64
+	FirstName      string
+	LastName       string
+	PhotoUrl       string
+	LastMessageAt  *time.Time
+}
+
+type MessageModel struct {
+	Id             int64
+	ConversationId int64
+	SenderId       int64
+	Text           string
+	CreatedAt      time.Time
+}
+
+type StartConversationResponse struct {
+	Id int64 `json:"id"`
+	// ссылка в чат с ботом, Mini App открывает ее через openTelegramLink
+	BotUrl string `json:"bot_url"`
+}
+
+type ConversationResponse struct {
+	Id            int64      `json:"id"`
+	AdUuid        uuid.UUID  `json:"ad_uuid"`
+	AdTitle       string     `json:"ad_title"`
+	AdImage       string     `json:"ad_image"`
+	Role          string     `json:"role"`
+	IsBlocked     bool       `json:"is_blocked"`
+	BlockedByMe   bool       `json:"blocked_by_me"`
+	LastMessage   string     `json:"last_message"`
+	LastMessageAt *time.Time `json:"last_message_at"`
+	BotUrl        string     `json:"bot_url"`
+}
+
+type ConversationsListResponse struct {
+	Items []ConversationResponse `json:"items"`
+	Total int                    `json:"total"`
+}
+
+type MessageResponse struct {
+	Id        int64     `json:"id"`
+	IsMine    bool      `json:"is_mine"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MessagesListResponse struct {
+	Conversation ConversationResponse `json:"conversation"`
+	Items        []MessageResponse    `json:"items"`
+}
+
+type ContactResponse struct {
+	UserId         int64      `json:"user_id"`
+	DisplayName    string     `json:"display_name"`
+	PhotoUrl       string     `json:"photo_url"`
+	ConversationId int64      `json:"conversation_id"`
+	LastMessageAt  *time.Time `json:"last_message_at"`
+}
+
+type ContactsListResponse struct {
+	Items []ContactResponse `json:"items"`
+	Total int               `json:"total"`
+}
+
+type ReportRequestBody struct {
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,332 @@
+package conversations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// один диалог на пару объявление + покупатель
+func (r *Repository) FindOrCreate(ctx context.Context, adUuid uuid.UUID, buyerId int64, sellerId int64) (int64, error) {
+	var result int64
+
+	query := `
+		INSERT INTO conversations (ad_uuid, buyer_id, seller_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (ad_uuid, buyer_id) DO UPDATE
+		SET updated_at = now()
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query, adUuid, buyerId, sellerId).Scan(&result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (ConversationModel, error) {
+	var result ConversationModel
+
+	query := `
+		SELECT
+			c.id,
+			c.ad_uuid,
+			a.title,
+			c.buyer_id,
+			c.seller_id,
+			c.blocked_by,
+			c.blocked_at,
+			c.last_message_at,
+			c.created_at
+		FROM conversations AS c
+		JOIN ads AS a ON a.uuid = c.ad_uuid
+		WHERE c.id = $1
+	`
+
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&result.Id,
+		&result.AdUuid,
+		&result.AdTitle,
+		&result.BuyerId,
+		&result.SellerId,
+		&result.BlockedBy,
+		&result.BlockedAt,
+		&result.LastMessageAt,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// диалоги пользователя в обеих ролях, сначала с последними сообщениями
+func (r *Repository) FindByUserId(ctx context.Context, userId int64) ([]ConversationsListItemRepository, error) {
+	var result []ConversationsListItemRepository
+
+	query := `
+		SELECT
+			c.id,
+			c.ad_uuid,
+			a.title,
+			c.buyer_id,
+			c.seller_id,
+			c.blocked_by,
+			c.blocked_at,
+			c.last_message_at,
+			c.created_at,
+			COALESCE(f.path, ''),
+			COALESCE(m.text, '')
+		FROM conversations AS c
+		JOIN ads AS a ON a.uuid = c.ad_uuid
+		LEFT JOIN LATERAL (
+			SELECT path
+			FROM files
+			WHERE files.ad_uuid = c.ad_uuid
+			ORDER BY created_at ASC
+			LIMIT 1
+		) f ON true
+		LEFT JOIN LATERAL (
+			SELECT "text"
+			FROM conversation_messages
+			WHERE conversation_messages.conversation_id = c.id
+			ORDER BY created_at DESC
+			LIMIT 1
+		) m ON true
+		WHERE
+			c.buyer_id = $1
+			OR c.seller_id = $1
+		ORDER BY
+			COALESCE(c.last_message_at, c.created_at) DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item ConversationsListItemRepository
+		if err := rows.Scan(
+			&item.Id,
+			&item.AdUuid,
+			&item.AdTitle,
+			&item.BuyerId,
+			&item.SellerId,
+			&item.BlockedBy,
+			&item.BlockedAt,
+			&item.LastMessageAt,
+			&item.CreatedAt,
+			&item.AdImage,
+			&item.LastMessage,
+		); err != nil {
+			return result, err
+		}
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+// покупатели, писавшие продавцу по объявлению: из них выбирают покупателя при продаже
+func (r *Repository) FindContactsByAdUuid(ctx context.Context, adUuid uuid.UUID, sellerId int64) ([]ContactRepository, error) {
+	var result []ContactRepository
+
+	query := `
+		SELECT
+			c.id,
+			c.buyer_id,
+			u.first_name,
+			u.last_name,
+			u.photo_url,
+			c.last_message_at
+		FROM conversations AS c
+		JOIN users AS u ON u.id = c.buyer_id
+		WHERE
+			c.ad_uuid = $1
+			AND c.seller_id = $2
+		ORDER BY
+			COALESCE(c.last_message_at, c.created_at) DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, adUuid, sellerId)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item ContactRepository
+		if err := rows.Scan(
+			&item.ConversationId,
+			&item.BuyerId,
+			&item.FirstName,
+			&item.LastName,
+			&item.PhotoUrl,
+			&item.LastMessageAt,
+		); err != nil {
+			return result, err
+		}
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Repository) CreateMessage(ctx context.Context, message MessageModel) (int64, error) {
+	var result int64
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO conversation_messages (conversation_id, sender_id, "text")
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+
+	err = tx.QueryRowContext(ctx, query, message.ConversationId, message.SenderId, message.Text).Scan(&result)
+	if err != nil {
+		return result, err
+	}
+
+	query = `
+		UPDATE conversations
+		SET
+			last_message_at = now(),
+			updated_at = now()
+		WHERE id = $1
+	`
+
+	_, err = tx.ExecContext(ctx, query, message.ConversationId)
+	if err != nil {
+		return result, err
+	}
+
+	return result, tx.Commit()
+}
+
+// последние limit сообщений в хронологическом порядке
+func (r *Repository) FindMessages(ctx context.Context, conversationId int64, limit int) ([]MessageModel, error) {
+	var result []MessageModel
+
+	query := `
+		SELECT id, conversation_id, sender_id, "text", created_at
+		FROM (
+			SELECT id, conversation_id, sender_id, "text", created_at
+			FROM conversation_messages
+			WHERE conversation_id = $1
+			ORDER BY created_at DESC
+			LIMIT $2
+		) t
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, conversationId, limit)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var message MessageModel
+		if err := rows.Scan(
+			&message.Id,
+			&message.ConversationId,
+			&message.SenderId,
+			&message.Text,
+			&message.CreatedAt,
+		); err != nil {
+			return result, err
+		}
+		result = append(result, message)
+	}
+
+	return result, rows.Err()
+}
+
+// blockedBy = nil снимает блокировку
+func (r *Repository) SetBlocked(ctx context.Context, id int64, blockedBy *int64) error {
+	query := `
+		UPDATE conversations
+		SET
+			blocked_by = $1,
+			blocked_at = CASE WHEN $1::int8 IS NULL THEN NULL ELSE now() END,
+			updated_at = now()
+		WHERE id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, blockedBy, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) CreateReport(ctx context.Context, conversationId int64, reporterId int64, reason string) error {
+	query := `
+		INSERT INTO conversation_reports (conversation_id, reporter_id, reason)
+		VALUES ($1, $2, $3)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, conversationId, reporterId, reason)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) GetActiveConversationId(ctx context.Context, userId int64) (*int64, error) {
+	var result *int64
+
+	query := `
+		SELECT active_conversation_id
+		FROM users
+		WHERE id = $1
+	`
+
+	err := r.db.QueryRowContext(ctx, query, userId).Scan(&result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// conversationId = nil — пользователь вышел из диалога
+func (r *Repository) SetActiveConversationId(ctx context.Context, userId int64, conversationId *int64) error {
+	query := `
+		UPDATE users
+		SET
+			active_conversation_id = $1,
+			updated_at = now()
+		WHERE id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, conversationId, userId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
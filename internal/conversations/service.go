@@ -0,0 +1,473 @@
+package conversations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"vietio/internal/ads"
+	"vietio/internal/authctx"
+	appErrors "vietio/internal/errors"
+	"vietio/internal/images"
+	"vietio/internal/telegram"
+	"vietio/internal/user"
+
+	"github.com/google/uuid"
+)
+
+// ограничение Bot API на длину текста сообщения с запасом под заголовок
+const maxMessageLength = 3500
+
+type AdRepository interface {
+	FindAdByUuid(context.Context, uuid.UUID) (ads.AdModel, error)
+}
+
+type UserRepository interface {
+	GetUserById(ctx context.Context, id int64) (user.UserModel, error)
+}
+
+// сообщения собеседнику идут через очередь уведомлений
+type Notifier interface {
+	Notify(ctx context.Context, params telegram.SendMessageParams) error
+}
+
+// подписанные ссылки на варианты фото (images.Signer)
+type ImageVariants interface {
+	GetVariantPath(path string, name string) string
+}
+
+type LinkBuilder interface {
+	Conversation(conversationId int64) string
+}
+
+// Service — анонимная переписка покупателя и продавца через бота.
+// Участники не видят username друг друга: бот пересылает текст от своего имени
+// с заголовком, по какому объявлению пишут
+type Service struct {
+	repo     *Repository
+	adRepo   AdRepository
+	userRepo UserRepository
+	notifier Notifier
+	images   ImageVariants
+	links    LinkBuilder
+}
+
+func NewService(
+	repo *Repository,
+	adRepository AdRepository,
+	userRepository UserRepository,
+	notifier Notifier,
+	imageVariants ImageVariants,
+	links LinkBuilder,
+) *Service {
+	return &Service{
+		repo:     repo,
+		adRepo:   adRepository,
+		userRepo: userRepository,
+		notifier: notifier,
+		images:   imageVariants,
+		links:    links,
+	}
+}
+
+// Start открывает (или находит) диалог текущего пользователя с продавцом
+// и делает его активным, чтобы следующие сообщения боту ушли продавцу
+func (s *Service) Start(ctx context.Context, adUuid uuid.UUID) (StartConversationResponse, error) {
+	var result StartConversationResponse
+
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	ad, err := s.adRepo.FindAdByUuid(ctx, adUuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErrors.ErrAdNotFound
+		}
+		return result, err
+	}
+
+	if ad.Status != ads.STATUS_ACTIVE {
+		return result, appErrors.ErrAdNotActive
+	}
+
+	if ad.UserId == contextUserId {
+		return result, appErrors.ErrConversationSelf
+	}
+
+	id, err := s.repo.FindOrCreate(ctx, ad.Uuid, contextUserId, ad.UserId)
+	if err != nil {
+		return result, err
+	}
+
+	err = s.repo.SetActiveConversationId(ctx, contextUserId, &id)
+	if err != nil {
+		return result, err
+	}
+
+	result.Id = id
+	result.BotUrl = s.links.Conversation(id)
+
+	return result, nil
+}
+
+func (s *Service) GetMyConversations(ctx context.Context) (ConversationsListResponse, error) {
+	var result ConversationsListResponse
+
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	items, err := s.repo.FindByUserId(ctx, contextUserId)
+	if err != nil {
+		return result, err
+	}
+
+	result.Items = make([]ConversationResponse, 0, len(items))
+	for _, item := range items {
+		response := s.toResponse(item.ConversationModel, contextUserId)
+		response.LastMessage = item.LastMessage
+		if item.AdImage != "" {
+			response.AdImage = s.images.GetVariantPath(item.AdImage, images.VARIANT_THUMB)
+		}
+
+		result.Items = append(result.Items, response)
+	}
+	result.Total = len(result.Items)
+
+	return result, nil
+}
+
+// GetAdContacts покупатели, писавшие по объявлению, видны только его владельцу
+func (s *Service) GetAdContacts(ctx context.Context, adUuid uuid.UUID) (ContactsListResponse, error) {
+	var result ContactsListResponse
+
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	ad, err := s.adRepo.FindAdByUuid(ctx, adUuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErrors.ErrAdNotFound
+		}
+		return result, err
+	}
+
+	if ad.UserId != contextUserId {
+		return result, appErrors.ErrForbidden
+	}
+
+	contacts, err := s.repo.FindContactsByAdUuid(ctx, ad.Uuid, contextUserId)
+	if err != nil {
+		return result, err
+	}
+
+	result.Items = make([]ContactResponse, 0, len(contacts))
+	for _, contact := range contacts {
+		buyer := user.UserModel{
+			FirstName: contact.FirstName,
+			LastName:  contact.LastName,
+		}
+
+		result.Items = append(result.Items, ContactResponse{
+			UserId:         contact.BuyerId,
+			DisplayName:    buyer.DisplayName(),
+			PhotoUrl:       contact.PhotoUrl,
+			ConversationId: contact.ConversationId,
+			LastMessageAt:  contact.LastMessageAt,
+		})
+	}
+	result.Total = len(result.Items)
+
+	return result, nil
+}
+
+// GetMessages история диалога, доступна только участникам
+func (s *Service) GetMessages(ctx context.Context, id int64) (MessagesListResponse, error) {
+	var result MessagesListResponse
+
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	conversation, err := s.findForParticipant(ctx, id, contextUserId)
+	if err != nil {
+		return result, err
+	}
+
+	messages, err := s.repo.FindMessages(ctx, conversation.Id, messagesLimit)
+	if err != nil {
+		return result, err
+	}
+
+	result.Conversation = s.toResponse(conversation, contextUserId)
+	result.Items = make([]MessageResponse, 0, len(messages))
+	for _, message := range messages {
+		result.Items = append(result.Items, MessageResponse{
+			Id:        message.Id,
+			IsMine:    message.SenderId == contextUserId,
+			Text:      message.Text,
+			CreatedAt: message.CreatedAt,
+		})
+	}
+
+	if len(result.Items) > 0 {
+		result.Conversation.LastMessage = result.Items[len(result.Items)-1].Text
+	}
+
+	return result, nil
+}
+
+// Block запрещает сообщения в диалоге в обе стороны.
+// Снять блокировку может только тот, кто ее поставил
+func (s *Service) Block(ctx context.Context, id int64) error {
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	conversation, err := s.findForParticipant(ctx, id, contextUserId)
+	if err != nil {
+		return err
+	}
+
+	if conversation.BlockedBy != nil {
+		return nil
+	}
+
+	err = s.repo.SetBlocked(ctx, conversation.Id, &contextUserId)
+	if err != nil {
+		return err
+	}
+
+	// выходим из диалога, чтобы следующие сообщения боту не уходили в заблокированный
+	return s.deactivateIf(ctx, contextUserId, conversation.Id)
+}
+
+func (s *Service) Unblock(ctx context.Context, id int64) error {
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	conversation, err := s.findForParticipant(ctx, id, contextUserId)
+	if err != nil {
+		return err
+	}
+
+	if conversation.BlockedBy == nil {
+		return nil
+	}
+
+	if *conversation.BlockedBy != contextUserId {
+		return appErrors.ErrForbidden
+	}
+
+	return s.repo.SetBlocked(ctx, conversation.Id, nil)
+}
+
+// Report жалоба на собеседника, диалог при этом блокируется
+func (s *Service) Report(ctx context.Context, id int64, reason string) error {
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	conversation, err := s.findForParticipant(ctx, id, contextUserId)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.CreateReport(ctx, conversation.Id, contextUserId, strings.TrimSpace(reason))
+	if err != nil {
+		return err
+	}
+
+	return s.Block(ctx, conversation.Id)
+}
+
+// Activate переключает сообщения пользователя боту на диалог id
+func (s *Service) Activate(ctx context.Context, id int64) (ConversationModel, error) {
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return ConversationModel{}, err
+	}
+
+	conversation, err := s.findForParticipant(ctx, id, contextUserId)
+	if err != nil {
+		return conversation, err
+	}
+
+	if conversation.BlockedBy != nil {
+		return conversation, appErrors.ErrConversationBlocked
+	}
+
+	err = s.repo.SetActiveConversationId(ctx, contextUserId, &conversation.Id)
+	if err != nil {
+		return conversation, err
+	}
+
+	return conversation, nil
+}
+
+// Deactivate выход из активного диалога, возвращает false, если его не было
+func (s *Service) Deactivate(ctx context.Context) (bool, error) {
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	activeId, err := s.repo.GetActiveConversationId(ctx, contextUserId)
+	if err != nil || activeId == nil {
+		return false, err
+	}
+
+	return true, s.repo.SetActiveConversationId(ctx, contextUserId, nil)
+}
+
+// Relay пересылает текст собеседнику в активном диалоге пользователя.
+// Возвращает false, если активного диалога нет и сообщение надо обработать иначе
+func (s *Service) Relay(ctx context.Context, text string) (ConversationModel, bool, error) {
+	var conversation ConversationModel
+
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return conversation, false, err
+	}
+
+	activeId, err := s.repo.GetActiveConversationId(ctx, contextUserId)
+	if err != nil || activeId == nil {
+		return conversation, false, err
+	}
+
+	conversation, err = s.repo.FindById(ctx, *activeId)
+	if err != nil {
+		return conversation, false, err
+	}
+
+	if conversation.BlockedBy != nil {
+		return conversation, true, appErrors.ErrConversationBlocked
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return conversation, true, nil
+	}
+	if runes := []rune(text); len(runes) > maxMessageLength {
+		text = string(runes[:maxMessageLength])
+	}
+
+	_, err = s.repo.CreateMessage(ctx, MessageModel{
+		ConversationId: conversation.Id,
+		SenderId:       contextUserId,
+		Text:           text,
+	})
+	if err != nil {
+		return conversation, true, err
+	}
+
+	recipient, err := s.userRepo.GetUserById(ctx, conversation.Recipient(contextUserId))
+	if err != nil {
+		return conversation, true, err
+	}
+
+	// сообщение сохранено и видно в Mini App, даже если бот у получателя заблокирован
+	if recipient.BotBlockedAt != nil {
+		return conversation, true, nil
+	}
+
+	err = s.notifier.Notify(ctx, telegram.SendMessageParams{
+		ChatId:      recipient.TelegramId,
+		Text:        s.relayText(conversation, contextUserId, text),
+		ParseMode:   telegram.PARSE_MODE_HTML,
+		ReplyMarkup: relayKeyboard(conversation.Id),
+	})
+	if err != nil {
+		return conversation, true, err
+	}
+
+	return conversation, true, nil
+}
+
+func (s *Service) findForParticipant(ctx context.Context, id int64, userId int64) (ConversationModel, error) {
+	conversation, err := s.repo.FindById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return conversation, appErrors.ErrConversationNotFound
+		}
+		return conversation, err
+	}
+
+	if !conversation.HasParticipant(userId) {
+		return conversation, appErrors.ErrForbidden
+	}
+
+	return conversation, nil
+}
+
+func (s *Service) deactivateIf(ctx context.Context, userId int64, conversationId int64) error {
+	activeId, err := s.repo.GetActiveConversationId(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if activeId == nil || *activeId != conversationId {
+		return nil
+	}
+
+	return s.repo.SetActiveConversationId(ctx, userId, nil)
+}
+
+func (s *Service) toResponse(conversation ConversationModel, userId int64) ConversationResponse {
+	return ConversationResponse{
+		Id:            conversation.Id,
+		AdUuid:        conversation.AdUuid,
+		AdTitle:       conversation.AdTitle,
+		Role:          conversation.Role(userId),
+		IsBlocked:     conversation.BlockedBy != nil,
+		BlockedByMe:   conversation.BlockedBy != nil && *conversation.BlockedBy == userId,
+		LastMessageAt: conversation.LastMessageAt,
+		BotUrl:        s.links.Conversation(conversation.Id),
+	}
+}
+
+// заголовок с ролью отправителя и объявлением, сам текст экранируем
+func (s *Service) relayText(conversation ConversationModel, senderId int64, text string) string {
+	sender := "Покупатель"
+	if conversation.Role(senderId) == ROLE_SELLER {
+		sender = "Продавец"
+	}
+
+	return fmt.Sprintf(
+		"💬 <b>%s</b> по «%s»:\n\n%s",
+		sender,
+		html.EscapeString(conversation.AdTitle),
+		html.EscapeString(text),
+	)
+}
+
+// кнопки под пересланным сообщением: conv:reply:<id>, conv:block:<id>, conv:report:<id>
+func relayKeyboard(conversationId int64) *telegram.InlineKeyboardMarkup {
+	id := strconv.FormatInt(conversationId, 10)
+
+	return &telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{
+			{
+				{Text: "Ответить", CallbackData: telegram.CallbackData(CALLBACK_PREFIX, "reply", id)},
+			},
+			{
+				{Text: "Заблокировать", CallbackData: telegram.CallbackData(CALLBACK_PREFIX, "block", id)},
+				{Text: "Пожаловаться", CallbackData: telegram.CallbackData(CALLBACK_PREFIX, "report", id)},
+			},
+		},
+	}
+}
@@ -25,8 +25,8 @@ func runAdsSeed(dbConn *sql.DB) error {
     query := `
 		INSERT INTO ads (
 			user_id, category_id, city_id, title, description, price, currency, 
-			district, status, expires_at, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			district, status, expires_at, created_at, updated_at, bumped_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 	stmt, err := dbConn.Prepare(query)
 	if err != nil {
@@ -61,6 +61,7 @@ func runAdsSeed(dbConn *sql.DB) error {
 			expiresAt,
 			createdAt,
 			updatedAt,
+			createdAt,
 		)
 		if err != nil {
 			return err
@@ -0,0 +1,96 @@
+package deeplink
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// префиксы startapp-параметра
+const AD_PREFIX = "ad_"
+const USER_PREFIX = "user_"
+
+// префикс /start-параметра для перехода в диалог с продавцом
+const CONVERSATION_PREFIX = "conv_"
+
+// типы экранов, на которые ведет ссылка
+const TARGET_AD = "ad"
+const TARGET_USER = "user"
+
+// Target экран Mini App, разобранный из start_param
+type Target struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+}
+
+// Builder собирает ссылки, открывающие Mini App на нужном экране
+type Builder struct {
+	botUsername string
+	appName     string
+}
+
+func NewBuilder(botUsername string, appName string) *Builder {
+	return &Builder{
+		botUsername: botUsername,
+		appName:     appName,
+	}
+}
+
+// t.me/<bot>/<app>?startapp=ad_<uuid>, пустая строка, если бот не настроен
+func (b *Builder) Ad(uuid string) string {
+	return b.startApp(AD_PREFIX + uuid)
+}
+
+// t.me/<bot>/<app>?startapp=user_<id>
+func (b *Builder) User(userId int64) string {
+	return b.startApp(USER_PREFIX + strconv.FormatInt(userId, 10))
+}
+
+// t.me/<bot>?start=conv_<id>: открывает чат с ботом и переключает его на диалог
+func (b *Builder) Conversation(conversationId int64) string {
+	if b.botUsername == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"https://t.me/%s?start=%s%d",
+		b.botUsername,
+		CONVERSATION_PREFIX,
+		conversationId,
+	)
+}
+
+func (b *Builder) startApp(param string) string {
+	if b.botUsername == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"https://t.me/%s/%s?startapp=%s",
+		b.botUsername,
+		b.appName,
+		url.QueryEscape(param),
+	)
+}
+
+// Parse разбирает start_param: ad_<uuid> или user_<id>
+func Parse(param string) (Target, bool) {
+	if value, ok := strings.CutPrefix(param, AD_PREFIX); ok {
+		if _, err := uuid.Parse(value); err != nil {
+			return Target{}, false
+		}
+		return Target{Type: TARGET_AD, Id: value}, true
+	}
+
+	if value, ok := strings.CutPrefix(param, USER_PREFIX); ok {
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return Target{}, false
+		}
+		return Target{Type: TARGET_USER, Id: value}, true
+	}
+
+	return Target{}, false
+}
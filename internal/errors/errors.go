@@ -3,6 +3,7 @@ package errors
 import (
 	"errors"
 	"strings"
+	"time"
 )
 
 var ErrAdsList = errors.New("ads list error")
@@ -11,10 +12,14 @@ var ErrCreateAd = errors.New("ad create error")
 var ErrUpdateAd = errors.New("ad update error")
 var ErrDeleteAd = errors.New("ad delete error")
 var ErrSoldAd = errors.New("ad sold error")
+var ErrDuplicateAd = errors.New("ad duplicate found")
+var ErrBumpAd = errors.New("ad bump error")
+var ErrPromoteAd = errors.New("ad promote error")
 var ErrCreateAdValidation = errors.New("ad create error validation")
 var ErrUpdateAdValidation = errors.New("ad update error validation")
 var ErrForbidden = errors.New("forbidden")
 var ErrNotValidUuid = errors.New("not valid uuid")
+var ErrNotValidId = errors.New("not valid id")
 var ErrMyAdsList = errors.New("me ads list error")
 var ErrMySoldAdsList = errors.New("my sold ads list error")
 var ErrMyFavoritesAdsList = errors.New("my favorites ads list error")
@@ -23,8 +28,74 @@ var ErrDeleteWithList = errors.New("delete wishlist error")
 
 var ErrAdNotFound = errors.New("ad not found")
 var ErrAdNotActive = errors.New("ad not active")
+var ErrAdNotEditable = errors.New("closed ad cannot be edited")
 var ErrAdUserNotFound = errors.New("ad user not found")
 var ErrAdFavorite = errors.New("ad error found")
+var ErrInvalidStatusTransition = errors.New("invalid ad status transition")
+var ErrReserveAd = errors.New("ad reserve error")
+var ErrRestoreAd = errors.New("ad restore error")
+var ErrAdRestoreExpired = errors.New("ad retention period expired")
+var ErrAdRevisions = errors.New("ad revisions error")
+var ErrModeration = errors.New("moderation error")
+var ErrAdVersionMismatch = errors.New("ad was modified")
+var ErrAdConflict = errors.New("ad is being modified concurrently, try again")
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with different payload")
+var ErrIdempotencyInProgress = errors.New("request with this idempotency key is in progress")
+
+var ErrUpload = errors.New("upload error")
+var ErrUploadImage = errors.New("image could not be processed")
+var ErrUploadUnsupported = errors.New("direct upload is not supported by storage")
+var ErrUploadNotFound = errors.New("upload not found")
+var ErrUploadNotReceived = errors.New("upload not received")
+var ErrUploadSignature = errors.New("invalid upload signature")
+var ErrUploadExpired = errors.New("upload url expired")
+var ErrUploadMismatch = errors.New("upload does not match signed constraints")
+
+var ErrImage = errors.New("image error")
+var ErrImageNotFound = errors.New("image not found")
+var ErrImageSignature = errors.New("invalid image signature")
+var ErrImageBusy = errors.New("image resize is busy")
+
+var ErrPaymentNotFound = errors.New("payment not found")
+var ErrPaymentInvalid = errors.New("payment invalid")
+
+var ErrConversation = errors.New("conversation error")
+var ErrConversationsList = errors.New("conversations list error")
+var ErrConversationNotFound = errors.New("conversation not found")
+var ErrConversationBlocked = errors.New("conversation blocked")
+var ErrConversationSelf = errors.New("conversation with yourself")
+
+var ErrProfile = errors.New("profile error")
+var ErrUserNotFound = errors.New("user not found")
+
+var ErrReview = errors.New("review error")
+var ErrReviewsList = errors.New("reviews list error")
+var ErrDealsList = errors.New("deals list error")
+var ErrDealNotFound = errors.New("deal not found")
+var ErrDealExists = errors.New("ad already sold to another buyer")
+var ErrReviewExists = errors.New("review already exists")
+var ErrBuyerNotContact = errors.New("buyer is not a contact")
+
+// найдено похожее объявление, Action — что предлагаем сделать клиенту
+type DuplicateAdError struct {
+	Message string `json:"error"`
+	Uuid    string `json:"duplicate_uuid"`
+	Action  string `json:"action"`
+}
+
+func (e *DuplicateAdError) Error() string {
+	return e.Message
+}
+
+// поднимать объявление еще рано
+type BumpNotAllowedError struct {
+	Message     string    `json:"error"`
+	AvailableAt time.Time `json:"available_at"`
+}
+
+func (e *BumpNotAllowedError) Error() string {
+	return e.Message
+}
 
 type ValidationError struct {
 	Errors []ValidationErrorItem `json:"errors"`
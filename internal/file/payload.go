@@ -1,10 +1,16 @@
 package file
 
-import "github.com/google/uuid"
+import (
+	"time"
 
+	"github.com/google/uuid"
+)
+
+// UserId — владелец загруженного заранее фото, пока оно не прикреплено к объявлению
 type FileModel struct {
 	Id          int64
 	AdUuid      uuid.UUID
+	UserId      *int64
 	Path        string
 	PreviewPath string
 	Size        int64
@@ -12,4 +18,5 @@ type FileModel struct {
 	Mime        string
 	PreviewMime string
 	Storage     string
+	CreatedAt   time.Time
 }
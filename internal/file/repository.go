@@ -3,6 +3,7 @@ package file
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -102,3 +103,122 @@ func (r *FileRepository) DeleteById(ctx context.Context, tx *sql.Tx, id int64) e
 
 	return nil
 }
+
+// SaveStaged сохраняет фото без объявления, возвращает id для file_ids
+func (r *FileRepository) SaveStaged(ctx context.Context, fileModel FileModel) (int64, error) {
+	var id int64
+
+	query := `
+		INSERT INTO files (
+			user_id,
+			path,
+			preview_path,
+			size,
+			preview_size,
+			mime,
+			preview_mime,
+			storage
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		fileModel.UserId,
+		fileModel.Path,
+		fileModel.PreviewPath,
+		fileModel.Size,
+		fileModel.PreviewSize,
+		fileModel.Mime,
+		fileModel.PreviewMime,
+		fileModel.Storage,
+	).Scan(&id)
+
+	return id, err
+}
+
+// AttachStagedWithTx прикрепляет загруженные пользователем фото к объявлению,
+// возвращает количество прикрепленных: чужие и уже прикрепленные пропускаются
+func (r *FileRepository) AttachStagedWithTx(ctx context.Context, tx *sql.Tx, userId int64, adUuid uuid.UUID, ids []int64) (int, error) {
+	query := `
+		UPDATE files
+		SET ad_uuid = $1
+		WHERE
+			id = ANY($2)
+			AND user_id = $3
+			AND ad_uuid IS NULL
+	`
+
+	res, err := tx.ExecContext(ctx, query, adUuid, ids, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return int(affected), err
+}
+
+// FindStagedBefore фото, которые так и не прикрепили к объявлению
+func (r *FileRepository) FindStagedBefore(ctx context.Context, before time.Time) ([]FileModel, error) {
+	var result []FileModel
+
+	query := `
+		SELECT
+			id,
+			path,
+			preview_path,
+			created_at
+		FROM
+			files
+		WHERE
+			ad_uuid IS NULL
+			AND user_id IS NOT NULL
+			AND created_at < $1
+		ORDER BY
+			id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, before)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var file FileModel
+
+		if err := rows.Scan(
+			&file.Id,
+			&file.Path,
+			&file.PreviewPath,
+			&file.CreatedAt,
+		); err != nil {
+			return result, err
+		}
+
+		result = append(result, file)
+	}
+
+	return result, rows.Err()
+}
+
+// DeleteStaged удаляет запись, только если фото так и не прикрепили
+func (r *FileRepository) DeleteStaged(ctx context.Context, id int64) (bool, error) {
+	query := `
+		DELETE FROM files
+		WHERE
+			id = $1
+			AND ad_uuid IS NULL
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
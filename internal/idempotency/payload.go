@@ -0,0 +1,24 @@
+package idempotency
+
+import "time"
+
+// сколько хранится ответ на запрос с Idempotency-Key
+const TTL = 24 * time.Hour
+
+// запрос без ответа дольше этого считаем оборвавшимся, ключ можно занять заново
+const pendingTimeout = 5 * time.Minute
+
+const MAX_KEY_LENGTH = 255
+
+type KeyModel struct {
+	UserId      int64
+	Key         string
+	RequestHash string
+	// nil — запрос с этим ключом еще выполняется
+	Response  []byte
+	CreatedAt time.Time
+}
+
+func (k KeyModel) IsPending() bool {
+	return k.Response == nil
+}
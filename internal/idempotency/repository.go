@@ -0,0 +1,137 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// Begin занимает ключ за запросом. true — ключ свободен и занят этим запросом,
+// false — возвращается сохраненная запись: готовый ответ или выполняющийся запрос.
+// Истекшие и оборвавшиеся записи перезаписываются
+func (r *Repository) Begin(ctx context.Context, userId int64, key string, requestHash string) (KeyModel, bool, error) {
+	result := KeyModel{
+		UserId:      userId,
+		Key:         key,
+		RequestHash: requestHash,
+	}
+
+	query := `
+		INSERT INTO idempotency_keys (user_id, "key", request_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, "key") DO UPDATE
+		SET
+			request_hash = EXCLUDED.request_hash,
+			response = NULL,
+			created_at = now()
+		WHERE
+			idempotency_keys.created_at < $4
+			OR (idempotency_keys.response IS NULL AND idempotency_keys.created_at < $5)
+		RETURNING created_at
+	`
+
+	now := time.Now()
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		userId,
+		key,
+		requestHash,
+		now.Add(-TTL),
+		now.Add(-pendingTimeout),
+	).Scan(&result.CreatedAt)
+	if err == nil {
+		return result, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return result, false, err
+	}
+
+	result, err = r.find(ctx, userId, key)
+
+	return result, false, err
+}
+
+// CompleteWithTx сохраняет ответ в транзакции основного запроса
+func (r *Repository) CompleteWithTx(ctx context.Context, tx *sql.Tx, userId int64, key string, response []byte) error {
+	query := `
+		UPDATE idempotency_keys
+		SET response = $1
+		WHERE
+			user_id = $2
+			AND "key" = $3
+	`
+
+	_, err := tx.ExecContext(ctx, query, response, userId, key)
+
+	return err
+}
+
+// Release освобождает ключ после ошибки, чтобы повтор выполнился заново
+func (r *Repository) Release(ctx context.Context, userId int64, key string) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE
+			user_id = $1
+			AND "key" = $2
+			AND response IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userId, key)
+
+	return err
+}
+
+func (r *Repository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE created_at < $1
+	`
+
+	res, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (r *Repository) find(ctx context.Context, userId int64, key string) (KeyModel, error) {
+	var result KeyModel
+
+	query := `
+		SELECT
+			user_id,
+			"key",
+			request_hash,
+			response,
+			created_at
+		FROM
+			idempotency_keys
+		WHERE
+			user_id = $1
+			AND "key" = $2
+	`
+
+	err := r.db.QueryRowContext(ctx, query, userId, key).Scan(
+		&result.UserId,
+		&result.Key,
+		&result.RequestHash,
+		&result.Response,
+		&result.CreatedAt,
+	)
+
+	return result, err
+}
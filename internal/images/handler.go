@@ -0,0 +1,59 @@
+package images
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	appErrors "vietio/internal/errors"
+)
+
+type Handler struct {
+	service *Service
+	signer  *Signer
+	logger  *slog.Logger
+}
+
+func NewHandler(service *Service, signer *Signer, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		signer:  signer,
+		logger:  logger,
+	}
+}
+
+// GetImage вариант фото по подписанной ссылке /img/{path}?w=&h=&fit=&fmt=&s=
+func (h *Handler) GetImage(w http.ResponseWriter, r *http.Request) {
+	path := r.PathValue("path")
+
+	variant, err := h.signer.Verify(path, r.URL.Query())
+	if err != nil {
+		h.logger.Info(appErrors.ErrImageSignature.Error(), "err", err, "path", path)
+		http.Error(w, appErrors.ErrImageSignature.Error(), http.StatusForbidden)
+		return
+	}
+
+	data, contentType, err := h.service.Get(r.Context(), path, variant)
+	if err != nil {
+		switch {
+		case errors.Is(err, appErrors.ErrImageNotFound):
+			h.logger.Info(appErrors.ErrImageNotFound.Error(), "path", path)
+			http.Error(w, appErrors.ErrImageNotFound.Error(), http.StatusNotFound)
+		case errors.Is(err, appErrors.ErrImageBusy):
+			h.logger.Warn(appErrors.ErrImageBusy.Error(), "path", path)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, appErrors.ErrImageBusy.Error(), http.StatusServiceUnavailable)
+		default:
+			h.logger.Error(appErrors.ErrImage.Error(), "err", err, "path", path)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", CACHE_CONTROL)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,135 @@
+package images
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	FIT_FIT  = "fit"
+	FIT_FILL = "fill"
+
+	FORMAT_JPG = "jpg"
+	FORMAT_PNG = "png"
+
+	// мастер хранится не больше 1200px, больший размер не имеет смысла
+	MAX_DIMENSION = 2000
+
+	// ссылка на вариант подписана и не меняется, поэтому кешируется навсегда
+	CACHE_CONTROL = "public, max-age=31536000, immutable"
+)
+
+// варианты, которые сервер выдает сам: превью в переписках, inline-поиск,
+// посты в каналах и og:image. В IMAGE_VARIANTS они обязательны
+const (
+	VARIANT_THUMB = "thumb"
+	VARIANT_CARD  = "card"
+	VARIANT_FULL  = "full"
+)
+
+var requiredVariants = []string{VARIANT_THUMB, VARIANT_CARD, VARIANT_FULL}
+
+var formatMimes = map[string]string{
+	FORMAT_JPG: "image/jpeg",
+	FORMAT_PNG: "image/png",
+}
+
+// Variant параметры ресайза.
+// fit — вписать в WxH без обрезки (0 — без ограничения по стороне),
+// fill — заполнить WxH с обрезкой по центру
+type Variant struct {
+	Width  int
+	Height int
+	Fit    string
+	Format string
+}
+
+func (v Variant) Validate() error {
+	if v.Width < 0 || v.Width > MAX_DIMENSION || v.Height < 0 || v.Height > MAX_DIMENSION {
+		return fmt.Errorf("size must be from 0 to %d", MAX_DIMENSION)
+	}
+
+	switch v.Fit {
+	case FIT_FIT:
+		if v.Width == 0 && v.Height == 0 {
+			return fmt.Errorf("width or height is required")
+		}
+	case FIT_FILL:
+		if v.Width == 0 || v.Height == 0 {
+			return fmt.Errorf("fill requires width and height")
+		}
+	default:
+		return fmt.Errorf("unknown fit: %s", v.Fit)
+	}
+
+	if _, ok := formatMimes[v.Format]; !ok {
+		return fmt.Errorf("unknown format: %s", v.Format)
+	}
+
+	return nil
+}
+
+// ключ закешированного варианта, все варианты фото лежат под его путем
+func (v Variant) cacheKey(path string) string {
+	return fmt.Sprintf("%s/%dx%d_%s.%s", path, v.Width, v.Height, v.Fit, v.Format)
+}
+
+// ParseVariants разбирает IMAGE_VARIANTS: thumb=300x300:fill,card=600x600:fit:png
+func ParseVariants(value string) (map[string]Variant, error) {
+	result := make(map[string]Variant)
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, spec, ok := strings.Cut(item, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid image variant: %s", item)
+		}
+
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid image variant: %s", item)
+		}
+
+		width, height, ok := strings.Cut(parts[0], "x")
+		if !ok {
+			return nil, fmt.Errorf("invalid image variant size: %s", item)
+		}
+
+		variant := Variant{
+			Fit:    parts[1],
+			Format: FORMAT_JPG,
+		}
+		if len(parts) == 3 {
+			variant.Format = parts[2]
+		}
+
+		var err error
+		variant.Width, err = strconv.Atoi(width)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image variant width: %s", item)
+		}
+		variant.Height, err = strconv.Atoi(height)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image variant height: %s", item)
+		}
+
+		if err := variant.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid image variant %s: %w", name, err)
+		}
+
+		result[name] = variant
+	}
+
+	for _, name := range requiredVariants {
+		if _, ok := result[name]; !ok {
+			return nil, fmt.Errorf("image variant %s is required", name)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,144 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	appErrors "vietio/internal/errors"
+
+	"github.com/disintegration/imaging"
+)
+
+// сколько запрос ждет свободного слота ресайза
+const acquireTimeout = 5 * time.Second
+
+// Storage мастер-фото и кеш вариантов
+type Storage interface {
+	OpenMaster(ctx context.Context, path string) (io.ReadCloser, error)
+	// ErrImageNotFound, если варианта еще нет в кеше
+	GetVariant(ctx context.Context, key string) ([]byte, error)
+	SaveVariant(ctx context.Context, key string, data []byte, contentType string) error
+}
+
+// Service — ресайз сохраненных фото под размер клиента.
+// Готовые варианты кешируются в хранилище, число одновременных ресайзов ограничено
+type Service struct {
+	storage Storage
+	slots   chan struct{}
+}
+
+func NewService(storage Storage, concurrency int) *Service {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &Service{
+		storage: storage,
+		slots:   make(chan struct{}, concurrency),
+	}
+}
+
+// Get вариант фото из кеша или ресайз мастера
+func (s *Service) Get(ctx context.Context, path string, variant Variant) ([]byte, string, error) {
+	key := variant.cacheKey(path)
+	contentType := formatMimes[variant.Format]
+
+	data, err := s.storage.GetVariant(ctx, key)
+	if err == nil {
+		return data, contentType, nil
+	}
+	if !errors.Is(err, appErrors.ErrImageNotFound) {
+		return nil, "", err
+	}
+
+	err = s.acquire(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer s.release()
+
+	// пока ждали слот, тот же вариант мог сделать другой запрос
+	data, err = s.storage.GetVariant(ctx, key)
+	if err == nil {
+		return data, contentType, nil
+	}
+	if !errors.Is(err, appErrors.ErrImageNotFound) {
+		return nil, "", err
+	}
+
+	data, err = s.resize(ctx, path, variant)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = s.storage.SaveVariant(ctx, key, data, contentType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, contentType, nil
+}
+
+func (s *Service) resize(ctx context.Context, path string, variant Variant) ([]byte, error) {
+	master, err := s.storage.OpenMaster(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer master.Close()
+
+	// мастер всегда jpg, сохраненный при загрузке
+	img, err := imaging.Decode(master)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode master: %w", err)
+	}
+
+	switch variant.Fit {
+	case FIT_FILL:
+		img = imaging.Fill(img, variant.Width, variant.Height, imaging.Center, imaging.Lanczos)
+	default:
+		// fit не увеличивает фото, 0 — сторона не ограничена
+		width, height := variant.Width, variant.Height
+		if width == 0 {
+			width = img.Bounds().Dx()
+		}
+		if height == 0 {
+			height = img.Bounds().Dy()
+		}
+		img = imaging.Fit(img, width, height, imaging.Lanczos)
+	}
+
+	buf := new(bytes.Buffer)
+	switch variant.Format {
+	case FORMAT_PNG:
+		err = imaging.Encode(buf, img, imaging.PNG)
+	default:
+		err = imaging.Encode(buf, img, imaging.JPEG, imaging.JPEGQuality(85))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode variant: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *Service) acquire(ctx context.Context) error {
+	timer := time.NewTimer(acquireTimeout)
+	defer timer.Stop()
+
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return appErrors.ErrImageBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Service) release() {
+	<-s.slots
+}
@@ -0,0 +1,98 @@
+package images
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	appErrors "vietio/internal/errors"
+)
+
+// Signer подписывает параметры ресайза, чтобы /img отдавал только выданные сервером варианты
+type Signer struct {
+	publicUrl string
+	secret    []byte
+	variants  map[string]Variant
+}
+
+func NewSigner(publicUrl, secret string, variants map[string]Variant) *Signer {
+	return &Signer{
+		publicUrl: publicUrl,
+		secret:    []byte(secret),
+		variants:  variants,
+	}
+}
+
+// GetPublicPath подписанная ссылка на вариант фото
+func (s *Signer) GetPublicPath(path string, variant Variant) string {
+	query := url.Values{}
+	query.Set("w", strconv.Itoa(variant.Width))
+	query.Set("h", strconv.Itoa(variant.Height))
+	query.Set("fit", variant.Fit)
+	query.Set("fmt", variant.Format)
+	query.Set("s", s.sign(path, variant))
+
+	return s.publicUrl + "/img/" + url.PathEscape(path) + "?" + query.Encode()
+}
+
+// GetVariantPath подписанная ссылка на вариант по имени, пустая строка, если фото нет
+func (s *Signer) GetVariantPath(path string, name string) string {
+	variant, ok := s.variants[name]
+	if path == "" || !ok {
+		return ""
+	}
+
+	return s.GetPublicPath(path, variant)
+}
+
+// GetVariantPaths ссылки на все варианты из IMAGE_VARIANTS по имени
+func (s *Signer) GetVariantPaths(path string) map[string]string {
+	if path == "" {
+		return nil
+	}
+
+	result := make(map[string]string, len(s.variants))
+	for name, variant := range s.variants {
+		result[name] = s.GetPublicPath(path, variant)
+	}
+
+	return result
+}
+
+// Verify проверяет подпись и возвращает параметры ресайза
+func (s *Signer) Verify(path string, query url.Values) (Variant, error) {
+	var variant Variant
+	var err error
+
+	variant.Width, err = strconv.Atoi(query.Get("w"))
+	if err != nil {
+		return variant, appErrors.ErrImageSignature
+	}
+	variant.Height, err = strconv.Atoi(query.Get("h"))
+	if err != nil {
+		return variant, appErrors.ErrImageSignature
+	}
+	variant.Fit = query.Get("fit")
+	variant.Format = query.Get("fmt")
+
+	if !hmac.Equal([]byte(s.sign(path, variant)), []byte(query.Get("s"))) {
+		return variant, appErrors.ErrImageSignature
+	}
+
+	// GetPublicPath подписывает любые параметры, ограничения проверяем здесь
+	if err := variant.Validate(); err != nil {
+		return variant, fmt.Errorf("%w: %w", appErrors.ErrImageSignature, err)
+	}
+
+	return variant, nil
+}
+
+func (s *Signer) sign(path string, variant Variant) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%d\n%d\n%s\n%s", path, variant.Width, variant.Height, variant.Fit, variant.Format)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
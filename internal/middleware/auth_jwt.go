@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -9,26 +10,38 @@ import (
 	"vietio/internal/authctx"
 )
 
+var errMissingAuthHeader = errors.New("missing authorization header")
+var errInvalidAuthHeader = errors.New("invalid authorization header")
+var errInvalidToken = errors.New("invalid token")
+
 func AuthJWT(authService *auth.Service) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				http.Error(w, "missing authorization header", http.StatusUnauthorized)
+			claims, err := parseBearer(authService, r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
 				return
 			}
 
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				http.Error(w, "invalid authorization header", http.StatusUnauthorized)
-				return
-			}
+			ctx := context.WithValue(r.Context(), authctx.UserIdKey, claims.UserId)
 
-			token := parts[1]
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-			claims, err := authService.ParseAndValidateJWT(token)
+// OptionalAuthJWT пропускает запросы без токена (ссылки, открытые вне Telegram),
+// но отклоняет запросы с невалидным токеном
+func OptionalAuthJWT(authService *auth.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := parseBearer(authService, r)
+			if errors.Is(err, errMissingAuthHeader) {
+				next.ServeHTTP(w, r)
+				return
+			}
 			if err != nil {
-				http.Error(w, "invalid token", http.StatusUnauthorized)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
 				return
 			}
 
@@ -38,3 +51,22 @@ func AuthJWT(authService *auth.Service) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+func parseBearer(authService *auth.Service, r *http.Request) (*auth.AccessTokenClaims, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, errMissingAuthHeader
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, errInvalidAuthHeader
+	}
+
+	claims, err := authService.ParseAndValidateJWT(parts[1])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,32 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// статусы уведомления
+const STATUS_PENDING = "pending"
+const STATUS_SENT = "sent"
+const STATUS_DEAD = "dead"
+
+// виды уведомлений, для каждого регистрируется HandlerFunc
+const KIND_MESSAGE = "message"
+
+// сообщение по инициативе сервиса (новое сообщение в переписке, приглашение оставить отзыв),
+// не отправляется, если пользователь выключил уведомления через /settings
+const KIND_NOTIFICATION = "notification"
+
+type NotificationModel struct {
+	Id       int64
+	Kind     string
+	ChatId   int64
+	Payload  json.RawMessage
+	Status   string
+	Attempts int
+	RunAt    time.Time
+}
+
+// отправка одного уведомления. Ошибка — повтор с backoff или dead-letter
+type HandlerFunc func(ctx context.Context, notification NotificationModel) error
@@ -0,0 +1,167 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) Create(ctx context.Context, notification NotificationModel) (int64, error) {
+	var result int64
+
+	query := `
+		INSERT INTO notifications (kind, chat_id, payload, run_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		notification.Kind,
+		notification.ChatId,
+		notification.Payload,
+		notification.RunAt,
+	).Scan(&result)
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// ClaimDue забирает готовые к отправке уведомления и сдвигает их run_at на lease:
+// пока воркер шлет, другие их не видят, а если процесс упал — уведомление вернется в очередь
+func (r *Repository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]NotificationModel, error) {
+	var result []NotificationModel
+
+	query := `
+		UPDATE notifications
+		SET
+			run_at = now() + make_interval(secs => $1),
+			attempts = attempts + 1,
+			updated_at = now()
+		WHERE id IN (
+			SELECT id
+			FROM notifications
+			WHERE
+				status = $2
+				AND run_at <= now()
+			ORDER BY run_at ASC
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, kind, chat_id, payload, status, attempts, run_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, lease.Seconds(), STATUS_PENDING, limit)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var notification NotificationModel
+		if err := rows.Scan(
+			&notification.Id,
+			&notification.Kind,
+			&notification.ChatId,
+			&notification.Payload,
+			&notification.Status,
+			&notification.Attempts,
+			&notification.RunAt,
+		); err != nil {
+			return result, err
+		}
+		result = append(result, notification)
+	}
+
+	return result, rows.Err()
+}
+
+func (r *Repository) MarkSent(ctx context.Context, id int64) error {
+	query := `
+		UPDATE notifications
+		SET
+			status = $1,
+			sent_at = now(),
+			last_error = NULL,
+			updated_at = now()
+		WHERE
+			id = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, STATUS_SENT, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MarkRetry возвращает уведомление в очередь на runAt
+func (r *Repository) MarkRetry(ctx context.Context, id int64, runAt time.Time, lastError string) error {
+	query := `
+		UPDATE notifications
+		SET
+			run_at = $1,
+			last_error = $2,
+			updated_at = now()
+		WHERE
+			id = $3
+	`
+
+	_, err := r.db.ExecContext(ctx, query, runAt, lastError, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) MarkDead(ctx context.Context, id int64, lastError string) error {
+	query := `
+		UPDATE notifications
+		SET
+			status = $1,
+			last_error = $2,
+			updated_at = now()
+		WHERE
+			id = $3
+	`
+
+	_, err := r.db.ExecContext(ctx, query, STATUS_DEAD, lastError, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// отправленные уведомления храним недолго, dead остаются для разбора
+func (r *Repository) DeleteSentBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM notifications
+		WHERE
+			status = $1
+			AND sent_at < $2
+	`
+
+	res, err := r.db.ExecContext(ctx, query, STATUS_SENT, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
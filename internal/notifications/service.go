@@ -0,0 +1,254 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"vietio/internal/telegram"
+	"vietio/internal/user"
+)
+
+// после стольких неудачных попыток уведомление уходит в dead
+const maxAttempts = 8
+
+// первая задержка перед повтором, дальше удваивается
+const retryBaseDelay = 10 * time.Second
+const retryMaxDelay = time.Hour
+
+// как часто воркер проверяет очередь, если его не разбудили
+const pollInterval = 2 * time.Second
+
+// сколько уведомлений воркер забирает за раз и на сколько их резервирует
+const claimLimit = 10
+const claimLease = 5 * time.Minute
+
+type UserRepository interface {
+	GetUserByTelegramId(ctx context.Context, telegramId int64) (user.UserModel, error)
+	SetBotBlocked(ctx context.Context, telegramId int64, blocked bool) error
+}
+
+// Service — очередь исходящих сообщений в Telegram поверх таблицы notifications.
+// Все, что пишет пользователям, ставит уведомление через Enqueue, отправляют воркеры из Run
+type Service struct {
+	repo     *Repository
+	client   *telegram.Client
+	users    UserRepository
+	logger   *slog.Logger
+	handlers map[string]HandlerFunc
+	wake     chan struct{}
+}
+
+func NewService(repo *Repository, client *telegram.Client, users UserRepository, logger *slog.Logger) *Service {
+	s := &Service{
+		repo:     repo,
+		client:   client,
+		users:    users,
+		logger:   logger,
+		handlers: make(map[string]HandlerFunc),
+		wake:     make(chan struct{}, 1),
+	}
+
+	s.Handle(KIND_MESSAGE, s.sendMessage)
+	s.Handle(KIND_NOTIFICATION, s.sendNotification)
+
+	return s
+}
+
+// Handle регистрирует отправку для вида уведомлений, вызывать до Run
+func (s *Service) Handle(kind string, handler HandlerFunc) {
+	s.handlers[kind] = handler
+}
+
+// Enqueue ставит уведомление в очередь, payload сохраняется как json
+func (s *Service) Enqueue(ctx context.Context, kind string, chatId int64, payload any) error {
+	return s.EnqueueAt(ctx, kind, chatId, payload, time.Now())
+}
+
+func (s *Service) EnqueueAt(ctx context.Context, kind string, chatId int64, payload any, runAt time.Time) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.repo.Create(ctx, NotificationModel{
+		Kind:    kind,
+		ChatId:  chatId,
+		Payload: data,
+		RunAt:   runAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	// будим воркер, чтобы не ждать pollInterval
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (s *Service) SendMessage(ctx context.Context, params telegram.SendMessageParams) error {
+	return s.Enqueue(ctx, KIND_MESSAGE, params.ChatId, params)
+}
+
+// Notify ставит сообщение, которое пользователь не запрашивал.
+// Настройка уведомлений проверяется при отправке, поэтому уже поставленные тоже не уйдут
+func (s *Service) Notify(ctx context.Context, params telegram.SendMessageParams) error {
+	return s.Enqueue(ctx, KIND_NOTIFICATION, params.ChatId, params)
+}
+
+// Run запускает воркеры и ждет их завершения после отмены ctx.
+// Уже взятые уведомления дообрабатываются
+func (s *Service) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (s *Service) work(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		notifications, err := s.repo.ClaimDue(ctx, claimLimit, claimLease)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Error("ошибка при получении уведомлений", "err", err)
+		}
+
+		for _, notification := range notifications {
+			s.process(context.WithoutCancel(ctx), notification)
+		}
+
+		// взяли полную пачку — скорее всего есть еще
+		if len(notifications) == claimLimit && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) process(ctx context.Context, notification NotificationModel) {
+	handler, ok := s.handlers[notification.Kind]
+	if !ok {
+		s.dead(ctx, notification, fmt.Errorf("unknown notification kind: %s", notification.Kind))
+		return
+	}
+
+	err := handler(ctx, notification)
+	if err == nil {
+		if err := s.repo.MarkSent(ctx, notification.Id); err != nil {
+			s.logger.Error("ошибка при сохранении статуса уведомления", "err", err, "id", notification.Id)
+		}
+		return
+	}
+
+	// пользователь заблокировал бота: помечаем недоступным и больше не пытаемся
+	if telegram.IsForbidden(err) {
+		if notification.ChatId > 0 {
+			if err := s.users.SetBotBlocked(ctx, notification.ChatId, true); err != nil {
+				s.logger.Error("ошибка при пометке пользователя недоступным", "err", err, "chat_id", notification.ChatId)
+			}
+		}
+		s.dead(ctx, notification, err)
+		return
+	}
+
+	if telegram.IsBadRequest(err) || notification.Attempts >= maxAttempts {
+		s.dead(ctx, notification, err)
+		return
+	}
+
+	delay := retryDelay(notification.Attempts)
+
+	var apiErr *telegram.APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+		delay = apiErr.RetryAfter
+	}
+
+	s.logger.Warn(
+		"ошибка отправки уведомления, повтор",
+		"err", err,
+		"id", notification.Id,
+		"attempts", notification.Attempts,
+		"delay", delay,
+	)
+
+	if err := s.repo.MarkRetry(ctx, notification.Id, time.Now().Add(delay), err.Error()); err != nil {
+		s.logger.Error("ошибка при сохранении статуса уведомления", "err", err, "id", notification.Id)
+	}
+}
+
+func (s *Service) dead(ctx context.Context, notification NotificationModel, reason error) {
+	s.logger.Error(
+		"уведомление не доставлено",
+		"err", reason,
+		"id", notification.Id,
+		"kind", notification.Kind,
+		"chat_id", notification.ChatId,
+		"attempts", notification.Attempts,
+	)
+
+	if err := s.repo.MarkDead(ctx, notification.Id, reason.Error()); err != nil {
+		s.logger.Error("ошибка при сохранении статуса уведомления", "err", err, "id", notification.Id)
+	}
+}
+
+func (s *Service) sendMessage(ctx context.Context, notification NotificationModel) error {
+	var params telegram.SendMessageParams
+	if err := json.Unmarshal(notification.Payload, &params); err != nil {
+		return err
+	}
+
+	_, err := s.client.SendMessage(ctx, params)
+
+	return err
+}
+
+func (s *Service) sendNotification(ctx context.Context, notification NotificationModel) error {
+	recipient, err := s.users.GetUserByTelegramId(ctx, notification.ChatId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if err == nil && !recipient.NotificationsEnabled {
+		s.logger.Info("уведомления выключены, пропускаем", "id", notification.Id, "chat_id", notification.ChatId)
+		return nil
+	}
+
+	return s.sendMessage(ctx, notification)
+}
+
+// 10s, 20s, 40s... но не больше retryMaxDelay
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+
+	return delay
+}
@@ -0,0 +1,68 @@
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	appErrors "vietio/internal/errors"
+	"vietio/internal/response"
+
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service *Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service *Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) PromoteAd(w http.ResponseWriter, r *http.Request) {
+	uuid, err := uuid.Parse(r.PathValue("uuid"))
+	if err != nil {
+		h.logger.Error(appErrors.ErrNotValidUuid.Error(), "err", err, "uuid", uuid)
+		http.Error(w, appErrors.ErrNotValidUuid.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	payload := PromoteAdRequestBody{}
+
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.Json(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.CreateInvoice(r.Context(), uuid, payload)
+
+	if err != nil {
+		var vError *appErrors.ValidationError
+		switch {
+		case errors.As(err, &vError):
+			h.logger.Warn(appErrors.ErrPromoteAd.Error(), "err", err, "payload", payload)
+			response.Json(w, err, http.StatusBadRequest)
+		case errors.Is(err, appErrors.ErrForbidden):
+			h.logger.Warn(appErrors.ErrForbidden.Error(), "err", "нет прав для продвижения объявления", "uuid", uuid)
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case errors.Is(err, appErrors.ErrAdNotFound):
+			h.logger.Info(appErrors.ErrAdNotFound.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotFound.Error(), http.StatusNotFound)
+		case errors.Is(err, appErrors.ErrAdNotActive):
+			h.logger.Info(appErrors.ErrAdNotActive.Error(), "err", err, "uuid", uuid)
+			http.Error(w, appErrors.ErrAdNotActive.Error(), http.StatusConflict)
+		default:
+			h.logger.Error(appErrors.ErrPromoteAd.Error(), "err", err, "uuid", uuid)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
@@ -0,0 +1,43 @@
+package payments
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const CURRENCY_STARS = "XTR"
+
+const STATUS_PENDING = "pending"
+const STATUS_PAID = "paid"
+
+// звезды списаны, но сумма, валюта или плательщик не совпали со счетом — на возврат
+const STATUS_INVALID = "invalid"
+
+type PaymentModel struct {
+	Id                      int64
+	Uuid                    uuid.UUID
+	UserId                  int64
+	AdUuid                  uuid.UUID
+	Type                    string
+	Days                    int
+	Amount                  int
+	Currency                string
+	Status                  string
+	TelegramPaymentChargeId string
+	ProviderPaymentChargeId string
+	CreatedAt               time.Time
+	PaidAt                  *time.Time
+}
+
+type PromoteAdRequestBody struct {
+	Type string `json:"type"`
+	Days int    `json:"days"`
+}
+
+type PromoteAdResponse struct {
+	PaymentUuid string `json:"payment_uuid"`
+	InvoiceLink string `json:"invoice_link"`
+	Amount      int    `json:"amount"`
+	Currency    string `json:"currency"`
+}
@@ -0,0 +1,189 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) CreatePayment(ctx context.Context, payment PaymentModel) (uuid.UUID, error) {
+	var result uuid.UUID
+
+	query := `
+		INSERT INTO payments (
+			user_id,
+			ad_uuid,
+			"type",
+			days,
+			amount,
+			currency,
+			status
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING uuid
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		payment.UserId,
+		payment.AdUuid,
+		payment.Type,
+		payment.Days,
+		payment.Amount,
+		payment.Currency,
+		payment.Status,
+	).Scan(&result)
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+const findPaymentByUuidQuery = `
+		SELECT
+			id,
+			uuid,
+			user_id,
+			ad_uuid,
+			"type",
+			days,
+			amount,
+			currency,
+			status
+		FROM payments
+		WHERE uuid = $1
+		LIMIT 1
+	`
+
+func (r *Repository) FindPaymentByUuid(ctx context.Context, uuid uuid.UUID) (PaymentModel, error) {
+	return scanPayment(r.db.QueryRowContext(ctx, findPaymentByUuidQuery, uuid))
+}
+
+// чтение внутри транзакции оплаты: строка блокируется до коммита
+func (r *Repository) FindPaymentByUuidWithTx(ctx context.Context, tx *sql.Tx, uuid uuid.UUID) (PaymentModel, error) {
+	return scanPayment(tx.QueryRowContext(ctx, findPaymentByUuidQuery+" FOR UPDATE", uuid))
+}
+
+func (r *Repository) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return r.db.BeginTx(ctx, opts)
+}
+
+func scanPayment(row *sql.Row) (PaymentModel, error) {
+	var result PaymentModel
+
+	err := row.Scan(
+		&result.Id,
+		&result.Uuid,
+		&result.UserId,
+		&result.AdUuid,
+		&result.Type,
+		&result.Days,
+		&result.Amount,
+		&result.Currency,
+		&result.Status,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// помечает ожидающий платеж оплаченным, для уже обработанного платежа вернет sql.ErrNoRows
+func (r *Repository) MarkPaidWithTx(
+	ctx context.Context,
+	tx *sql.Tx,
+	uuid uuid.UUID,
+	telegramChargeId string,
+	providerChargeId string,
+) (PaymentModel, error) {
+	var result PaymentModel
+
+	query := `
+		UPDATE payments
+		SET
+			status = $1,
+			telegram_payment_charge_id = $2,
+			provider_payment_charge_id = $3,
+			paid_at = now()
+		WHERE
+			uuid = $4
+			AND status = $5
+		RETURNING id, uuid, user_id, ad_uuid, "type", days, amount, currency, status
+	`
+
+	err := tx.QueryRowContext(
+		ctx,
+		query,
+		STATUS_PAID,
+		telegramChargeId,
+		providerChargeId,
+		uuid,
+		STATUS_PENDING,
+	).Scan(
+		&result.Id,
+		&result.Uuid,
+		&result.UserId,
+		&result.AdUuid,
+		&result.Type,
+		&result.Days,
+		&result.Amount,
+		&result.Currency,
+		&result.Status,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// MarkInvalidWithTx сохраняет списание, которое не совпало со счетом, чтобы его можно было вернуть
+func (r *Repository) MarkInvalidWithTx(
+	ctx context.Context,
+	tx *sql.Tx,
+	uuid uuid.UUID,
+	telegramChargeId string,
+	providerChargeId string,
+) error {
+	query := `
+		UPDATE payments
+		SET
+			status = $1,
+			telegram_payment_charge_id = $2,
+			provider_payment_charge_id = $3,
+			paid_at = now()
+		WHERE
+			uuid = $4
+			AND status = $5
+	`
+
+	_, err := tx.ExecContext(
+		ctx,
+		query,
+		STATUS_INVALID,
+		telegramChargeId,
+		providerChargeId,
+		uuid,
+		STATUS_PENDING,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
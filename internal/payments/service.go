@@ -0,0 +1,274 @@
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"vietio/config"
+	"vietio/internal/ads"
+	"vietio/internal/authctx"
+	appErrors "vietio/internal/errors"
+	"vietio/internal/telegram"
+	"vietio/internal/user"
+
+	"github.com/google/uuid"
+)
+
+type AdRepository interface {
+	FindAdByUuid(context.Context, uuid.UUID) (ads.AdModel, error)
+	PromoteAdWithTx(ctx context.Context, tx *sql.Tx, uuid uuid.UUID, promotion string, days int) (time.Time, error)
+}
+
+// ledger платежей (payments.Repository)
+type PaymentRepository interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	CreatePayment(ctx context.Context, payment PaymentModel) (uuid.UUID, error)
+	FindPaymentByUuid(ctx context.Context, uuid uuid.UUID) (PaymentModel, error)
+	FindPaymentByUuidWithTx(ctx context.Context, tx *sql.Tx, uuid uuid.UUID) (PaymentModel, error)
+	MarkPaidWithTx(ctx context.Context, tx *sql.Tx, uuid uuid.UUID, telegramChargeId string, providerChargeId string) (PaymentModel, error)
+	MarkInvalidWithTx(ctx context.Context, tx *sql.Tx, uuid uuid.UUID, telegramChargeId string, providerChargeId string) error
+}
+
+type UserRepository interface {
+	GetUserByTelegramId(ctx context.Context, telegramId int64) (user.UserModel, error)
+}
+
+type InvoiceCreator interface {
+	CreateInvoiceLink(ctx context.Context, params telegram.InvoiceParams) (string, error)
+}
+
+type Service struct {
+	repo     PaymentRepository
+	adRepo   AdRepository
+	userRepo UserRepository
+	invoices InvoiceCreator
+	config   config.Payments
+}
+
+func NewService(
+	repo PaymentRepository,
+	adRepository AdRepository,
+	userRepository UserRepository,
+	invoices InvoiceCreator,
+	config config.Payments,
+) *Service {
+	return &Service{
+		repo:     repo,
+		adRepo:   adRepository,
+		userRepo: userRepository,
+		invoices: invoices,
+		config:   config,
+	}
+}
+
+// создает ожидающий платеж и ссылку на счет в Telegram Stars
+func (s *Service) CreateInvoice(ctx context.Context, adUuid uuid.UUID, payload PromoteAdRequestBody) (PromoteAdResponse, error) {
+	var result PromoteAdResponse
+
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	validationErrors := appErrors.NewValidationError()
+	if !ads.IsValidPromotion(payload.Type) {
+		validationErrors.Add("type", "type должен быть pin или highlight")
+	}
+	if payload.Days < 1 || payload.Days > s.config.MaxDays {
+		validationErrors.Add("days", fmt.Sprintf("days должен быть от 1 до %d", s.config.MaxDays))
+	}
+	if validationErrors.HasErrors() {
+		return result, validationErrors
+	}
+
+	ad, err := s.adRepo.FindAdByUuid(ctx, adUuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErrors.ErrAdNotFound
+		}
+		return result, err
+	}
+
+	if ad.UserId != contextUserId {
+		return result, appErrors.ErrForbidden
+	}
+
+	if ad.Status != ads.STATUS_ACTIVE {
+		return result, appErrors.ErrAdNotActive
+	}
+
+	amount := s.pricePerDay(payload.Type) * payload.Days
+
+	paymentUuid, err := s.repo.CreatePayment(ctx, PaymentModel{
+		UserId:   contextUserId,
+		AdUuid:   ad.Uuid,
+		Type:     payload.Type,
+		Days:     payload.Days,
+		Amount:   amount,
+		Currency: CURRENCY_STARS,
+		Status:   STATUS_PENDING,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	title := "Закрепление объявления"
+	if payload.Type == ads.PROMOTION_HIGHLIGHT {
+		title = "Выделение объявления"
+	}
+
+	link, err := s.invoices.CreateInvoiceLink(ctx, telegram.InvoiceParams{
+		Title:       title,
+		Description: fmt.Sprintf("«%s» на %d дн.", ad.Title, payload.Days),
+		Payload:     paymentUuid.String(),
+		Currency:    CURRENCY_STARS,
+		Prices: []telegram.LabeledPrice{
+			{Label: title, Amount: amount},
+		},
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.PaymentUuid = paymentUuid.String()
+	result.InvoiceLink = link
+	result.Amount = amount
+	result.Currency = CURRENCY_STARS
+
+	return result, nil
+}
+
+// проверка платежа перед списанием звезд
+func (s *Service) PreCheckout(ctx context.Context, query telegram.PreCheckoutQuery) error {
+	paymentUuid, err := uuid.Parse(query.InvoicePayload)
+	if err != nil {
+		return appErrors.ErrPaymentNotFound
+	}
+
+	payment, err := s.repo.FindPaymentByUuid(ctx, paymentUuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return appErrors.ErrPaymentNotFound
+		}
+		return err
+	}
+
+	if payment.Status != STATUS_PENDING ||
+		payment.Amount != query.TotalAmount ||
+		payment.Currency != query.Currency {
+		return appErrors.ErrPaymentInvalid
+	}
+
+	payer, err := s.userRepo.GetUserByTelegramId(ctx, query.From.Id)
+	if err != nil {
+		return err
+	}
+
+	if payer.Id != payment.UserId {
+		return appErrors.ErrPaymentInvalid
+	}
+
+	ad, err := s.adRepo.FindAdByUuid(ctx, payment.AdUuid)
+	if err != nil {
+		return err
+	}
+
+	if ad.Status != ads.STATUS_ACTIVE {
+		return appErrors.ErrAdNotActive
+	}
+
+	return nil
+}
+
+// фиксирует оплату и включает продвижение, повторные уведомления игнорируются.
+// Звезды к этому моменту уже списаны: несовпадение со счетом сохраняем для возврата
+func (s *Service) SuccessfulPayment(ctx context.Context, from telegram.User, payment telegram.SuccessfulPayment) error {
+	paymentUuid, err := uuid.Parse(payment.InvoicePayload)
+	if err != nil {
+		return appErrors.ErrPaymentNotFound
+	}
+
+	tx, err := s.repo.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	paymentModel, err := s.repo.FindPaymentByUuidWithTx(ctx, tx, paymentUuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return appErrors.ErrPaymentNotFound
+		}
+		return err
+	}
+
+	switch paymentModel.Status {
+	case STATUS_PAID:
+		return nil
+	case STATUS_INVALID:
+		return appErrors.ErrPaymentInvalid
+	}
+
+	payer, err := s.userRepo.GetUserByTelegramId(ctx, from.Id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if payment.Currency != CURRENCY_STARS ||
+		payment.Currency != paymentModel.Currency ||
+		payment.TotalAmount != paymentModel.Amount ||
+		payer.Id != paymentModel.UserId {
+		err = s.repo.MarkInvalidWithTx(
+			ctx,
+			tx,
+			paymentUuid,
+			payment.TelegramPaymentChargeId,
+			payment.ProviderPaymentChargeId,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+
+		return fmt.Errorf(
+			"%w: charge %s, %d %s from telegram user %d",
+			appErrors.ErrPaymentInvalid,
+			payment.TelegramPaymentChargeId,
+			payment.TotalAmount,
+			payment.Currency,
+			from.Id,
+		)
+	}
+
+	_, err = s.repo.MarkPaidWithTx(
+		ctx,
+		tx,
+		paymentUuid,
+		payment.TelegramPaymentChargeId,
+		payment.ProviderPaymentChargeId,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.adRepo.PromoteAdWithTx(ctx, tx, paymentModel.AdUuid, paymentModel.Type, paymentModel.Days)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Service) pricePerDay(promotion string) int {
+	if promotion == ads.PROMOTION_HIGHLIGHT {
+		return s.config.HighlightPrice
+	}
+
+	return s.config.PinPrice
+}
@@ -0,0 +1,50 @@
+package profile
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	appErrors "vietio/internal/errors"
+	"vietio/internal/response"
+	"vietio/pkg/utils"
+)
+
+type Handler struct {
+	service *Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service *Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		h.logger.Info(appErrors.ErrNotValidId.Error(), "err", err, "id", r.PathValue("id"))
+		http.Error(w, appErrors.ErrNotValidId.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page := utils.ParseInt(r.URL.Query().Get("page"), 1)
+
+	result, err := h.service.GetProfile(r.Context(), id, page)
+	if err != nil {
+		switch {
+		case errors.Is(err, appErrors.ErrUserNotFound):
+			h.logger.Info(appErrors.ErrUserNotFound.Error(), "err", err, "id", id)
+			http.Error(w, appErrors.ErrUserNotFound.Error(), http.StatusNotFound)
+		default:
+			h.logger.Error(appErrors.ErrProfile.Error(), "err", err, "id", id)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
@@ -0,0 +1,21 @@
+package profile
+
+import (
+	"time"
+
+	"vietio/internal/ads"
+	"vietio/internal/user"
+)
+
+type ProfileResponse struct {
+	Id          int64       `json:"id"`
+	DisplayName string      `json:"display_name"`
+	PhotoUrl    string      `json:"photo_url"`
+	MemberSince time.Time   `json:"member_since"`
+	ActiveCount int         `json:"active_count"`
+	SoldCount   int         `json:"sold_count"`
+	Rating      user.Rating `json:"rating"`
+	ShareUrl    string      `json:"share_url"`
+	// активные объявления, постранично через ?page=
+	Ads ads.AdsListResponse `json:"ads"`
+}
@@ -0,0 +1,97 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"vietio/internal/ads"
+	appErrors "vietio/internal/errors"
+	"vietio/internal/user"
+)
+
+type UserRepository interface {
+	GetUserById(ctx context.Context, id int64) (user.UserModel, error)
+}
+
+type AdRepository interface {
+	CountAdsByUserId(ctx context.Context, userId int64) (map[int]int, error)
+}
+
+type AdsService interface {
+	GetUserAds(ctx context.Context, userId int64, page int) (ads.AdsListResponse, error)
+}
+
+type RatingProvider interface {
+	GetUserRating(ctx context.Context, userId int64) (user.Rating, error)
+}
+
+type LinkBuilder interface {
+	User(userId int64) string
+}
+
+// Service — публичный профиль продавца: имя, аватар и его объявления.
+// Username не отдаем, связаться с продавцом можно только через бота
+type Service struct {
+	userRepo UserRepository
+	adRepo   AdRepository
+	ads      AdsService
+	ratings  RatingProvider
+	links    LinkBuilder
+}
+
+func NewService(
+	userRepository UserRepository,
+	adRepository AdRepository,
+	adsService AdsService,
+	ratings RatingProvider,
+	links LinkBuilder,
+) *Service {
+	return &Service{
+		userRepo: userRepository,
+		adRepo:   adRepository,
+		ads:      adsService,
+		ratings:  ratings,
+		links:    links,
+	}
+}
+
+func (s *Service) GetProfile(ctx context.Context, userId int64, page int) (ProfileResponse, error) {
+	var result ProfileResponse
+
+	profileUser, err := s.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErrors.ErrUserNotFound
+		}
+		return result, err
+	}
+
+	counts, err := s.adRepo.CountAdsByUserId(ctx, profileUser.Id)
+	if err != nil {
+		return result, err
+	}
+
+	rating, err := s.ratings.GetUserRating(ctx, profileUser.Id)
+	if err != nil {
+		return result, err
+	}
+
+	userAds, err := s.ads.GetUserAds(ctx, profileUser.Id, page)
+	if err != nil {
+		return result, err
+	}
+
+	result.Id = profileUser.Id
+	result.DisplayName = profileUser.DisplayName()
+	result.PhotoUrl = profileUser.PhotoUrl
+	result.MemberSince = profileUser.CreatedAt
+	// забронированные еще не проданы, считаем их активными
+	result.ActiveCount = counts[ads.STATUS_ACTIVE] + counts[ads.STATUS_RESERVED]
+	result.SoldCount = counts[ads.STATUS_SOLD]
+	result.Rating = rating
+	result.ShareUrl = s.links.User(profileUser.Id)
+	result.Ads = userAds
+
+	return result, nil
+}
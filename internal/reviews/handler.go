@@ -0,0 +1,105 @@
+package reviews
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	appErrors "vietio/internal/errors"
+	"vietio/internal/response"
+	"vietio/pkg/utils"
+)
+
+type Handler struct {
+	service *Service
+	logger  *slog.Logger
+}
+
+func NewHandler(service *Service, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) CreateReview(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseId(w, r)
+	if !ok {
+		return
+	}
+
+	payload := CreateReviewRequestBody{}
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.Json(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.CreateReview(r.Context(), id, payload)
+	if err != nil {
+		var vError *appErrors.ValidationError
+		switch {
+		case errors.As(err, &vError):
+			h.logger.Info(appErrors.ErrReview.Error(), "err", err, "id", id)
+			response.Json(w, err, http.StatusBadRequest)
+		case errors.Is(err, appErrors.ErrDealNotFound):
+			h.logger.Info(appErrors.ErrDealNotFound.Error(), "err", err, "id", id)
+			http.Error(w, appErrors.ErrDealNotFound.Error(), http.StatusNotFound)
+		case errors.Is(err, appErrors.ErrForbidden):
+			h.logger.Warn(appErrors.ErrForbidden.Error(), "err", "нет прав оставить отзыв по сделке", "id", id)
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case errors.Is(err, appErrors.ErrReviewExists):
+			h.logger.Info(appErrors.ErrReviewExists.Error(), "err", err, "id", id)
+			http.Error(w, appErrors.ErrReviewExists.Error(), http.StatusConflict)
+		default:
+			h.logger.Error(appErrors.ErrReview.Error(), "err", err, "id", id)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
+
+func (h *Handler) GetUserReviews(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseId(w, r)
+	if !ok {
+		return
+	}
+
+	page := utils.ParseInt(r.URL.Query().Get("page"), 1)
+
+	result, err := h.service.GetUserReviews(r.Context(), id, page)
+	if err != nil {
+		h.logger.Error(appErrors.ErrReviewsList.Error(), "err", err, "id", id)
+		http.Error(w, "internal server", http.StatusInternalServerError)
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
+
+func (h *Handler) GetMyDeals(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.GetMyDeals(r.Context())
+	if err != nil {
+		h.logger.Error(appErrors.ErrDealsList.Error(), "err", err)
+		http.Error(w, "internal server", http.StatusInternalServerError)
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
+
+func (h *Handler) parseId(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		h.logger.Info(appErrors.ErrNotValidId.Error(), "err", err, "id", r.PathValue("id"))
+		http.Error(w, appErrors.ErrNotValidId.Error(), http.StatusBadRequest)
+		return 0, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,118 @@
+package reviews
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// роль автора отзыва в сделке
+const ROLE_BUYER = "buyer"
+const ROLE_SELLER = "seller"
+
+// префикс callback-кнопок с оценкой: review:rate:<deal_id>:<rating>
+const CALLBACK_PREFIX = "review"
+
+const MIN_RATING = 1
+const MAX_RATING = 5
+
+// ограничение длины комментария
+const maxCommentLength = 1000
+
+type DealModel struct {
+	Id        int64
+	AdUuid    uuid.UUID
+	AdTitle   string
+	SellerId  int64
+	BuyerId   int64
+	CreatedAt time.Time
+}
+
+// Counterpart второй участник сделки
+func (d DealModel) Counterpart(userId int64) int64 {
+	if d.SellerId == userId {
+		return d.BuyerId
+	}
+
+	return d.SellerId
+}
+
+func (d DealModel) Role(userId int64) string {
+	if d.SellerId == userId {
+		return ROLE_SELLER
+	}
+
+	return ROLE_BUYER
+}
+
+func (d DealModel) HasParticipant(userId int64) bool {
+	return d.SellerId == userId || d.BuyerId == userId
+}
+
+type DealsListItemRepository struct {
+	DealModel
+	// отзыв текущего пользователя уже оставлен
+	IsReviewed bool
+}
+
+type ReviewModel struct {
+	Id         int64
+	DealId     int64
+	AuthorId   int64
+	TargetId   int64
+	Rating     int
+	Comment    string
+	FlagReason string
+	CreatedAt  time.Time
+}
+
+type ReviewsListItemRepository struct {
+	ReviewModel
+	AuthorFirstName string
+	AuthorLastName  string
+	AdTitle         string
+	// автор был продавцом в сделке
+	AuthorIsSeller bool
+}
+
+type CreateReviewRequestBody struct {
+	Rating  int    `json:"rating"`
+	Comment string `json:"comment"`
+}
+
+type CreateReviewResponse struct {
+	Id int64 `json:"id"`
+	// отзыв скрыт до проверки модератором
+	IsFlagged bool `json:"is_flagged"`
+}
+
+type ReviewResponse struct {
+	Id         int64     `json:"id"`
+	Rating     int       `json:"rating"`
+	Comment    string    `json:"comment"`
+	AuthorName string    `json:"author_name"`
+	AuthorRole string    `json:"author_role"`
+	AdTitle    string    `json:"ad_title"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ReviewsListResponse struct {
+	Items []ReviewResponse `json:"items"`
+	Total int              `json:"total"`
+	Limit int              `json:"limit"`
+	Page  int              `json:"page"`
+}
+
+type DealResponse struct {
+	Id         int64     `json:"id"`
+	AdUuid     uuid.UUID `json:"ad_uuid"`
+	AdTitle    string    `json:"ad_title"`
+	Role       string    `json:"role"`
+	IsReviewed bool      `json:"is_reviewed"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type DealsListResponse struct {
+	Items []DealResponse `json:"items"`
+	Total int            `json:"total"`
+}
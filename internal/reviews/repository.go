@@ -0,0 +1,306 @@
+package reviews
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"vietio/internal/user"
+
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// покупателя можно выбрать только из тех, кто писал продавцу по объявлению
+func (r *Repository) HasConversation(ctx context.Context, adUuid uuid.UUID, sellerId int64, buyerId int64) (bool, error) {
+	var result bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM conversations
+			WHERE
+				ad_uuid = $1
+				AND seller_id = $2
+				AND buyer_id = $3
+		)
+	`
+
+	err := r.db.QueryRowContext(ctx, query, adUuid, sellerId, buyerId).Scan(&result)
+	return result, err
+}
+
+// одна сделка на объявление, false — сделка по объявлению уже есть
+func (r *Repository) CreateDealWithTx(ctx context.Context, tx *sql.Tx, deal DealModel) (int64, bool, error) {
+	var result int64
+
+	query := `
+		INSERT INTO deals (ad_uuid, seller_id, buyer_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (ad_uuid) DO NOTHING
+		RETURNING id
+	`
+
+	err := tx.QueryRowContext(ctx, query, deal.AdUuid, deal.SellerId, deal.BuyerId).Scan(&result)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, false, nil
+		}
+		return result, false, err
+	}
+
+	return result, true, nil
+}
+
+func (r *Repository) FindDealByAdUuidWithTx(ctx context.Context, tx *sql.Tx, adUuid uuid.UUID) (DealModel, error) {
+	var result DealModel
+
+	query := `
+		SELECT
+			id,
+			ad_uuid,
+			seller_id,
+			buyer_id,
+			created_at
+		FROM deals
+		WHERE ad_uuid = $1
+	`
+
+	err := tx.QueryRowContext(ctx, query, adUuid).Scan(
+		&result.Id,
+		&result.AdUuid,
+		&result.SellerId,
+		&result.BuyerId,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// сделку, по которой уже оставили отзыв, не удаляем
+func (r *Repository) DeleteDealWithoutReviewsWithTx(ctx context.Context, tx *sql.Tx, adUuid uuid.UUID) error {
+	query := `
+		DELETE FROM deals AS d
+		WHERE
+			d.ad_uuid = $1
+			AND NOT EXISTS (SELECT 1 FROM reviews WHERE reviews.deal_id = d.id)
+	`
+
+	_, err := tx.ExecContext(ctx, query, adUuid)
+
+	return err
+}
+
+func (r *Repository) FindDealById(ctx context.Context, id int64) (DealModel, error) {
+	var result DealModel
+
+	query := `
+		SELECT
+			d.id,
+			d.ad_uuid,
+			a.title,
+			d.seller_id,
+			d.buyer_id,
+			d.created_at
+		FROM deals AS d
+		JOIN ads AS a ON a.uuid = d.ad_uuid
+		WHERE d.id = $1
+	`
+
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&result.Id,
+		&result.AdUuid,
+		&result.AdTitle,
+		&result.SellerId,
+		&result.BuyerId,
+		&result.CreatedAt,
+	)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// сделки пользователя в обеих ролях с отметкой, оставлен ли им отзыв
+func (r *Repository) FindDealsByUserId(ctx context.Context, userId int64) ([]DealsListItemRepository, error) {
+	var result []DealsListItemRepository
+
+	query := `
+		SELECT
+			d.id,
+			d.ad_uuid,
+			a.title,
+			d.seller_id,
+			d.buyer_id,
+			d.created_at,
+			EXISTS (
+				SELECT 1
+				FROM reviews
+				WHERE
+					reviews.deal_id = d.id
+					AND reviews.author_id = $1
+			)
+		FROM deals AS d
+		JOIN ads AS a ON a.uuid = d.ad_uuid
+		WHERE
+			d.seller_id = $1
+			OR d.buyer_id = $1
+		ORDER BY d.created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item DealsListItemRepository
+		if err := rows.Scan(
+			&item.Id,
+			&item.AdUuid,
+			&item.AdTitle,
+			&item.SellerId,
+			&item.BuyerId,
+			&item.CreatedAt,
+			&item.IsReviewed,
+		); err != nil {
+			return result, err
+		}
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
+
+// false — отзыв от этого автора по сделке уже есть
+func (r *Repository) CreateReview(ctx context.Context, review ReviewModel) (int64, bool, error) {
+	var result int64
+
+	var flagReason *string
+	if review.FlagReason != "" {
+		flagReason = &review.FlagReason
+	}
+
+	query := `
+		INSERT INTO reviews (
+			deal_id,
+			author_id,
+			target_id,
+			rating,
+			"comment",
+			flagged_at,
+			flag_reason
+		)
+		VALUES ($1, $2, $3, $4, $5, CASE WHEN $6::text IS NULL THEN NULL ELSE now() END, $6)
+		ON CONFLICT (deal_id, author_id) DO NOTHING
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		review.DealId,
+		review.AuthorId,
+		review.TargetId,
+		review.Rating,
+		review.Comment,
+		flagReason,
+	).Scan(&result)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, false, nil
+		}
+		return result, false, err
+	}
+
+	return result, true, nil
+}
+
+// отзывы о пользователе без отправленных на модерацию
+func (r *Repository) FindReviewsByTargetId(ctx context.Context, targetId int64, limit int, offset int) ([]ReviewsListItemRepository, int, error) {
+	var result []ReviewsListItemRepository
+	var total int
+
+	query := `
+		SELECT
+			rv.id,
+			rv.deal_id,
+			rv.author_id,
+			rv.target_id,
+			rv.rating,
+			rv."comment",
+			rv.created_at,
+			u.first_name,
+			u.last_name,
+			a.title,
+			d.seller_id = rv.author_id,
+			count(*) over()
+		FROM reviews AS rv
+		JOIN deals AS d ON d.id = rv.deal_id
+		JOIN ads AS a ON a.uuid = d.ad_uuid
+		JOIN users AS u ON u.id = rv.author_id
+		WHERE
+			rv.target_id = $1
+			AND rv.flagged_at IS NULL
+		ORDER BY rv.created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, targetId, limit, offset)
+	if err != nil {
+		return result, total, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item ReviewsListItemRepository
+		if err := rows.Scan(
+			&item.Id,
+			&item.DealId,
+			&item.AuthorId,
+			&item.TargetId,
+			&item.Rating,
+			&item.Comment,
+			&item.CreatedAt,
+			&item.AuthorFirstName,
+			&item.AuthorLastName,
+			&item.AdTitle,
+			&item.AuthorIsSeller,
+			&total,
+		); err != nil {
+			return result, total, err
+		}
+		result = append(result, item)
+	}
+
+	return result, total, rows.Err()
+}
+
+func (r *Repository) GetRating(ctx context.Context, userId int64) (user.Rating, error) {
+	var result user.Rating
+
+	query := `
+		SELECT
+			COALESCE(ROUND(AVG(rating), 1), 0)::float8,
+			count(*)
+		FROM reviews
+		WHERE target_id = $1
+	`
+
+	err := r.db.QueryRowContext(ctx, query, userId).Scan(&result.Average, &result.Count)
+	return result, err
+}
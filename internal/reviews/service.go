@@ -0,0 +1,330 @@
+package reviews
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"vietio/internal/ads"
+	"vietio/internal/authctx"
+	"vietio/internal/contentfilter"
+	appErrors "vietio/internal/errors"
+	"vietio/internal/telegram"
+	"vietio/internal/user"
+
+	"github.com/google/uuid"
+)
+
+// сколько отзывов на странице профиля
+const reviewsLimit = 20
+
+type UserRepository interface {
+	GetUserById(ctx context.Context, id int64) (user.UserModel, error)
+}
+
+// приглашения оставить отзыв идут через очередь уведомлений
+type Notifier interface {
+	Notify(ctx context.Context, params telegram.SendMessageParams) error
+}
+
+type ContentFilter interface {
+	Check(ctx context.Context, categoryId int, fields []contentfilter.Field) (contentfilter.Result, error)
+}
+
+// Service — сделки и отзывы после них.
+// Сделку создает ads.Service при отметке «продано» с выбранным покупателем,
+// после этого обе стороны могут оставить по одному отзыву друг о друге
+type Service struct {
+	repo          *Repository
+	userRepo      UserRepository
+	notifier      Notifier
+	contentFilter ContentFilter
+}
+
+func NewService(
+	repo *Repository,
+	userRepository UserRepository,
+	notifier Notifier,
+	contentFilter ContentFilter,
+) *Service {
+	return &Service{
+		repo:          repo,
+		userRepo:      userRepository,
+		notifier:      notifier,
+		contentFilter: contentFilter,
+	}
+}
+
+// IsBuyerContact покупатель писал продавцу по этому объявлению
+func (s *Service) IsBuyerContact(ctx context.Context, adUuid uuid.UUID, sellerId int64, buyerId int64) (bool, error) {
+	if sellerId == buyerId {
+		return false, nil
+	}
+
+	return s.repo.HasConversation(ctx, adUuid, sellerId, buyerId)
+}
+
+// RecordDealWithTx фиксирует сделку в транзакции смены статуса на «продано».
+// Сделка на объявление одна: повторная отметка с тем же покупателем возвращает ее же (created = false),
+// с другим покупателем — ErrDealExists
+func (s *Service) RecordDealWithTx(ctx context.Context, tx *sql.Tx, ad ads.AdModel, buyerId int64) (int64, bool, error) {
+	id, created, err := s.repo.CreateDealWithTx(ctx, tx, DealModel{
+		AdUuid:   ad.Uuid,
+		SellerId: ad.UserId,
+		BuyerId:  buyerId,
+	})
+	if err != nil || created {
+		return id, created, err
+	}
+
+	deal, err := s.repo.FindDealByAdUuidWithTx(ctx, tx, ad.Uuid)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if deal.BuyerId != buyerId {
+		return 0, false, appErrors.ErrDealExists
+	}
+
+	return deal.Id, false, nil
+}
+
+// SendDealPrompts предлагает обеим сторонам сделки оценить друг друга, вызывается после коммита сделки
+func (s *Service) SendDealPrompts(ctx context.Context, dealId int64) error {
+	deal, err := s.repo.FindDealById(ctx, dealId)
+	if err != nil {
+		return err
+	}
+
+	for _, userId := range []int64{deal.SellerId, deal.BuyerId} {
+		err = s.sendPrompt(ctx, deal, userId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CancelDealWithTx продажу отменили восстановлением объявления: сделку без отзывов убираем
+// в той же транзакции, чтобы при повторной продаже можно было выбрать другого покупателя
+func (s *Service) CancelDealWithTx(ctx context.Context, tx *sql.Tx, adUuid uuid.UUID) error {
+	return s.repo.DeleteDealWithoutReviewsWithTx(ctx, tx, adUuid)
+}
+
+// GetUserRating средняя оценка пользователя для профиля и карточки объявления
+func (s *Service) GetUserRating(ctx context.Context, userId int64) (user.Rating, error) {
+	return s.repo.GetRating(ctx, userId)
+}
+
+// CreateReview отзыв текущего пользователя о второй стороне сделки
+func (s *Service) CreateReview(ctx context.Context, dealId int64, payload CreateReviewRequestBody) (CreateReviewResponse, error) {
+	var result CreateReviewResponse
+
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	deal, err := s.repo.FindDealById(ctx, dealId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErrors.ErrDealNotFound
+		}
+		return result, err
+	}
+
+	if !deal.HasParticipant(contextUserId) {
+		return result, appErrors.ErrForbidden
+	}
+
+	targetId := deal.Counterpart(contextUserId)
+	if targetId == contextUserId {
+		return result, appErrors.ErrForbidden
+	}
+
+	comment := strings.TrimSpace(payload.Comment)
+
+	validationErrors := appErrors.NewValidationError()
+	if payload.Rating < MIN_RATING || payload.Rating > MAX_RATING {
+		validationErrors.Add("rating", fmt.Sprintf("rating должен быть от %d до %d", MIN_RATING, MAX_RATING))
+	}
+	if len([]rune(comment)) > maxCommentLength {
+		validationErrors.Add("comment", fmt.Sprintf("comment должен быть не длиннее %d символов", maxCommentLength))
+	}
+	if validationErrors.HasErrors() {
+		return result, validationErrors
+	}
+
+	flagReason := ""
+	if comment != "" {
+		comment, flagReason, err = s.filterComment(ctx, comment)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	id, created, err := s.repo.CreateReview(ctx, ReviewModel{
+		DealId:     deal.Id,
+		AuthorId:   contextUserId,
+		TargetId:   targetId,
+		Rating:     payload.Rating,
+		Comment:    comment,
+		FlagReason: flagReason,
+	})
+	if err != nil {
+		return result, err
+	}
+	if !created {
+		return result, appErrors.ErrReviewExists
+	}
+
+	result.Id = id
+	result.IsFlagged = flagReason != ""
+
+	return result, nil
+}
+
+func (s *Service) GetUserReviews(ctx context.Context, userId int64, page int) (ReviewsListResponse, error) {
+	var result ReviewsListResponse
+
+	if page < 1 {
+		page = 1
+	}
+
+	items, total, err := s.repo.FindReviewsByTargetId(ctx, userId, reviewsLimit, reviewsLimit*(page-1))
+	if err != nil {
+		return result, err
+	}
+
+	result.Items = make([]ReviewResponse, 0, len(items))
+	for _, item := range items {
+		authorRole := ROLE_BUYER
+		if item.AuthorIsSeller {
+			authorRole = ROLE_SELLER
+		}
+
+		author := user.UserModel{
+			FirstName: item.AuthorFirstName,
+			LastName:  item.AuthorLastName,
+		}
+
+		result.Items = append(result.Items, ReviewResponse{
+			Id:         item.Id,
+			Rating:     item.Rating,
+			Comment:    item.Comment,
+			AuthorName: author.DisplayName(),
+			AuthorRole: authorRole,
+			AdTitle:    item.AdTitle,
+			CreatedAt:  item.CreatedAt,
+		})
+	}
+	result.Total = total
+	result.Limit = reviewsLimit
+	result.Page = page
+
+	return result, nil
+}
+
+func (s *Service) GetMyDeals(ctx context.Context) (DealsListResponse, error) {
+	var result DealsListResponse
+
+	contextUserId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	items, err := s.repo.FindDealsByUserId(ctx, contextUserId)
+	if err != nil {
+		return result, err
+	}
+
+	result.Items = make([]DealResponse, 0, len(items))
+	for _, item := range items {
+		result.Items = append(result.Items, DealResponse{
+			Id:         item.Id,
+			AdUuid:     item.AdUuid,
+			AdTitle:    item.AdTitle,
+			Role:       item.Role(contextUserId),
+			IsReviewed: item.IsReviewed,
+			CreatedAt:  item.CreatedAt,
+		})
+	}
+	result.Total = len(result.Items)
+
+	return result, nil
+}
+
+// проверка комментария тем же контент-фильтром, что и объявления:
+// запрещенное — ошибка валидации, подозрительное уходит на модерацию
+func (s *Service) filterComment(ctx context.Context, comment string) (string, string, error) {
+	filterResult, err := s.contentFilter.Check(ctx, 0, []contentfilter.Field{
+		{Name: "comment", Value: comment},
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	if filterResult.IsRejected() {
+		validationErrors := appErrors.NewValidationError()
+		for _, m := range filterResult.Rejected() {
+			validationErrors.Add(m.Field, m.Message)
+		}
+		return "", "", validationErrors
+	}
+
+	var reasons []string
+	for _, m := range filterResult.Flagged() {
+		reasons = append(reasons, m.Field+": "+m.Message)
+	}
+
+	return filterResult.Value("comment"), strings.Join(reasons, "; "), nil
+}
+
+func (s *Service) sendPrompt(ctx context.Context, deal DealModel, userId int64) error {
+	recipient, err := s.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if recipient.BotBlockedAt != nil {
+		return nil
+	}
+
+	counterpart := "продавца"
+	if deal.Role(userId) == ROLE_SELLER {
+		counterpart = "покупателя"
+	}
+
+	return s.notifier.Notify(ctx, telegram.SendMessageParams{
+		ChatId: recipient.TelegramId,
+		Text: fmt.Sprintf(
+			"🤝 Сделка по «%s» завершена.\n\nОцените %s от 1 до 5 — это поможет другим пользователям.",
+			html.EscapeString(deal.AdTitle),
+			counterpart,
+		),
+		ParseMode:   telegram.PARSE_MODE_HTML,
+		ReplyMarkup: ratingKeyboard(deal.Id),
+	})
+}
+
+// кнопки оценки: review:rate:<deal_id>:<rating>
+func ratingKeyboard(dealId int64) *telegram.InlineKeyboardMarkup {
+	id := strconv.FormatInt(dealId, 10)
+
+	row := make([]telegram.InlineKeyboardButton, 0, MAX_RATING)
+	for rating := MIN_RATING; rating <= MAX_RATING; rating++ {
+		row = append(row, telegram.InlineKeyboardButton{
+			Text:         strconv.Itoa(rating) + "⭐",
+			CallbackData: telegram.CallbackData(CALLBACK_PREFIX, "rate", id, strconv.Itoa(rating)),
+		})
+	}
+
+	return &telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{row},
+	}
+}
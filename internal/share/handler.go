@@ -0,0 +1,117 @@
+package share
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"vietio/internal/ads"
+	appErrors "vietio/internal/errors"
+	"vietio/internal/images"
+	"vietio/pkg/utils"
+
+	"github.com/google/uuid"
+)
+
+// длина og:description, дальше мессенджеры все равно обрезают
+const descriptionMaxLength = 200
+
+// превью кешируется мессенджерами, нам хватит нескольких минут
+const cacheControl = "public, max-age=300"
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+type AdsService interface {
+	GetAd(ctx context.Context, uuid uuid.UUID) (ads.AdResponse, error)
+}
+
+type adPage struct {
+	Title       string
+	Description string
+	Price       int
+	PriceText   string
+	Image       string
+	Url         string
+	// ссылка в Mini App, по ней страница сразу уводит пользователя
+	AppUrl string
+}
+
+// Handler отдает /ad/{uuid}: html с OpenGraph/Twitter-разметкой для превью ссылок
+// в Zalo, Facebook, WhatsApp и редиректом в Mini App
+type Handler struct {
+	ads       AdsService
+	publicUrl string
+	logger    *slog.Logger
+	templates *template.Template
+}
+
+func NewHandler(adsService AdsService, publicUrl string, logger *slog.Logger) *Handler {
+	return &Handler{
+		ads:       adsService,
+		publicUrl: strings.TrimRight(publicUrl, "/"),
+		logger:    logger,
+		templates: template.Must(template.ParseFS(templatesFS, "templates/*.html")),
+	}
+}
+
+func (h *Handler) GetAdPage(w http.ResponseWriter, r *http.Request) {
+	adUuid, err := uuid.Parse(r.PathValue("uuid"))
+	if err != nil {
+		h.render(w, "not_found.html", nil, http.StatusNotFound)
+		return
+	}
+
+	ad, err := h.ads.GetAd(r.Context(), adUuid)
+	if err != nil {
+		if errors.Is(err, appErrors.ErrAdNotFound) || errors.Is(err, appErrors.ErrAdNotActive) {
+			h.render(w, "not_found.html", nil, http.StatusNotFound)
+			return
+		}
+
+		h.logger.Error(appErrors.ErrAd.Error(), "err", err, "uuid", adUuid)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	page := adPage{
+		Title:       ad.Title,
+		Description: utils.FormatPrice(ad.Price) + " · " + truncate(ad.Description, descriptionMaxLength),
+		Price:       ad.Price,
+		PriceText:   utils.FormatPrice(ad.Price),
+		Url:         h.publicUrl + "/ad/" + ad.Uuid.String(),
+		AppUrl:      ad.ShareUrl,
+	}
+	if len(ad.ImageVariants) > 0 {
+		page.Image = ad.ImageVariants[0][images.VARIANT_FULL]
+	}
+
+	w.Header().Set("Cache-Control", cacheControl)
+	h.render(w, "ad.html", page, http.StatusOK)
+}
+
+func (h *Handler) render(w http.ResponseWriter, name string, data any, status int) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := h.templates.ExecuteTemplate(w, name, data); err != nil {
+		h.logger.Error("ошибка рендера страницы", "err", err, "template", name)
+	}
+}
+
+func truncate(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	runes := []rune(text)
+
+	return strings.TrimSpace(string(runes[:limit])) + "…"
+}
@@ -14,12 +14,20 @@ import (
 type LocalStorage struct {
 	PublicUrl string
 	BasePath string
+	// оригиналы прямой загрузки: вне BasePath, чтобы не раздавались как есть
+	OriginalsPath string
+	// кеш вариантов /img, по папке на фото
+	VariantsPath string
+	signingSecret []byte
 }
 
-func NewLocalStorage(publicUrl, basePath string) *LocalStorage {
+func NewLocalStorage(publicUrl, basePath, originalsPath, variantsPath, signingSecret string) *LocalStorage {
 	return &LocalStorage{
 		PublicUrl: publicUrl,
 		BasePath: basePath,
+		OriginalsPath: originalsPath,
+		VariantsPath: variantsPath,
+		signingSecret: []byte(signingSecret),
 	}
 }
 
@@ -85,7 +93,7 @@ func (s *LocalStorage) DeleteByPath(ctx context.Context, path string) error {
 		return err
 	}
 
-	return nil
+	return s.deleteVariants(path)
 }
 
 func (s *LocalStorage) GetPublicPath(path string) string {
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	appErrors "vietio/internal/errors"
+)
+
+func (s *LocalStorage) OpenMaster(ctx context.Context, path string) (io.ReadCloser, error) {
+	if !isPlainFileName(path) {
+		return nil, appErrors.ErrImageNotFound
+	}
+
+	file, err := os.Open(filepath.Join(s.BasePath, path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, appErrors.ErrImageNotFound
+	}
+
+	return file, err
+}
+
+func (s *LocalStorage) GetVariant(ctx context.Context, key string) ([]byte, error) {
+	variantPath, err := s.variantPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(variantPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, appErrors.ErrImageNotFound
+	}
+
+	return data, err
+}
+
+func (s *LocalStorage) SaveVariant(ctx context.Context, key string, data []byte, contentType string) error {
+	variantPath, err := s.variantPath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(variantPath), 0755); err != nil {
+		return err
+	}
+
+	// пишем во временный файл, чтобы параллельный запрос не прочитал вариант наполовину
+	tmp, err := os.CreateTemp(filepath.Dir(variantPath), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), variantPath)
+}
+
+func (s *LocalStorage) deleteVariants(path string) error {
+	if !isPlainFileName(path) {
+		return nil
+	}
+
+	return os.RemoveAll(filepath.Join(s.VariantsPath, path))
+}
+
+// ключ варианта: {path}/{вариант}
+func (s *LocalStorage) variantPath(key string) (string, error) {
+	path, name, ok := strings.Cut(key, "/")
+	if !ok || !isPlainFileName(path) || !isPlainFileName(name) {
+		return "", fmt.Errorf("invalid variant key: %s", key)
+	}
+
+	return filepath.Join(s.VariantsPath, path, name), nil
+}
+
+// имя файла без каталогов, фото хранятся плоско
+func isPlainFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name
+}
@@ -119,7 +119,7 @@ func (s *S3Storage) DeleteByPath(ctx context.Context, path string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete file from S3: %w", err)
 	}
-	return nil
+	return s.deleteVariants(ctx, path)
 }
 
 func (s *S3Storage) GetPublicPath(path string) string {
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	appErrors "vietio/internal/errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// оригиналы прямой загрузки лежат отдельно и не публикуются
+const s3OriginalsPrefix = "originals/"
+
+// PresignUpload presigned PUT: Content-Type и Content-Length входят в подпись,
+// поэтому загрузить другой тип или размер по ссылке нельзя
+func (s *S3Storage) PresignUpload(
+	ctx context.Context,
+	key string,
+	contentType string,
+	size int64,
+	expiresAt time.Time,
+) (string, map[string]string, error) {
+	request, err := s3.NewPresignClient(s.client).PresignPutObject(
+		ctx,
+		&s3.PutObjectInput{
+			Bucket:        aws.String(s.bucketName),
+			Key:           aws.String(s3OriginalsPrefix + key),
+			ContentType:   aws.String(contentType),
+			ContentLength: aws.Int64(size),
+		},
+		s3.WithPresignExpires(time.Until(expiresAt)),
+	)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to presign upload: %w", err)
+	}
+
+	return request.URL, map[string]string{"Content-Type": contentType}, nil
+}
+
+func (s *S3Storage) OpenOriginal(ctx context.Context, key string) (io.ReadCloser, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(s3OriginalsPrefix + key),
+	})
+	if err != nil {
+		var notFound *types.NoSuchKey
+		if errors.As(err, &notFound) {
+			return nil, appErrors.ErrUploadNotReceived
+		}
+		return nil, fmt.Errorf("failed to get original from S3: %w", err)
+	}
+
+	return output.Body, nil
+}
+
+func (s *S3Storage) DeleteOriginal(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(s3OriginalsPrefix + key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete original from S3: %w", err)
+	}
+
+	return nil
+}
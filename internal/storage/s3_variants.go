@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	appErrors "vietio/internal/errors"
+	"vietio/internal/images"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// кеш вариантов /img, по «папке» на фото
+const s3VariantsPrefix = "variants/"
+
+func (s *S3Storage) OpenMaster(ctx context.Context, path string) (io.ReadCloser, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, appErrors.ErrImageNotFound
+		}
+		return nil, fmt.Errorf("failed to get master from S3: %w", err)
+	}
+
+	return output.Body, nil
+}
+
+func (s *S3Storage) GetVariant(ctx context.Context, key string) ([]byte, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(s3VariantsPrefix + key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, appErrors.ErrImageNotFound
+		}
+		return nil, fmt.Errorf("failed to get variant from S3: %w", err)
+	}
+	defer output.Body.Close()
+
+	return io.ReadAll(output.Body)
+}
+
+func (s *S3Storage) SaveVariant(ctx context.Context, key string, data []byte, contentType string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:       aws.String(s.bucketName),
+		Key:          aws.String(s3VariantsPrefix + key),
+		Body:         bytes.NewReader(data),
+		ContentType:  aws.String(contentType),
+		CacheControl: aws.String(images.CACHE_CONTROL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload variant: %w", err)
+	}
+
+	return nil
+}
+
+func (s *S3Storage) deleteVariants(ctx context.Context, path string) error {
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucketName),
+		Prefix: aws.String(s3VariantsPrefix + path + "/"),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list variants in S3: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+				Bucket: aws.String(s.bucketName),
+				Key:    object.Key,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to delete variant from S3: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
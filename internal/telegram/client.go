@@ -2,51 +2,299 @@ package telegram
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 )
 
+// сколько раз повторяем запрос после 429
+const maxRetries = 3
+
+const maxDownloadSize = 20 << 20
+
+// 20 МБ должны успеть скачаться и на медленном канале
+const downloadTimeout = 2 * time.Minute
+
 type Client struct {
-	Token  string
-	Client *http.Client
+	Token   string
+	BaseUrl string
+	Client  *http.Client
+	// без общего таймаута: getUpdates держит соединение до timeout секунд,
+	// а скачивание файла дольше таймаута API. Срок задается контекстом запроса
+	LongPollClient *http.Client
+	limiter        *rateLimiter
 }
 
-func NewClient(token string) *Client {
+func NewClient(token string, baseUrl string) *Client {
 	return &Client{
-		Token: token,
+		Token:   token,
+		BaseUrl: baseUrl,
 		Client: &http.Client{
 			Timeout: 5 * time.Second,
 		},
+		LongPollClient: &http.Client{},
+		limiter:        newRateLimiter(),
+	}
+}
+
+type apiResponse struct {
+	Ok          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+func (c *Client) SendMessage(ctx context.Context, params SendMessageParams) (Message, error) {
+	var result Message
+
+	err := c.send(ctx, params.ChatId, "sendMessage", params, &result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// photo: file_id, уже загруженный в Telegram, или публичный url
+func (c *Client) SendPhoto(ctx context.Context, params SendPhotoParams) (Message, error) {
+	var result Message
+
+	err := c.send(ctx, params.ChatId, "sendPhoto", params, &result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// альбом из 2-10 фото, подпись берется из первого элемента
+func (c *Client) SendMediaGroup(ctx context.Context, params SendMediaGroupParams) ([]Message, error) {
+	var result []Message
+
+	err := c.send(ctx, params.ChatId, "sendMediaGroup", params, &result)
+	if err != nil {
+		return nil, err
 	}
+
+	return result, nil
 }
 
-func(c *Client) SendMessage(chatId int64, msg string) error {
-    url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", c.Token)
+func (c *Client) EditMessageText(ctx context.Context, params EditMessageTextParams) error {
+	return c.send(ctx, params.ChatId, "editMessageText", params, nil)
+}
 
-    payload := map[string]any{
-        "chat_id": chatId,
-        "text": msg,
-    }
+// подпись у фото или первого сообщения альбома
+func (c *Client) EditMessageCaption(ctx context.Context, params EditMessageCaptionParams) error {
+	return c.send(ctx, params.ChatId, "editMessageCaption", params, nil)
+}
 
-    body, err := json.Marshal(payload)
-    if err != nil {
-        return err
-    }
+// сообщения старше 48 часов Telegram удалить не дает
+func (c *Client) DeleteMessage(ctx context.Context, chatId int64, messageId int64) error {
+	payload := map[string]any{
+		"chat_id":    chatId,
+		"message_id": messageId,
+	}
 
-    resp, err := c.Client.Post(
-        url,
-        "application/json",
-        bytes.NewBuffer(body),
-    )
-    if err != nil {
-        return err
-    }
+	return c.send(ctx, chatId, "deleteMessage", payload, nil)
+}
 
-    if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("telegram returned status %d", resp.StatusCode)
+// удаление нескольких сообщений одним запросом, например альбома
+func (c *Client) DeleteMessages(ctx context.Context, chatId int64, messageIds []int64) error {
+	payload := map[string]any{
+		"chat_id":     chatId,
+		"message_ids": messageIds,
 	}
 
-    return nil
+	return c.send(ctx, chatId, "deleteMessages", payload, nil)
+}
+
+// ответ нужно отправить, пока пользователь печатает, без ожидания лимитов чата
+func (c *Client) AnswerInlineQuery(ctx context.Context, params AnswerInlineQueryParams) error {
+	return c.call(ctx, "answerInlineQuery", params, nil)
+}
+
+// на каждый callback_query нужно ответить, иначе у кнопки крутится индикатор загрузки
+func (c *Client) AnswerCallbackQuery(ctx context.Context, params AnswerCallbackQueryParams) error {
+	return c.call(ctx, "answerCallbackQuery", params, nil)
+}
+
+// путь к файлу для DownloadFile, ссылка живет не меньше часа
+func (c *Client) GetFile(ctx context.Context, fileId string) (File, error) {
+	var result File
+
+	err := c.call(ctx, "getFile", map[string]any{"file_id": fileId}, &result)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Bot API отдает ботам файлы до 20 МБ
+func (c *Client) DownloadFile(ctx context.Context, filePath string) ([]byte, error) {
+	url := fmt.Sprintf("%s/file/bot%s/%s", c.BaseUrl, c.Token, filePath)
+
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.LongPollClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("telegram download returned status %d", resp.StatusCode)
+	}
+
+	// лишний байт отличает файл ровно на лимит от обрезанного
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownloadSize {
+		return nil, ErrFileTooLarge
+	}
+
+	return data, nil
+}
+
+// long polling, timeout в секундах
+func (c *Client) GetUpdates(ctx context.Context, params GetUpdatesParams) ([]Update, error) {
+	var result []Update
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(params.Timeout+10)*time.Second)
+	defer cancel()
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.do(ctx, c.LongPollClient, "getUpdates", body, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// getUpdates не работает, пока установлен вебхук
+func (c *Client) DeleteWebhook(ctx context.Context) error {
+	return c.call(ctx, "deleteWebhook", map[string]any{}, nil)
+}
+
+// вебхук с секретом, который Telegram присылает в X-Telegram-Bot-Api-Secret-Token
+func (c *Client) SetWebhook(ctx context.Context, params SetWebhookParams) error {
+	return c.call(ctx, "setWebhook", params, nil)
+}
+
+// ссылка на оплату, которую Mini App открывает через openInvoice
+func (c *Client) CreateInvoiceLink(ctx context.Context, params InvoiceParams) (string, error) {
+	var result string
+
+	err := c.call(ctx, "createInvoiceLink", params, &result)
+	if err != nil {
+		return "", err
+	}
+
+	return result, nil
+}
+
+// ответить на pre_checkout_query нужно в течение 10 секунд
+func (c *Client) AnswerPreCheckoutQuery(ctx context.Context, queryId string, ok bool, errorMessage string) error {
+	payload := map[string]any{
+		"pre_checkout_query_id": queryId,
+		"ok":                    ok,
+	}
+	if !ok {
+		payload["error_message"] = errorMessage
+	}
+
+	return c.call(ctx, "answerPreCheckoutQuery", payload, nil)
+}
+
+// отправка в чат с учетом лимитов Bot API
+func (c *Client) send(ctx context.Context, chatId int64, method string, payload any, result any) error {
+	if err := c.limiter.Wait(ctx, chatId); err != nil {
+		return err
+	}
+
+	return c.call(ctx, method, payload, result)
+}
+
+// вызов метода Bot API, result заполняется полем result ответа.
+// На 429 ждем retry_after и повторяем
+func (c *Client) call(ctx context.Context, method string, payload any, result any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = c.do(ctx, c.Client, method, body, result)
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.RetryAfter == 0 || attempt >= maxRetries {
+			return err
+		}
+
+		timer := time.NewTimer(apiErr.RetryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, httpClient *http.Client, method string, body []byte, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.methodUrl(method), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var apiResp apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("telegram %s returned status %d: %w", method, resp.StatusCode, err)
+	}
+
+	if !apiResp.Ok {
+		return &APIError{
+			Method:      method,
+			Code:        apiResp.ErrorCode,
+			Description: apiResp.Description,
+			RetryAfter:  time.Duration(apiResp.Parameters.RetryAfter) * time.Second,
+		}
+	}
+
+	if result != nil {
+		return json.Unmarshal(apiResp.Result, result)
+	}
+
+	return nil
+}
+
+func (c *Client) methodUrl(method string) string {
+	return fmt.Sprintf("%s/bot%s/%s", c.BaseUrl, c.Token, method)
 }
@@ -0,0 +1,198 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCallRetriesAfterTooManyRequests(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 1","parameters":{"retry_after":1}}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":5,"chat":{"id":10}}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("token", server.URL)
+
+	start := time.Now()
+	message, err := client.SendMessage(context.Background(), SendMessageParams{ChatId: 10, Text: "hi"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+
+	if message.MessageId != 5 {
+		t.Errorf("MessageId = %d, want 5", message.MessageId)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want at least retry_after", elapsed)
+	}
+}
+
+func TestCallStopsRetryOnContextCancel(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, `{"ok":false,"error_code":429,"description":"Too Many Requests","parameters":{"retry_after":30}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("token", server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := client.AnswerPreCheckoutQuery(ctx, "query", true, "")
+	if !IsTooManyRequests(err) {
+		t.Fatalf("err = %v, want 429", err)
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter != 30*time.Second {
+		t.Errorf("RetryAfter = %s, want 30s", apiErr.RetryAfter)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestCallDoesNotRetryOtherErrors(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("token", server.URL)
+
+	_, err := client.SendMessage(context.Background(), SendMessageParams{ChatId: 10, Text: "hi"})
+	if !IsForbidden(err) {
+		t.Fatalf("err = %v, want 403", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	limiter := newRateLimiter()
+
+	first := limiter.reserve(1)
+	second := limiter.reserve(1)
+	if gap := second.Sub(first); gap < privateChatSendInterval {
+		t.Errorf("private chat gap = %s, want at least %s", gap, privateChatSendInterval)
+	}
+
+	group := limiter.reserve(-100)
+	groupNext := limiter.reserve(-100)
+	if gap := groupNext.Sub(group); gap < groupChatSendInterval {
+		t.Errorf("group chat gap = %s, want at least %s", gap, groupChatSendInterval)
+	}
+
+	// другой чат ждет только глобальный интервал
+	other := limiter.reserve(2)
+	if gap := other.Sub(groupNext); gap > globalSendInterval {
+		t.Errorf("other chat waits %s after previous send, want at most %s", gap, globalSendInterval)
+	}
+}
+
+func TestRateLimiterWaitCancelled(t *testing.T) {
+	limiter := newRateLimiter()
+
+	if err := limiter.Wait(context.Background(), 1); err != nil {
+		t.Fatalf("first Wait: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := limiter.Wait(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed >= privateChatSendInterval {
+		t.Errorf("Wait returned after %s, want on ctx cancel", elapsed)
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		err  error
+	}{
+		{"within limit", 1024, nil},
+		{"exactly limit", maxDownloadSize, nil},
+		{"too large", maxDownloadSize + 1, ErrFileTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := bytes.Repeat([]byte{'x'}, tt.size)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/file/bottoken/photos/file_1.jpg" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write(body)
+			}))
+			defer server.Close()
+
+			client := NewClient("token", server.URL)
+
+			data, err := client.DownloadFile(context.Background(), "photos/file_1.jpg")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && len(data) != tt.size {
+				t.Errorf("len(data) = %d, want %d", len(data), tt.size)
+			}
+		})
+	}
+}
+
+func TestDownloadFileOutlivesAPITimeout(t *testing.T) {
+	body := bytes.Repeat([]byte{'x'}, 64<<10)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// отдаем файл частями дольше таймаута обычного вызова API
+		for i := 0; i < 4; i++ {
+			w.Write(body[i*len(body)/4 : (i+1)*len(body)/4])
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("token", server.URL)
+	client.Client.Timeout = 50 * time.Millisecond
+
+	data, err := client.DownloadFile(context.Background(), "photos/file_1.jpg")
+	if err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+	if len(data) != len(body) {
+		t.Errorf("len(data) = %d, want %d", len(data), len(body))
+	}
+}
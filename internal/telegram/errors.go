@@ -0,0 +1,48 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// файл больше maxDownloadSize, Bot API такие все равно не отдает
+var ErrFileTooLarge = errors.New("telegram file too large")
+
+// ошибка, которую вернул Bot API (ok: false)
+type APIError struct {
+	Method      string
+	Code        int
+	Description string
+	// для 429: через сколько можно повторить запрос
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram %s error %d: %s", e.Method, e.Code, e.Description)
+}
+
+// 403: пользователь заблокировал бота или бот удален из чата
+func IsForbidden(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden
+}
+
+func IsTooManyRequests(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests
+}
+
+// 400: запрос некорректен (чат не найден, сообщение не изменилось...), повтор не поможет
+func IsBadRequest(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest
+}
+
+// editMessage* с тем же текстом Telegram считает ошибкой
+func IsMessageNotModified(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && strings.Contains(apiErr.Description, "message is not modified")
+}
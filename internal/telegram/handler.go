@@ -1,52 +1,54 @@
 package telegram
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"log/slog"
 	"net/http"
 	"vietio/internal/response"
 )
 
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
 type Handler struct {
-	Logger   *slog.Logger
-	TgClient *Client
+	Logger        *slog.Logger
+	Router        *Router
+	WebhookSecret string
 }
 
-func NewHandler(logger *slog.Logger, tgClient *Client) *Handler {
+func NewHandler(logger *slog.Logger, router *Router, webhookSecret string) *Handler {
 	return &Handler{
-		Logger:   logger,
-		TgClient: tgClient,
+		Logger:        logger,
+		Router:        router,
+		WebhookSecret: webhookSecret,
 	}
 }
 
+// Telegram повторяет апдейт, пока не получит 200: при ошибке обработки отвечаем 500,
+// чтобы апдейт пришел снова. Невалидный json повторять бессмысленно
 func (h *Handler) Webhook(w http.ResponseWriter, r *http.Request) {
-	payload := WebhookRequestBody{}
+	// секрет передается в setWebhook и приходит в каждом запросе от Telegram
+	secret := r.Header.Get(secretTokenHeader)
+	if h.WebhookSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(h.WebhookSecret)) != 1 {
+		h.Logger.Warn("telegram webhook invalid secret token", "remote_addr", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
 
-	err := json.NewDecoder(r.Body).Decode(&payload)
+	update := Update{}
+
+	err := json.NewDecoder(r.Body).Decode(&update)
 	if err != nil {
 		h.Logger.Warn("telegram webhook error", "err", err)
 		response.Json(w, "", http.StatusOK)
 		return
 	}
 
-	if payload.Message.Text != "/start" {
-		h.Logger.Info("telegram webhook not start", "request", r.Body)
-		response.Json(w, "", http.StatusOK)
+	err = h.Router.Dispatch(r.Context(), &update)
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	go func(chatId int64) {
-        text := `Добро пожаловать в Vietio 🇻🇳
-
-Локальные объявления в Нячанге — всё в одном месте.
-
-👇 Нажмите «Открыть» и начните пользоваться`
-
-		err := h.TgClient.SendMessage(chatId, text)
-        if err != nil {
-            h.Logger.Warn("sendMessage telegram error", "err", err)
-        }
-	}(payload.Message.Chat.Id)
-
 	response.Json(w, "", http.StatusOK)
 }
@@ -0,0 +1,94 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+)
+
+func LoggingMiddleware(logger *slog.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, update *Update) error {
+			start := time.Now()
+
+			err := next(ctx, update)
+
+			attrs := []any{
+				"update_id", update.UpdateId,
+				"type", update.Type(),
+				"duration", time.Since(start).String(),
+			}
+			if from := update.From(); from != nil {
+				attrs = append(attrs, "telegram_id", from.Id)
+			}
+
+			if err != nil {
+				logger.Error("telegram update error", append(attrs, "err", err)...)
+			} else {
+				logger.Info("telegram update", attrs...)
+			}
+
+			return err
+		}
+	}
+}
+
+func RecoverMiddleware(logger *slog.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, update *Update) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error("telegram panic recovered",
+						slog.Any("error", r),
+						slog.String("stack", string(debug.Stack())),
+						slog.Int64("update_id", update.UpdateId),
+					)
+					err = fmt.Errorf("panic: %v", r)
+				}
+			}()
+
+			return next(ctx, update)
+		}
+	}
+}
+
+type UpdateStore interface {
+	MarkProcessed(ctx context.Context, updateId int64) (bool, error)
+	UnmarkProcessed(ctx context.Context, updateId int64) error
+}
+
+// повторно доставленные апдейты (ретраи Telegram, подделка) не обрабатываем.
+// update_id занимаем до обработки, чтобы параллельный ретрай не прошел,
+// а при ошибке или панике освобождаем — повтор от Telegram обработается заново
+func DeduplicateMiddleware(store UpdateStore, logger *slog.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, update *Update) (err error) {
+			isNew, err := store.MarkProcessed(ctx, update.UpdateId)
+			if err != nil {
+				return err
+			}
+
+			if !isNew {
+				logger.Info("telegram duplicate update skipped", "update_id", update.UpdateId)
+				return nil
+			}
+
+			succeeded := false
+			defer func() {
+				if succeeded {
+					return
+				}
+				if unmarkErr := store.UnmarkProcessed(context.WithoutCancel(ctx), update.UpdateId); unmarkErr != nil {
+					logger.Error("telegram update unmark error", "err", unmarkErr, "update_id", update.UpdateId)
+				}
+			}()
+
+			err = next(ctx, update)
+			succeeded = err == nil
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+type memoryUpdateStore struct {
+	processed map[int64]bool
+}
+
+func (s *memoryUpdateStore) MarkProcessed(ctx context.Context, updateId int64) (bool, error) {
+	if s.processed[updateId] {
+		return false, nil
+	}
+	s.processed[updateId] = true
+	return true, nil
+}
+
+func (s *memoryUpdateStore) UnmarkProcessed(ctx context.Context, updateId int64) error {
+	delete(s.processed, updateId)
+	return nil
+}
+
+func TestDeduplicateMiddleware(t *testing.T) {
+	store := &memoryUpdateStore{processed: map[int64]bool{}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	calls := 0
+	failures := 1
+	handler := DeduplicateMiddleware(store, logger)(func(ctx context.Context, update *Update) error {
+		calls++
+		if failures > 0 {
+			failures--
+			return errors.New("temporary")
+		}
+		return nil
+	})
+
+	update := &Update{UpdateId: 42}
+
+	if err := handler(context.Background(), update); err == nil {
+		t.Fatal("first delivery: want error")
+	}
+	if store.processed[42] {
+		t.Fatal("failed update must be unmarked")
+	}
+
+	if err := handler(context.Background(), update); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if err := handler(context.Background(), update); err != nil {
+		t.Fatalf("duplicate: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("handler calls = %d, want 2", calls)
+	}
+}
+
+func TestDeduplicateMiddlewarePanic(t *testing.T) {
+	store := &memoryUpdateStore{processed: map[int64]bool{}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	handler := RecoverMiddleware(logger)(DeduplicateMiddleware(store, logger)(func(ctx context.Context, update *Update) error {
+		panic("boom")
+	}))
+
+	if err := handler(context.Background(), &Update{UpdateId: 7}); err == nil {
+		t.Fatal("want error from recovered panic")
+	}
+	if store.processed[7] {
+		t.Error("panicked update must be unmarked")
+	}
+}
@@ -1,14 +1,347 @@
 package telegram
 
-type WebhookRequestBody struct {
-	Message Message `json:"message"`
+import "strings"
+
+type Update struct {
+	UpdateId         int64              `json:"update_id"`
+	Message          *Message           `json:"message"`
+	EditedMessage    *Message           `json:"edited_message"`
+	CallbackQuery    *CallbackQuery     `json:"callback_query"`
+	InlineQuery      *InlineQuery       `json:"inline_query"`
+	MyChatMember     *ChatMemberUpdated `json:"my_chat_member"`
+	PreCheckoutQuery *PreCheckoutQuery  `json:"pre_checkout_query"`
 }
 
 type Message struct {
-	Text string `json:"text"`
-	Chat Chat   `json:"chat"`
+	MessageId         int64              `json:"message_id"`
+	From              *User              `json:"from"`
+	Chat              Chat               `json:"chat"`
+	Date              int64              `json:"date"`
+	Text              string             `json:"text"`
+	Entities          []MessageEntity    `json:"entities"`
+	Caption           string             `json:"caption"`
+	Photo             []PhotoSize        `json:"photo"`
+	SuccessfulPayment *SuccessfulPayment `json:"successful_payment"`
+}
+
+type MessageEntity struct {
+	Type   string `json:"type"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+}
+
+type PhotoSize struct {
+	FileId       string `json:"file_id"`
+	FileUniqueId string `json:"file_unique_id"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	FileSize     int64  `json:"file_size"`
+}
+
+type File struct {
+	FileId   string `json:"file_id"`
+	FileSize int64  `json:"file_size"`
+	FilePath string `json:"file_path"`
 }
 
 type Chat struct {
-	Id int64 `json:"id"`
+	Id       int64  `json:"id"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Username string `json:"username"`
+}
+
+type User struct {
+	Id           int64  `json:"id"`
+	IsBot        bool   `json:"is_bot"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name"`
+	Username     string `json:"username"`
+	LanguageCode string `json:"language_code"`
+}
+
+type CallbackQuery struct {
+	Id           string   `json:"id"`
+	From         User     `json:"from"`
+	Message      *Message `json:"message"`
+	ChatInstance string   `json:"chat_instance"`
+	Data         string   `json:"data"`
+}
+
+type InlineQuery struct {
+	Id       string `json:"id"`
+	From     User   `json:"from"`
+	Query    string `json:"query"`
+	Offset   string `json:"offset"`
+	ChatType string `json:"chat_type"`
+}
+
+type ChatMemberUpdated struct {
+	Chat          Chat       `json:"chat"`
+	From          User       `json:"from"`
+	Date          int64      `json:"date"`
+	OldChatMember ChatMember `json:"old_chat_member"`
+	NewChatMember ChatMember `json:"new_chat_member"`
+}
+
+// status: creator, administrator, member, restricted, left, kicked
+type ChatMember struct {
+	Status string `json:"status"`
+	User   User   `json:"user"`
+}
+
+type PreCheckoutQuery struct {
+	Id             string `json:"id"`
+	From           User   `json:"from"`
+	Currency       string `json:"currency"`
+	TotalAmount    int    `json:"total_amount"`
+	InvoicePayload string `json:"invoice_payload"`
+}
+
+type SuccessfulPayment struct {
+	Currency                string `json:"currency"`
+	TotalAmount             int    `json:"total_amount"`
+	InvoicePayload          string `json:"invoice_payload"`
+	TelegramPaymentChargeId string `json:"telegram_payment_charge_id"`
+	ProviderPaymentChargeId string `json:"provider_payment_charge_id"`
+}
+
+type LabeledPrice struct {
+	Label  string `json:"label"`
+	Amount int    `json:"amount"`
+}
+
+// для оплаты в Telegram Stars currency = XTR, provider_token пустой
+type InvoiceParams struct {
+	Title         string         `json:"title"`
+	Description   string         `json:"description"`
+	Payload       string         `json:"payload"`
+	ProviderToken string         `json:"provider_token"`
+	Currency      string         `json:"currency"`
+	Prices        []LabeledPrice `json:"prices"`
+}
+
+// типы апдейтов, которые обрабатывает бот
+var AllowedUpdates = []string{
+	"message",
+	"edited_message",
+	"callback_query",
+	"inline_query",
+	"my_chat_member",
+	"pre_checkout_query",
+}
+
+type SetWebhookParams struct {
+	Url                string   `json:"url"`
+	SecretToken        string   `json:"secret_token"`
+	AllowedUpdates     []string `json:"allowed_updates"`
+	DropPendingUpdates bool     `json:"drop_pending_updates"`
+}
+
+// режимы форматирования текста
+const PARSE_MODE_HTML = "HTML"
+const PARSE_MODE_MARKDOWN_V2 = "MarkdownV2"
+
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// заполняется ровно одно из полей Url, CallbackData, WebApp, SwitchInlineQuery
+type InlineKeyboardButton struct {
+	Text              string      `json:"text"`
+	Url               string      `json:"url,omitempty"`
+	CallbackData      string      `json:"callback_data,omitempty"`
+	WebApp            *WebAppInfo `json:"web_app,omitempty"`
+	SwitchInlineQuery *string     `json:"switch_inline_query,omitempty"`
+}
+
+type WebAppInfo struct {
+	Url string `json:"url"`
+}
+
+type ReplyKeyboardRemove struct {
+	RemoveKeyboard bool `json:"remove_keyboard"`
+}
+
+type LinkPreviewOptions struct {
+	IsDisabled bool `json:"is_disabled"`
+}
+
+type SendMessageParams struct {
+	ChatId             int64               `json:"chat_id"`
+	Text               string              `json:"text"`
+	ParseMode          string              `json:"parse_mode,omitempty"`
+	LinkPreviewOptions *LinkPreviewOptions `json:"link_preview_options,omitempty"`
+	// *InlineKeyboardMarkup или *ReplyKeyboardRemove
+	ReplyMarkup any `json:"reply_markup,omitempty"`
+}
+
+type SendPhotoParams struct {
+	ChatId      int64  `json:"chat_id"`
+	Photo       string `json:"photo"`
+	Caption     string `json:"caption,omitempty"`
+	ParseMode   string `json:"parse_mode,omitempty"`
+	ReplyMarkup any    `json:"reply_markup,omitempty"`
+}
+
+type InputMediaPhoto struct {
+	// всегда "photo"
+	Type      string `json:"type"`
+	Media     string `json:"media"`
+	Caption   string `json:"caption,omitempty"`
+	ParseMode string `json:"parse_mode,omitempty"`
+}
+
+func NewInputMediaPhoto(media string) InputMediaPhoto {
+	return InputMediaPhoto{
+		Type:  "photo",
+		Media: media,
+	}
+}
+
+type SendMediaGroupParams struct {
+	ChatId int64             `json:"chat_id"`
+	Media  []InputMediaPhoto `json:"media"`
+}
+
+type EditMessageTextParams struct {
+	ChatId             int64                 `json:"chat_id"`
+	MessageId          int64                 `json:"message_id"`
+	Text               string                `json:"text"`
+	ParseMode          string                `json:"parse_mode,omitempty"`
+	LinkPreviewOptions *LinkPreviewOptions   `json:"link_preview_options,omitempty"`
+	ReplyMarkup        *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+type EditMessageCaptionParams struct {
+	ChatId      int64                 `json:"chat_id"`
+	MessageId   int64                 `json:"message_id"`
+	Caption     string                `json:"caption"`
+	ParseMode   string                `json:"parse_mode,omitempty"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+// результаты inline-режима: InlineQueryResultPhoto или InlineQueryResultArticle
+type AnswerInlineQueryParams struct {
+	InlineQueryId string `json:"inline_query_id"`
+	Results       []any  `json:"results"`
+	CacheTime     int    `json:"cache_time,omitempty"`
+	IsPersonal    bool   `json:"is_personal,omitempty"`
+	// пустая строка — результатов больше нет
+	NextOffset string `json:"next_offset"`
+}
+
+type InlineQueryResultPhoto struct {
+	// всегда "photo"
+	Type         string                `json:"type"`
+	Id           string                `json:"id"`
+	PhotoUrl     string                `json:"photo_url"`
+	ThumbnailUrl string                `json:"thumbnail_url"`
+	Title        string                `json:"title,omitempty"`
+	Description  string                `json:"description,omitempty"`
+	Caption      string                `json:"caption,omitempty"`
+	ParseMode    string                `json:"parse_mode,omitempty"`
+	ReplyMarkup  *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+type InlineQueryResultArticle struct {
+	// всегда "article"
+	Type                string                  `json:"type"`
+	Id                  string                  `json:"id"`
+	Title               string                  `json:"title"`
+	Description         string                  `json:"description,omitempty"`
+	ThumbnailUrl        string                  `json:"thumbnail_url,omitempty"`
+	InputMessageContent InputTextMessageContent `json:"input_message_content"`
+	ReplyMarkup         *InlineKeyboardMarkup   `json:"reply_markup,omitempty"`
+}
+
+type InputTextMessageContent struct {
+	MessageText        string              `json:"message_text"`
+	ParseMode          string              `json:"parse_mode,omitempty"`
+	LinkPreviewOptions *LinkPreviewOptions `json:"link_preview_options,omitempty"`
+}
+
+type AnswerCallbackQueryParams struct {
+	CallbackQueryId string `json:"callback_query_id"`
+	Text            string `json:"text,omitempty"`
+	ShowAlert       bool   `json:"show_alert,omitempty"`
+	Url             string `json:"url,omitempty"`
+}
+
+type GetUpdatesParams struct {
+	Offset         int64    `json:"offset"`
+	Timeout        int      `json:"timeout"`
+	AllowedUpdates []string `json:"allowed_updates"`
+}
+
+// From автор апдейта независимо от его типа
+func (u *Update) From() *User {
+	switch {
+	case u.Message != nil:
+		return u.Message.From
+	case u.EditedMessage != nil:
+		return u.EditedMessage.From
+	case u.CallbackQuery != nil:
+		return &u.CallbackQuery.From
+	case u.InlineQuery != nil:
+		return &u.InlineQuery.From
+	case u.MyChatMember != nil:
+		return &u.MyChatMember.From
+	case u.PreCheckoutQuery != nil:
+		return &u.PreCheckoutQuery.From
+	default:
+		return nil
+	}
+}
+
+// ChatId чат, в который нужно отвечать
+func (u *Update) ChatId() int64 {
+	switch {
+	case u.Message != nil:
+		return u.Message.Chat.Id
+	case u.EditedMessage != nil:
+		return u.EditedMessage.Chat.Id
+	case u.CallbackQuery != nil && u.CallbackQuery.Message != nil:
+		return u.CallbackQuery.Message.Chat.Id
+	case u.MyChatMember != nil:
+		return u.MyChatMember.Chat.Id
+	}
+
+	if from := u.From(); from != nil {
+		return from.Id
+	}
+
+	return 0
+}
+
+// Type тип апдейта для логов
+func (u *Update) Type() string {
+	switch {
+	case u.Message != nil:
+		return "message"
+	case u.EditedMessage != nil:
+		return "edited_message"
+	case u.CallbackQuery != nil:
+		return "callback_query"
+	case u.InlineQuery != nil:
+		return "inline_query"
+	case u.MyChatMember != nil:
+		return "my_chat_member"
+	case u.PreCheckoutQuery != nil:
+		return "pre_checkout_query"
+	default:
+		return "unknown"
+	}
+}
+
+// Command разбирает "/search@vietio_bot honda" на команду и аргументы
+func (m *Message) Command() (string, string) {
+	if !strings.HasPrefix(m.Text, "/") {
+		return "", ""
+	}
+
+	command, args, _ := strings.Cut(m.Text, " ")
+	command, _, _ = strings.Cut(command, "@")
+
+	return strings.TrimPrefix(command, "/"), strings.TrimSpace(args)
 }
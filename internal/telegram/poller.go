@@ -0,0 +1,99 @@
+package telegram
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// сколько секунд Telegram держит запрос getUpdates
+const pollTimeout = 30
+
+// пауза после ошибки, чтобы не долбить API
+const pollRetryDelay = 3 * time.Second
+
+type OffsetStore interface {
+	GetOffset(ctx context.Context) (int64, error)
+	SaveOffset(ctx context.Context, offset int64) error
+}
+
+// Poller получает апдейты через getUpdates и отдает их в тот же Router, что и вебхук
+type Poller struct {
+	client *Client
+	router *Router
+	store  OffsetStore
+	logger *slog.Logger
+}
+
+func NewPoller(client *Client, router *Router, store OffsetStore, logger *slog.Logger) *Poller {
+	return &Poller{
+		client: client,
+		router: router,
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Run работает до отмены ctx, текущий апдейт при остановке дообрабатывается
+func (p *Poller) Run(ctx context.Context) error {
+	if err := p.client.DeleteWebhook(ctx); err != nil {
+		return err
+	}
+
+	offset, err := p.store.GetOffset(ctx)
+	if err != nil {
+		return err
+	}
+
+	p.logger.Info("telegram polling started", "offset", offset)
+
+	for ctx.Err() == nil {
+		updates, err := p.client.GetUpdates(ctx, GetUpdatesParams{
+			Offset:         offset,
+			Timeout:        pollTimeout,
+			AllowedUpdates: AllowedUpdates,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+
+			p.logger.Warn("telegram getUpdates error", "err", err)
+			sleep(ctx, pollRetryDelay)
+			continue
+		}
+
+		for _, update := range updates {
+			// offset не сдвигаем: после паузы пачка придет заново начиная с этого апдейта,
+			// уже обработанные отсечет DeduplicateMiddleware
+			if err := p.router.Dispatch(context.WithoutCancel(ctx), &update); err != nil {
+				p.logger.Error("telegram dispatch error", "err", err, "update_id", update.UpdateId)
+				sleep(ctx, pollRetryDelay)
+				break
+			}
+
+			offset = update.UpdateId + 1
+			if err := p.store.SaveOffset(context.WithoutCancel(ctx), offset); err != nil {
+				p.logger.Error("telegram save offset error", "err", err, "offset", offset)
+			}
+
+			if ctx.Err() != nil {
+				break
+			}
+		}
+	}
+
+	p.logger.Info("telegram polling stopped", "offset", offset)
+
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,87 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// лимиты Bot API: около 30 сообщений в секунду на бота,
+// не больше одного в секунду в личный чат и 20 в минуту в группу
+const globalSendInterval = time.Second / 30
+const privateChatSendInterval = time.Second
+const groupChatSendInterval = 3 * time.Second
+
+// после скольких чатов чистим устаревшие записи
+const rateLimiterSweepSize = 1000
+
+// rateLimiter раздает слоты на отправку: следующий запрос в чат
+// не раньше, чем через интервал чата, и не чаще глобального лимита
+type rateLimiter struct {
+	mu         sync.Mutex
+	globalNext time.Time
+	chatNext   map[int64]time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		chatNext: make(map[int64]time.Time),
+	}
+}
+
+// Wait блокирует до выделенного слота или отмены ctx
+func (l *rateLimiter) Wait(ctx context.Context, chatId int64) error {
+	at := l.reserve(chatId)
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (l *rateLimiter) reserve(chatId int64) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	if len(l.chatNext) >= rateLimiterSweepSize {
+		for id, next := range l.chatNext {
+			if next.Before(now) {
+				delete(l.chatNext, id)
+			}
+		}
+	}
+
+	at := now
+	if l.globalNext.After(at) {
+		at = l.globalNext
+	}
+	if next, ok := l.chatNext[chatId]; ok && next.After(at) {
+		at = next
+	}
+
+	l.globalNext = at.Add(globalSendInterval)
+	l.chatNext[chatId] = at.Add(chatSendInterval(chatId))
+
+	return at
+}
+
+// у групп и каналов отрицательные id
+func chatSendInterval(chatId int64) time.Duration {
+	if chatId < 0 {
+		return groupChatSendInterval
+	}
+
+	return privateChatSendInterval
+}
@@ -0,0 +1,109 @@
+package telegram
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ключ в telegram_state для offset getUpdates
+const offsetKey = "updates_offset"
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// MarkProcessed запоминает update_id, false — апдейт уже обрабатывался
+func (r *Repository) MarkProcessed(ctx context.Context, updateId int64) (bool, error) {
+	query := `
+		INSERT INTO telegram_updates (update_id)
+		VALUES ($1)
+		ON CONFLICT (update_id) DO NOTHING
+	`
+
+	res, err := r.db.ExecContext(ctx, query, updateId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// UnmarkProcessed освобождает update_id, если обработка не удалась
+func (r *Repository) UnmarkProcessed(ctx context.Context, updateId int64) error {
+	query := `
+		DELETE FROM telegram_updates
+		WHERE update_id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, updateId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteProcessedBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM telegram_updates
+		WHERE created_at < $1
+	`
+
+	res, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (r *Repository) GetOffset(ctx context.Context) (int64, error) {
+	var result int64
+
+	query := `
+		SELECT "value"
+		FROM telegram_state
+		WHERE "key" = $1
+	`
+
+	err := r.db.QueryRowContext(ctx, query, offsetKey).Scan(&result)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return result, nil
+}
+
+func (r *Repository) SaveOffset(ctx context.Context, offset int64) error {
+	query := `
+		INSERT INTO telegram_state ("key", "value")
+		VALUES ($1, $2)
+		ON CONFLICT ("key") DO UPDATE
+		SET
+			"value" = EXCLUDED."value",
+			updated_at = now()
+	`
+
+	_, err := r.db.ExecContext(ctx, query, offsetKey, offset)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
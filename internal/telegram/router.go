@@ -0,0 +1,115 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+)
+
+type HandlerFunc func(ctx context.Context, update *Update) error
+
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Router раскладывает апдейты по обработчикам:
+// команды, callback-кнопки (по префиксу data до ":"), inline-запросы и т.д.
+type Router struct {
+	commands          map[string]HandlerFunc
+	callbacks         map[string]HandlerFunc
+	message           HandlerFunc
+	inlineQuery       HandlerFunc
+	myChatMember      HandlerFunc
+	preCheckoutQuery  HandlerFunc
+	successfulPayment HandlerFunc
+	middlewares       []Middleware
+}
+
+func NewRouter() *Router {
+	return &Router{
+		commands:  make(map[string]HandlerFunc),
+		callbacks: make(map[string]HandlerFunc),
+	}
+}
+
+func (r *Router) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+func (r *Router) Command(name string, handler HandlerFunc) {
+	r.commands[name] = handler
+}
+
+func (r *Router) Callback(prefix string, handler HandlerFunc) {
+	r.callbacks[prefix] = handler
+}
+
+// Message обработчик текстовых сообщений, которые не являются командами
+func (r *Router) Message(handler HandlerFunc) {
+	r.message = handler
+}
+
+func (r *Router) InlineQuery(handler HandlerFunc) {
+	r.inlineQuery = handler
+}
+
+func (r *Router) MyChatMember(handler HandlerFunc) {
+	r.myChatMember = handler
+}
+
+func (r *Router) PreCheckoutQuery(handler HandlerFunc) {
+	r.preCheckoutQuery = handler
+}
+
+func (r *Router) SuccessfulPayment(handler HandlerFunc) {
+	r.successfulPayment = handler
+}
+
+func (r *Router) Dispatch(ctx context.Context, update *Update) error {
+	handler := r.route(update)
+	if handler == nil {
+		return nil
+	}
+
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		handler = r.middlewares[i](handler)
+	}
+
+	return handler(ctx, update)
+}
+
+func (r *Router) route(update *Update) HandlerFunc {
+	switch {
+	case update.Message != nil:
+		if update.Message.SuccessfulPayment != nil {
+			return r.successfulPayment
+		}
+
+		if command, _ := update.Message.Command(); command != "" {
+			if handler, ok := r.commands[command]; ok {
+				return handler
+			}
+		}
+
+		return r.message
+	case update.CallbackQuery != nil:
+		prefix, _, _ := strings.Cut(update.CallbackQuery.Data, ":")
+		return r.callbacks[prefix]
+	case update.InlineQuery != nil:
+		return r.inlineQuery
+	case update.MyChatMember != nil:
+		return r.myChatMember
+	case update.PreCheckoutQuery != nil:
+		return r.preCheckoutQuery
+	default:
+		return nil
+	}
+}
+
+// CallbackData собирает data для кнопки: "prefix:arg1:arg2"
+func CallbackData(prefix string, args ...string) string {
+	return strings.Join(append([]string{prefix}, args...), ":")
+}
+
+// CallbackArgs аргументы из data кнопки без префикса
+func CallbackArgs(data string) []string {
+	parts := strings.Split(data, ":")
+	return parts[1:]
+}
@@ -0,0 +1,164 @@
+package uploads
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	appErrors "vietio/internal/errors"
+	"vietio/internal/response"
+)
+
+type Handler struct {
+	service *Service
+	maxSize int64
+	logger  *slog.Logger
+}
+
+func NewHandler(service *Service, maxSize int64, logger *slog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		maxSize: maxSize,
+		logger:  logger,
+	}
+}
+
+// UploadImage одно фото в поле image
+func (h *Handler) UploadImage(w http.ResponseWriter, r *http.Request) {
+	// Максимальный размер тела 10 MB
+	err := r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		response.Json(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, header, err := r.FormFile("image")
+	if err != nil {
+		validationErrors := appErrors.NewValidationError()
+		validationErrors.Add("image", "image обязательно для загрузки")
+		response.Json(w, validationErrors, http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.Upload(r.Context(), header)
+	if err != nil {
+		switch {
+		case errors.Is(err, appErrors.ErrUploadImage):
+			h.logger.Info(appErrors.ErrUploadImage.Error(), "err", err, "filename", header.Filename)
+			http.Error(w, appErrors.ErrUploadImage.Error(), http.StatusBadRequest)
+		default:
+			h.logger.Error(appErrors.ErrUpload.Error(), "err", err, "filename", header.Filename)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
+
+// PresignUpload ссылка на прямую загрузку оригинала в хранилище
+func (h *Handler) PresignUpload(w http.ResponseWriter, r *http.Request) {
+	payload := PresignRequestBody{}
+
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.Json(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.Presign(r.Context(), payload)
+	if err != nil {
+		var vError *appErrors.ValidationError
+
+		switch {
+		case errors.As(err, &vError):
+			h.logger.Info(appErrors.ErrUpload.Error(), "err", err, "payload", payload)
+			response.Json(w, err, http.StatusBadRequest)
+		case errors.Is(err, appErrors.ErrUploadUnsupported):
+			h.logger.Info(appErrors.ErrUploadUnsupported.Error())
+			http.Error(w, appErrors.ErrUploadUnsupported.Error(), http.StatusNotImplemented)
+		default:
+			h.logger.Error(appErrors.ErrUpload.Error(), "err", err)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
+
+// ReceiveDirectUpload прием файла по подписанной ссылке, авторизацию заменяет подпись
+func (h *Handler) ReceiveDirectUpload(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	// размер проверяется по подписи, здесь только защита от бесконечного тела
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxSize+1)
+
+	err := h.service.ReceiveSigned(r.Context(), key, r.URL.Query(), r.Header.Get("Content-Type"), r.Body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.Is(err, appErrors.ErrUploadSignature):
+			h.logger.Warn(appErrors.ErrUploadSignature.Error(), "key", key)
+			http.Error(w, appErrors.ErrUploadSignature.Error(), http.StatusForbidden)
+		case errors.Is(err, appErrors.ErrUploadExpired):
+			h.logger.Info(appErrors.ErrUploadExpired.Error(), "key", key)
+			http.Error(w, appErrors.ErrUploadExpired.Error(), http.StatusForbidden)
+		case errors.Is(err, appErrors.ErrUploadMismatch), errors.As(err, &maxBytesError):
+			h.logger.Info(appErrors.ErrUploadMismatch.Error(), "err", err, "key", key)
+			http.Error(w, appErrors.ErrUploadMismatch.Error(), http.StatusBadRequest)
+		case errors.Is(err, appErrors.ErrUploadUnsupported):
+			h.logger.Info(appErrors.ErrUploadUnsupported.Error(), "key", key)
+			http.Error(w, appErrors.ErrUploadUnsupported.Error(), http.StatusNotFound)
+		default:
+			h.logger.Error(appErrors.ErrUpload.Error(), "err", err, "key", key)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// CompleteUpload подтверждение прямой загрузки: оригинал проверяется и обрабатывается
+func (h *Handler) CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		h.logger.Info(appErrors.ErrNotValidId.Error(), "err", err, "id", r.PathValue("id"))
+		http.Error(w, appErrors.ErrNotValidId.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.Complete(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, appErrors.ErrUploadNotFound):
+			h.logger.Info(appErrors.ErrUploadNotFound.Error(), "id", id)
+			http.Error(w, appErrors.ErrUploadNotFound.Error(), http.StatusNotFound)
+		case errors.Is(err, appErrors.ErrForbidden):
+			h.logger.Warn(appErrors.ErrForbidden.Error(), "err", "чужая загрузка", "id", id)
+			http.Error(w, "forbidden", http.StatusForbidden)
+		case errors.Is(err, appErrors.ErrUploadNotReceived):
+			h.logger.Info(appErrors.ErrUploadNotReceived.Error(), "id", id)
+			http.Error(w, appErrors.ErrUploadNotReceived.Error(), http.StatusConflict)
+		case errors.Is(err, appErrors.ErrUploadMismatch):
+			h.logger.Info(appErrors.ErrUploadMismatch.Error(), "id", id)
+			http.Error(w, appErrors.ErrUploadMismatch.Error(), http.StatusBadRequest)
+		case errors.Is(err, appErrors.ErrUploadImage):
+			h.logger.Info(appErrors.ErrUploadImage.Error(), "err", err, "id", id)
+			http.Error(w, appErrors.ErrUploadImage.Error(), http.StatusBadRequest)
+		case errors.Is(err, appErrors.ErrUploadUnsupported):
+			h.logger.Info(appErrors.ErrUploadUnsupported.Error(), "id", id)
+			http.Error(w, appErrors.ErrUploadUnsupported.Error(), http.StatusNotImplemented)
+		default:
+			h.logger.Error(appErrors.ErrUpload.Error(), "err", err, "id", id)
+			http.Error(w, "internal server", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.Json(w, result, http.StatusOK)
+}
@@ -0,0 +1,46 @@
+package uploads
+
+import "time"
+
+// сколько живет загруженное фото, которое не прикрепили к объявлению
+const STAGED_TTL = 24 * time.Hour
+
+type UploadResponse struct {
+	Id         int64  `json:"id"`
+	Url        string `json:"url"`
+	PreviewUrl string `json:"preview_url"`
+}
+
+// типы, которые можно загрузить напрямую в хранилище
+var allowedContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+	"image/heic": true,
+	"image/heif": true,
+}
+
+// выданная ссылка на прямую загрузку оригинала
+type IntentModel struct {
+	Id          int64
+	UserId      int64
+	ObjectKey   string
+	ContentType string
+	Size        int64
+	ExpiresAt   time.Time
+}
+
+type PresignRequestBody struct {
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// клиент загружает файл запросом Method на Url с заголовками Headers,
+// затем подтверждает загрузку через POST /api/uploads/{id}/complete
+type PresignResponse struct {
+	Id        int64             `json:"id"`
+	Url       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
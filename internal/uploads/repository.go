@@ -0,0 +1,135 @@
+package uploads
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+func (r *Repository) CreateIntent(ctx context.Context, intent IntentModel) (int64, error) {
+	var id int64
+
+	query := `
+		INSERT INTO upload_intents (
+			user_id,
+			object_key,
+			content_type,
+			"size",
+			expires_at
+		)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		intent.UserId,
+		intent.ObjectKey,
+		intent.ContentType,
+		intent.Size,
+		intent.ExpiresAt,
+	).Scan(&id)
+
+	return id, err
+}
+
+func (r *Repository) FindIntentById(ctx context.Context, id int64) (IntentModel, error) {
+	var result IntentModel
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			object_key,
+			content_type,
+			"size",
+			expires_at
+		FROM
+			upload_intents
+		WHERE
+			id = $1
+	`
+
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&result.Id,
+		&result.UserId,
+		&result.ObjectKey,
+		&result.ContentType,
+		&result.Size,
+		&result.ExpiresAt,
+	)
+
+	return result, err
+}
+
+// DeleteIntent false — загрузку уже подтвердили параллельно
+func (r *Repository) DeleteIntent(ctx context.Context, id int64) (bool, error) {
+	query := `
+		DELETE FROM upload_intents
+		WHERE id = $1
+	`
+
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+
+	return affected > 0, err
+}
+
+// ссылки, по которым так и не подтвердили загрузку
+func (r *Repository) FindIntentsExpiredBefore(ctx context.Context, before time.Time) ([]IntentModel, error) {
+	var result []IntentModel
+
+	query := `
+		SELECT
+			id,
+			user_id,
+			object_key,
+			content_type,
+			"size",
+			expires_at
+		FROM
+			upload_intents
+		WHERE
+			expires_at < $1
+		ORDER BY
+			id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, before)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item IntentModel
+		if err := rows.Scan(
+			&item.Id,
+			&item.UserId,
+			&item.ObjectKey,
+			&item.ContentType,
+			&item.Size,
+			&item.ExpiresAt,
+		); err != nil {
+			return result, err
+		}
+		result = append(result, item)
+	}
+
+	return result, rows.Err()
+}
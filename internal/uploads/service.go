@@ -0,0 +1,316 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"time"
+
+	"vietio/config"
+	"vietio/internal/ads"
+	"vietio/internal/authctx"
+	appErrors "vietio/internal/errors"
+	fileApp "vietio/internal/file"
+
+	"github.com/google/uuid"
+)
+
+// сколько после истечения ссылки ждем подтверждения загрузки, прежде чем удалить оригинал
+const intentGrace = time.Hour
+
+type FileRepository interface {
+	SaveStaged(ctx context.Context, fileModel fileApp.FileModel) (int64, error)
+	FindStagedBefore(ctx context.Context, before time.Time) ([]fileApp.FileModel, error)
+	DeleteStaged(ctx context.Context, id int64) (bool, error)
+}
+
+// DirectStorage хранилище, в которое клиент загружает оригинал сам по подписанной ссылке
+type DirectStorage interface {
+	PresignUpload(ctx context.Context, key string, contentType string, size int64, expiresAt time.Time) (string, map[string]string, error)
+	OpenOriginal(ctx context.Context, key string) (io.ReadCloser, error)
+	DeleteOriginal(ctx context.Context, key string) error
+}
+
+// SignedUploadReceiver сам принимает загрузку по подписанной ссылке (LocalStorage)
+type SignedUploadReceiver interface {
+	ReceiveUpload(ctx context.Context, key string, query url.Values, contentType string, body io.Reader) error
+}
+
+// Service — загрузка фото по одному до создания объявления.
+// Фото обрабатывается и сохраняется сразу, а к объявлению прикрепляется по id в file_ids.
+// Оригинал можно загрузить напрямую в хранилище: presign → PUT → complete
+type Service struct {
+	fileRepo FileRepository
+	repo     *Repository
+	storage  ads.FileStorage
+	config   config.Uploads
+}
+
+func NewService(
+	fileRepository FileRepository,
+	repo *Repository,
+	storage ads.FileStorage,
+	config config.Uploads,
+) *Service {
+	return &Service{
+		fileRepo: fileRepository,
+		repo:     repo,
+		storage:  storage,
+		config:   config,
+	}
+}
+
+func (s *Service) Upload(ctx context.Context, header *multipart.FileHeader) (UploadResponse, error) {
+	var result UploadResponse
+
+	userId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return result, err
+	}
+	defer file.Close()
+
+	return s.stage(ctx, userId, file, header)
+}
+
+// Presign выдает ссылку на прямую загрузку оригинала с ограничением типа и размера
+func (s *Service) Presign(ctx context.Context, payload PresignRequestBody) (PresignResponse, error) {
+	var result PresignResponse
+
+	userId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	direct, ok := s.storage.(DirectStorage)
+	if !ok {
+		return result, appErrors.ErrUploadUnsupported
+	}
+
+	validationErrors := appErrors.NewValidationError()
+	if !allowedContentTypes[payload.ContentType] {
+		validationErrors.Add("content_type", "content_type должен быть изображением jpeg, png, webp или heic")
+	}
+	if payload.Size < 1 || payload.Size > s.config.MaxSize {
+		validationErrors.Add("size", fmt.Sprintf("size должен быть от 1 до %d байт", s.config.MaxSize))
+	}
+	if validationErrors.HasErrors() {
+		return result, validationErrors
+	}
+
+	intent := IntentModel{
+		UserId:      userId,
+		ObjectKey:   uuid.NewString(),
+		ContentType: payload.ContentType,
+		Size:        payload.Size,
+		ExpiresAt:   time.Now().Add(s.config.UrlTtl),
+	}
+
+	uploadUrl, headers, err := direct.PresignUpload(ctx, intent.ObjectKey, intent.ContentType, intent.Size, intent.ExpiresAt)
+	if err != nil {
+		return result, err
+	}
+
+	id, err := s.repo.CreateIntent(ctx, intent)
+	if err != nil {
+		return result, err
+	}
+
+	result.Id = id
+	result.Url = uploadUrl
+	result.Method = "PUT"
+	result.Headers = headers
+	result.ExpiresAt = intent.ExpiresAt
+
+	return result, nil
+}
+
+// ReceiveSigned загрузка по подписанной ссылке LocalStorage, для S3 клиент загружает напрямую
+func (s *Service) ReceiveSigned(ctx context.Context, key string, query url.Values, contentType string, body io.Reader) error {
+	receiver, ok := s.storage.(SignedUploadReceiver)
+	if !ok {
+		return appErrors.ErrUploadUnsupported
+	}
+
+	return receiver.ReceiveUpload(ctx, key, query, contentType, body)
+}
+
+// Complete забирает загруженный оригинал, проверяет и обрабатывает его как обычную загрузку
+func (s *Service) Complete(ctx context.Context, id int64) (UploadResponse, error) {
+	var result UploadResponse
+
+	userId, err := authctx.GeUserIdFromContext(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	direct, ok := s.storage.(DirectStorage)
+	if !ok {
+		return result, appErrors.ErrUploadUnsupported
+	}
+
+	intent, err := s.repo.FindIntentById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, appErrors.ErrUploadNotFound
+		}
+		return result, err
+	}
+
+	if intent.UserId != userId {
+		return result, appErrors.ErrForbidden
+	}
+
+	original, err := direct.OpenOriginal(ctx, intent.ObjectKey)
+	if err != nil {
+		return result, err
+	}
+	defer original.Close()
+
+	data, err := io.ReadAll(io.LimitReader(original, intent.Size+1))
+	if err != nil {
+		return result, err
+	}
+	if int64(len(data)) != intent.Size {
+		return result, appErrors.ErrUploadMismatch
+	}
+
+	header := &multipart.FileHeader{
+		Filename: intent.ObjectKey,
+		Size:     intent.Size,
+	}
+
+	// сначала обрабатываем: если не вышло, ссылка и оригинал остаются
+	// и их удалит DeleteExpired после истечения ссылки
+	result, err = s.stage(ctx, userId, memoryFile{bytes.NewReader(data)}, header)
+	if err != nil {
+		return result, err
+	}
+
+	// повторное подтверждение ссылку уже не займет, а его фото
+	// без объявления удалит DeleteExpired через STAGED_TTL
+	claimed, err := s.repo.DeleteIntent(ctx, intent.Id)
+	if err != nil {
+		return result, err
+	}
+	if !claimed {
+		return UploadResponse{}, appErrors.ErrUploadNotFound
+	}
+
+	err = direct.DeleteOriginal(ctx, intent.ObjectKey)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// DeleteExpired удаляет фото, которые не прикрепили к объявлению за STAGED_TTL,
+// и оригиналы, загрузку которых так и не подтвердили
+func (s *Service) DeleteExpired(ctx context.Context) (int, error) {
+	files, err := s.fileRepo.FindStagedBefore(ctx, time.Now().Add(-STAGED_TTL))
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, f := range files {
+		// запись удаляем первой: если фото успели прикрепить, файлы не трогаем
+		ok, err := s.fileRepo.DeleteStaged(ctx, f.Id)
+		if err != nil {
+			return deleted, err
+		}
+		if !ok {
+			continue
+		}
+
+		err = s.storage.DeleteByPath(ctx, f.Path)
+		if err != nil {
+			return deleted, err
+		}
+
+		err = s.storage.DeleteByPath(ctx, f.PreviewPath)
+		if err != nil {
+			return deleted, err
+		}
+
+		deleted++
+	}
+
+	direct, ok := s.storage.(DirectStorage)
+	if !ok {
+		return deleted, nil
+	}
+
+	intents, err := s.repo.FindIntentsExpiredBefore(ctx, time.Now().Add(-intentGrace))
+	if err != nil {
+		return deleted, err
+	}
+
+	for _, intent := range intents {
+		ok, err := s.repo.DeleteIntent(ctx, intent.Id)
+		if err != nil {
+			return deleted, err
+		}
+		if !ok {
+			continue
+		}
+
+		err = direct.DeleteOriginal(ctx, intent.ObjectKey)
+		if err != nil {
+			return deleted, err
+		}
+
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+func (s *Service) stage(ctx context.Context, userId int64, file multipart.File, header *multipart.FileHeader) (UploadResponse, error) {
+	var result UploadResponse
+
+	// не удалось декодировать или сохранить картинку
+	fileInfo, err := s.storage.Save(ctx, file, header)
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", appErrors.ErrUploadImage, err)
+	}
+
+	id, err := s.fileRepo.SaveStaged(ctx, fileApp.FileModel{
+		UserId:      &userId,
+		Path:        fileInfo.FileName,
+		PreviewPath: fileInfo.PreviewFileName,
+		Mime:        fileInfo.Mime,
+		PreviewMime: fileInfo.PreviewMime,
+		Size:        fileInfo.Size,
+		PreviewSize: fileInfo.PreviewSize,
+		Storage:     s.storage.GetType(),
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Id = id
+	result.Url = s.storage.GetPublicPath(fileInfo.FileName)
+	result.PreviewUrl = s.storage.GetPublicPath(fileInfo.PreviewFileName)
+
+	return result, nil
+}
+
+// оригинал в памяти вместо multipart.File для storage.Save
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (f memoryFile) Close() error {
+	return nil
+}
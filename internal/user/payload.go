@@ -1,11 +1,36 @@
 package user
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type UserModel struct {
-	Id         int64
-	TelegramId int64
-	Username   string
-	CreatedAt  time.Time
-	UpdateAt   time.Time
+	Id                   int64
+	TelegramId           int64
+	Username             string
+	FirstName            string
+	LastName             string
+	PhotoUrl             string
+	NotificationsEnabled bool
+	BotBlockedAt         *time.Time
+	IsModerator          bool
+	CreatedAt            time.Time
+	UpdateAt             time.Time
+}
+
+// DisplayName имя для публичного профиля, username не показываем
+func (u UserModel) DisplayName() string {
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if name == "" {
+		return "Пользователь"
+	}
+
+	return name
+}
+
+// Rating средняя оценка пользователя по отзывам после сделок
+type Rating struct {
+	Average float64 `json:"average"`
+	Count   int     `json:"count"`
 }
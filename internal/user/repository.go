@@ -23,7 +23,14 @@ func (r *Repository) GetUserByTelegramId(ctx context.Context, telegramId int64)
         SELECT
             id,
             telegram_id,
-            username
+            COALESCE(username, ''),
+            first_name,
+            last_name,
+            photo_url,
+            notifications_enabled,
+            bot_blocked_at,
+            is_moderator,
+            created_at
         FROM
             users
         WHERE
@@ -35,6 +42,13 @@ func (r *Repository) GetUserByTelegramId(ctx context.Context, telegramId int64)
         &user.Id,
         &user.TelegramId,
         &user.Username,
+        &user.FirstName,
+        &user.LastName,
+        &user.PhotoUrl,
+        &user.NotificationsEnabled,
+        &user.BotBlockedAt,
+        &user.IsModerator,
+        &user.CreatedAt,
     )
 
     if err != nil {
@@ -54,7 +68,14 @@ func (r *Repository) GetUserById(ctx context.Context, id int64) (UserModel, erro
         SELECT
             id,
             telegram_id,
-            username
+            COALESCE(username, ''),
+            first_name,
+            last_name,
+            photo_url,
+            notifications_enabled,
+            bot_blocked_at,
+            is_moderator,
+            created_at
         FROM
             users
         WHERE
@@ -66,6 +87,13 @@ func (r *Repository) GetUserById(ctx context.Context, id int64) (UserModel, erro
         &user.Id,
         &user.TelegramId,
         &user.Username,
+        &user.FirstName,
+        &user.LastName,
+        &user.PhotoUrl,
+        &user.NotificationsEnabled,
+        &user.BotBlockedAt,
+        &user.IsModerator,
+        &user.CreatedAt,
     )
 
     if err != nil {
@@ -78,18 +106,30 @@ func (r *Repository) GetUserById(ctx context.Context, id int64) (UserModel, erro
     return user, nil
 }
 
-func (r *Repository) UpdateUsername(ctx context.Context, user UserModel) error {
+// данные профиля из Telegram: username, имя и аватар
+func (r *Repository) UpdateProfile(ctx context.Context, user UserModel) error {
     query := `
         UPDATE 
             users
         SET 
             username = $1,
+            first_name = $2,
+            last_name = $3,
+            photo_url = $4,
             updated_at = now()
         WHERE 
-            telegram_id = $2
+            telegram_id = $5
     `
 
-    _, err := r.db.ExecContext(ctx, query, user.Username, user.TelegramId)
+    _, err := r.db.ExecContext(
+        ctx,
+        query,
+        user.Username,
+        user.FirstName,
+        user.LastName,
+        user.PhotoUrl,
+        user.TelegramId,
+    )
     if err != nil {
         return err
     }
@@ -103,9 +143,12 @@ func (r *Repository) CreateUser(ctx context.Context, user UserModel) (int64, err
 	query := `
 		INSERT INTO users (
 			telegram_id,
-			username
+			username,
+			first_name,
+			last_name,
+			photo_url
 		)
-		VALUES ($1, $2)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id
 	`
 
@@ -114,6 +157,9 @@ func (r *Repository) CreateUser(ctx context.Context, user UserModel) (int64, err
 		query,
 		user.TelegramId,
 		user.Username,
+		user.FirstName,
+		user.LastName,
+		user.PhotoUrl,
 	).Scan(&id)
 
 	if err != nil {
@@ -121,4 +167,43 @@ func (r *Repository) CreateUser(ctx context.Context, user UserModel) (int64, err
 	}
 
 	return id, nil
-}
\ No newline at end of file
+}
+
+// пользователь заблокировал бота или снова разрешил ему писать
+func (r *Repository) SetBotBlocked(ctx context.Context, telegramId int64, blocked bool) error {
+    query := `
+        UPDATE
+            users
+        SET
+            bot_blocked_at = CASE WHEN $1 THEN now() ELSE NULL END,
+            updated_at = now()
+        WHERE
+            telegram_id = $2
+    `
+
+    _, err := r.db.ExecContext(ctx, query, blocked, telegramId)
+    if err != nil {
+        return err
+    }
+
+    return nil
+}
+
+func (r *Repository) SetNotificationsEnabled(ctx context.Context, id int64, enabled bool) error {
+    query := `
+        UPDATE
+            users
+        SET
+            notifications_enabled = $1,
+            updated_at = now()
+        WHERE
+            id = $2
+    `
+
+    _, err := r.db.ExecContext(ctx, query, enabled, id)
+    if err != nil {
+        return err
+    }
+
+    return nil
+}
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS content_rules (
+  id bigserial NOT NULL,
+  "type" varchar(32) NOT NULL,
+  pattern varchar(1024) NOT NULL DEFAULT '',
+  "action" varchar(32) NOT NULL,
+  category_id int8 NULL,
+  message varchar(255) NULL,
+  is_active bool NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  updated_at TIMESTAMPTZ DEFAULT NOW(),
+  CONSTRAINT content_rules_pkey PRIMARY KEY (id),
+  CONSTRAINT content_rules_category_id_foreign FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS flagged_at TIMESTAMPTZ NULL;
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS flag_reason text NULL;
+
+INSERT INTO content_rules ("type", pattern, "action", message)
+VALUES
+    ('caps', '0.7', 'flag', 'слишком много заглавных букв'),
+    ('emoji', '10', 'mask', 'слишком много эмодзи'),
+    ('repeat', '4', 'mask', 'слишком много повторяющихся символов'),
+    ('link', '', 'flag', 'ссылки на сторонние ресурсы');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE ads DROP COLUMN IF EXISTS flag_reason;
+ALTER TABLE ads DROP COLUMN IF EXISTS flagged_at;
+DROP TABLE IF EXISTS content_rules;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS bumped_at TIMESTAMPTZ DEFAULT NOW();
+
+UPDATE ads SET bumped_at = created_at;
+
+CREATE INDEX IF NOT EXISTS ads_status_bumped_at_idx ON ads (status, bumped_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS ads_status_bumped_at_idx;
+ALTER TABLE ads DROP COLUMN IF EXISTS bumped_at;
+-- +goose StatementEnd
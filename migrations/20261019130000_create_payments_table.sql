@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS payments (
+  id bigserial NOT NULL,
+  uuid UUID NOT NULL DEFAULT uuidv7(),
+  user_id int8 NOT NULL,
+  ad_uuid UUID NULL,
+  "type" varchar(32) NOT NULL,
+  days int4 NOT NULL,
+  amount int4 NOT NULL,
+  currency varchar(8) NOT NULL,
+  status varchar(32) NOT NULL,
+  telegram_payment_charge_id varchar(255) NULL,
+  provider_payment_charge_id varchar(255) NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  paid_at TIMESTAMPTZ NULL,
+  CONSTRAINT payments_pkey PRIMARY KEY (id),
+  CONSTRAINT payments_uuid_unique UNIQUE (uuid),
+  CONSTRAINT payments_user_id_foreign FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT payments_ad_uuid_foreign FOREIGN KEY (ad_uuid) REFERENCES ads(uuid) ON DELETE SET NULL
+);
+
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS pinned_until TIMESTAMPTZ NULL;
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS highlighted_until TIMESTAMPTZ NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE ads DROP COLUMN IF EXISTS highlighted_until;
+ALTER TABLE ads DROP COLUMN IF EXISTS pinned_until;
+DROP TABLE IF EXISTS payments;
+-- +goose StatementEnd
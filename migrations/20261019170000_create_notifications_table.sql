@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS notifications (
+  id bigserial NOT NULL,
+  kind varchar(64) NOT NULL,
+  chat_id int8 NOT NULL,
+  payload jsonb NOT NULL DEFAULT '{}',
+  status varchar(16) NOT NULL DEFAULT 'pending',
+  attempts int4 NOT NULL DEFAULT 0,
+  last_error text NULL,
+  run_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  sent_at TIMESTAMPTZ NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  updated_at TIMESTAMPTZ DEFAULT NOW(),
+  CONSTRAINT notifications_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS notifications_pending_run_at_index ON notifications (run_at) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS notifications;
+-- +goose StatementEnd
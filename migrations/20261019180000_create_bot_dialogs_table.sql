@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS bot_dialogs (
+  user_id int8 NOT NULL,
+  scenario varchar(64) NOT NULL,
+  step varchar(64) NOT NULL,
+  "data" jsonb NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  updated_at TIMESTAMPTZ DEFAULT NOW(),
+  CONSTRAINT bot_dialogs_pkey PRIMARY KEY (user_id),
+  CONSTRAINT bot_dialogs_user_id_foreign FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS bot_dialogs;
+-- +goose StatementEnd
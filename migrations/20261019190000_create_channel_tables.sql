@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS channel_routes (
+  id bigserial NOT NULL,
+  chat_id int8 NOT NULL,
+  city_id int8 NULL,
+  category_id int8 NULL,
+  is_active bool NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  updated_at TIMESTAMPTZ DEFAULT NOW(),
+  CONSTRAINT channel_routes_pkey PRIMARY KEY (id),
+  CONSTRAINT channel_routes_city_id_foreign FOREIGN KEY (city_id) REFERENCES cities(id) ON DELETE CASCADE,
+  CONSTRAINT channel_routes_category_id_foreign FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS channel_posts (
+  ad_uuid uuid NOT NULL,
+  chat_id int8 NOT NULL,
+  message_ids jsonb NOT NULL DEFAULT '[]',
+  with_media bool NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  updated_at TIMESTAMPTZ DEFAULT NOW(),
+  CONSTRAINT channel_posts_pkey PRIMARY KEY (ad_uuid, chat_id),
+  CONSTRAINT channel_posts_ad_uuid_foreign FOREIGN KEY (ad_uuid) REFERENCES ads(uuid) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS channel_posts;
+DROP TABLE IF EXISTS channel_routes;
+-- +goose StatementEnd
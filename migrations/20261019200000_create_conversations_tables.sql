@@ -0,0 +1,59 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS conversations (
+  id bigserial NOT NULL,
+  ad_uuid uuid NOT NULL,
+  buyer_id int8 NOT NULL,
+  seller_id int8 NOT NULL,
+  blocked_by int8 NULL,
+  blocked_at TIMESTAMPTZ NULL,
+  last_message_at TIMESTAMPTZ NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  updated_at TIMESTAMPTZ DEFAULT NOW(),
+  CONSTRAINT conversations_pkey PRIMARY KEY (id),
+  CONSTRAINT conversations_ad_buyer_unique UNIQUE (ad_uuid, buyer_id),
+  CONSTRAINT conversations_ad_uuid_foreign FOREIGN KEY (ad_uuid) REFERENCES ads(uuid) ON DELETE CASCADE,
+  CONSTRAINT conversations_buyer_id_foreign FOREIGN KEY (buyer_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT conversations_seller_id_foreign FOREIGN KEY (seller_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS conversations_buyer_id_index ON conversations (buyer_id);
+CREATE INDEX IF NOT EXISTS conversations_seller_id_index ON conversations (seller_id);
+
+CREATE TABLE IF NOT EXISTS conversation_messages (
+  id bigserial NOT NULL,
+  conversation_id int8 NOT NULL,
+  sender_id int8 NOT NULL,
+  "text" text NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  CONSTRAINT conversation_messages_pkey PRIMARY KEY (id),
+  CONSTRAINT conversation_messages_conversation_id_foreign FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+  CONSTRAINT conversation_messages_sender_id_foreign FOREIGN KEY (sender_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS conversation_messages_conversation_id_index ON conversation_messages (conversation_id, created_at);
+
+CREATE TABLE IF NOT EXISTS conversation_reports (
+  id bigserial NOT NULL,
+  conversation_id int8 NOT NULL,
+  reporter_id int8 NOT NULL,
+  reason text NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  CONSTRAINT conversation_reports_pkey PRIMARY KEY (id),
+  CONSTRAINT conversation_reports_conversation_id_foreign FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+  CONSTRAINT conversation_reports_reporter_id_foreign FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- диалог, в который уходят обычные сообщения пользователя боту
+ALTER TABLE users ADD COLUMN IF NOT EXISTS active_conversation_id int8 NULL;
+ALTER TABLE users ADD CONSTRAINT users_active_conversation_id_foreign FOREIGN KEY (active_conversation_id) REFERENCES conversations(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_active_conversation_id_foreign;
+ALTER TABLE users DROP COLUMN IF EXISTS active_conversation_id;
+DROP TABLE IF EXISTS conversation_reports;
+DROP TABLE IF EXISTS conversation_messages;
+DROP TABLE IF EXISTS conversations;
+-- +goose StatementEnd
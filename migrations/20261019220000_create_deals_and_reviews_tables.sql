@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS deals (
+  id bigserial NOT NULL,
+  ad_uuid uuid NOT NULL,
+  seller_id int8 NOT NULL,
+  buyer_id int8 NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  CONSTRAINT deals_pkey PRIMARY KEY (id),
+  CONSTRAINT deals_ad_uuid_unique UNIQUE (ad_uuid),
+  CONSTRAINT deals_not_self CHECK (seller_id <> buyer_id),
+  CONSTRAINT deals_ad_uuid_foreign FOREIGN KEY (ad_uuid) REFERENCES ads(uuid) ON DELETE CASCADE,
+  CONSTRAINT deals_seller_id_foreign FOREIGN KEY (seller_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT deals_buyer_id_foreign FOREIGN KEY (buyer_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS deals_seller_id_index ON deals (seller_id);
+CREATE INDEX IF NOT EXISTS deals_buyer_id_index ON deals (buyer_id);
+
+-- по одному отзыву от каждой стороны сделки
+CREATE TABLE IF NOT EXISTS reviews (
+  id bigserial NOT NULL,
+  deal_id int8 NOT NULL,
+  author_id int8 NOT NULL,
+  target_id int8 NOT NULL,
+  rating int2 NOT NULL,
+  "comment" text NOT NULL DEFAULT '',
+  flagged_at TIMESTAMPTZ NULL,
+  flag_reason text NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  CONSTRAINT reviews_pkey PRIMARY KEY (id),
+  CONSTRAINT reviews_deal_author_unique UNIQUE (deal_id, author_id),
+  CONSTRAINT reviews_rating_check CHECK (rating BETWEEN 1 AND 5),
+  CONSTRAINT reviews_not_self CHECK (author_id <> target_id),
+  CONSTRAINT reviews_deal_id_foreign FOREIGN KEY (deal_id) REFERENCES deals(id) ON DELETE CASCADE,
+  CONSTRAINT reviews_author_id_foreign FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE CASCADE,
+  CONSTRAINT reviews_target_id_foreign FOREIGN KEY (target_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS reviews_target_id_index ON reviews (target_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS reviews;
+DROP TABLE IF EXISTS deals;
+-- +goose StatementEnd
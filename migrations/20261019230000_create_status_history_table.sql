@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS status_history (
+  id bigserial NOT NULL,
+  ad_uuid uuid NOT NULL,
+  from_status int2 NOT NULL,
+  to_status int2 NOT NULL,
+  -- NULL — переход сделала система (архивация по сроку)
+  actor_id int8 NULL,
+  reason text NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  CONSTRAINT status_history_pkey PRIMARY KEY (id),
+  CONSTRAINT status_history_ad_uuid_foreign FOREIGN KEY (ad_uuid) REFERENCES ads(uuid) ON DELETE CASCADE,
+  CONSTRAINT status_history_actor_id_foreign FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS status_history_ad_uuid_index ON status_history (ad_uuid, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS status_history;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- closed_at — когда объявление закрыли (удалено, продано, истек срок):
+-- фото хранятся до closed_at + AD_RETENTION_DAYS, потом их удаляет -purge
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ NULL;
+-- purged_at — фото удалены, а строка оставлена ради сделки и отзывов
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS purged_at TIMESTAMPTZ NULL;
+
+-- срок хранения уже закрытых объявлений отсчитываем от последнего изменения
+UPDATE ads SET closed_at = COALESCE(updated_at, created_at) WHERE status IN (2, 3, 4);
+
+CREATE INDEX IF NOT EXISTS ads_closed_at_index ON ads (closed_at) WHERE purged_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS ads_closed_at_index;
+ALTER TABLE ads DROP COLUMN IF EXISTS purged_at;
+ALTER TABLE ads DROP COLUMN IF EXISTS closed_at;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_moderator boolean NOT NULL DEFAULT false;
+
+-- история правок объявления: changes — {"price": {"old": 15000000, "new": 17000000}, ...}
+CREATE TABLE IF NOT EXISTS ad_revisions (
+  id bigserial NOT NULL,
+  ad_uuid uuid NOT NULL,
+  editor_id int8 NULL,
+  changes jsonb NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  CONSTRAINT ad_revisions_pkey PRIMARY KEY (id),
+  CONSTRAINT ad_revisions_ad_uuid_foreign FOREIGN KEY (ad_uuid) REFERENCES ads(uuid) ON DELETE CASCADE,
+  CONSTRAINT ad_revisions_editor_id_foreign FOREIGN KEY (editor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS ad_revisions_ad_uuid_index ON ad_revisions (ad_uuid, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS ad_revisions;
+ALTER TABLE users DROP COLUMN IF EXISTS is_moderator;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- версия для ETag/If-Match: растет при каждом изменении объявления
+ALTER TABLE ads ADD COLUMN IF NOT EXISTS version int4 NOT NULL DEFAULT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE ads DROP COLUMN IF EXISTS version;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Idempotency-Key запросов: response NULL — запрос еще выполняется
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  user_id int8 NOT NULL,
+  "key" varchar(255) NOT NULL,
+  request_hash varchar(64) NOT NULL,
+  response jsonb NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT idempotency_keys_pkey PRIMARY KEY (user_id, "key"),
+  CONSTRAINT idempotency_keys_user_id_foreign FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_index ON idempotency_keys (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS idempotency_keys;
+-- +goose StatementEnd
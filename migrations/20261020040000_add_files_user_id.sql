@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- загруженные заранее фото: ad_uuid NULL, владелец — user_id, пока их не прикрепили к объявлению
+ALTER TABLE files ADD COLUMN IF NOT EXISTS user_id int8 NULL;
+ALTER TABLE files ADD CONSTRAINT files_user_id_foreign FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS files_staged_index ON files (created_at) WHERE ad_uuid IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS files_staged_index;
+ALTER TABLE files DROP CONSTRAINT IF EXISTS files_user_id_foreign;
+ALTER TABLE files DROP COLUMN IF EXISTS user_id;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- выданные ссылки на прямую загрузку: оригинал по object_key ждет подтверждения клиентом
+CREATE TABLE IF NOT EXISTS upload_intents (
+  id bigserial NOT NULL,
+  user_id int8 NOT NULL,
+  object_key varchar(255) NOT NULL,
+  content_type varchar(255) NOT NULL,
+  "size" int8 NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  CONSTRAINT upload_intents_pkey PRIMARY KEY (id),
+  CONSTRAINT upload_intents_object_key_unique UNIQUE (object_key),
+  CONSTRAINT upload_intents_user_id_foreign FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS upload_intents_expires_at_index ON upload_intents (expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS upload_intents;
+-- +goose StatementEnd
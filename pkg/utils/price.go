@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 15000000 -> "15 000 000 ₫"
+func FormatPrice(price int) string {
+	if price == 0 {
+		return "бесплатно"
+	}
+
+	digits := strconv.Itoa(price)
+
+	var result strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			result.WriteRune(' ')
+		}
+		result.WriteRune(d)
+	}
+	result.WriteString(" ₫")
+
+	return result.String()
+}